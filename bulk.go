@@ -0,0 +1,189 @@
+package simpleforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce/soql"
+)
+
+// Bulk API 2.0 job states.
+const (
+	BulkJobUploadComplete = "UploadComplete"
+	BulkJobInProgress     = "InProgress"
+	BulkJobComplete       = "JobComplete"
+	BulkJobFailed         = "Failed"
+	BulkJobAborted        = "Aborted"
+)
+
+// bulkResultsPageSize is the number of records requested per page of bulk query results.
+const bulkResultsPageSize = 10000
+
+// Polling intervals of bulk query jobs. The interval doubles up to the maximum while the job runs.
+var (
+	bulkPollInterval    = 500 * time.Millisecond
+	bulkMaxPollInterval = 10 * time.Second
+)
+
+// BulkJob is the status of a Bulk API 2.0 query job.
+type BulkJob struct {
+	ID                     string `json:"id"`
+	Object                 string `json:"object"`
+	State                  string `json:"state"`
+	ErrorMessage           string `json:"errorMessage"`
+	NumberRecordsProcessed int    `json:"numberRecordsProcessed"`
+}
+
+// BulkQuerier runs Bulk API queries. HTTPClient implements BulkQuerier.
+type BulkQuerier interface {
+	BulkQuery(ctx context.Context, query string, handle func(records []*SObject) error) error
+}
+
+// BulkQuery runs a query as a Bulk API 2.0 job, which suits result sets too large for Query, and passes the records
+// to handle a page at a time. Bulk results are CSV, so field values are strings and empty values are nil; relationship
+// fields are flattened to keys such as Account.Name. The client must use API version 47.0 or later.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/queries.htm
+func (h *HTTPClient) BulkQuery(ctx context.Context, query string, handle func(records []*SObject) error) error {
+	reqData, err := json.Marshal(map[string]string{
+		"operation": "query",
+		"query":     query,
+	})
+	if err != nil {
+		return err
+	}
+
+	job := &BulkJob{}
+
+	err = h.bulkRequest(ctx, http.MethodPost, h.makeURL("jobs/query"), bytes.NewReader(reqData), job)
+	if err != nil {
+		return err
+	}
+
+	job, err = h.waitBulkJob(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	// The type of the records is only known from the query.
+	var typeName string
+	if stmt, err := soql.Parse(query); err == nil {
+		typeName = stmt.From
+	}
+
+	var locator string
+	for {
+		params := url.Values{}
+		params.Set("maxRecords", strconv.Itoa(bulkResultsPageSize))
+		if len(locator) > 0 {
+			params.Set("locator", locator)
+		}
+
+		headers := http.Header{}
+		headers.Set("Accept", "text/csv")
+
+		res, err := h.request(ctx, http.MethodGet, h.makeURL("jobs/query/"+job.ID+"/results?"+params.Encode()), nil, headers)
+		if err != nil {
+			return err
+		}
+
+		records, err := decodeBulkRecords(res.Body, typeName)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		err = handle(records)
+		if err != nil {
+			return err
+		}
+
+		locator = res.Header.Get("Sforce-Locator")
+		if len(locator) == 0 || locator == "null" {
+			return nil
+		}
+	}
+}
+
+// waitBulkJob polls a query job until it completes. ErrBulkJob is returned if the job failed or was aborted.
+func (h *HTTPClient) waitBulkJob(ctx context.Context, jobID string) (*BulkJob, error) {
+	interval := bulkPollInterval
+
+	for {
+		job := &BulkJob{}
+
+		err := h.bulkRequest(ctx, http.MethodGet, h.makeURL("jobs/query/"+jobID), nil, job)
+		if err != nil {
+			return nil, err
+		}
+
+		switch job.State {
+		case BulkJobComplete:
+			return job, nil
+		case BulkJobFailed, BulkJobAborted:
+			return nil, ErrBulkJob{ID: job.ID, State: job.State, Message: job.ErrorMessage}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > bulkMaxPollInterval {
+			interval = bulkMaxPollInterval
+		}
+	}
+}
+
+func (h *HTTPClient) bulkRequest(ctx context.Context, method, url string, body io.Reader, v interface{}) error {
+	res, err := h.request(ctx, method, url, body, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// decodeBulkRecords decodes CSV bulk query results, whose first row holds the field names.
+func decodeBulkRecords(r io.Reader, typeName string) ([]*SObject, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*SObject
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := NewSObject(typeName)
+		for i, field := range header {
+			if len(row[i]) == 0 {
+				(*record)[field] = nil
+			} else {
+				(*record)[field] = row[i]
+			}
+		}
+
+		records = append(records, record)
+	}
+}
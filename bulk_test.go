@@ -0,0 +1,81 @@
+package simpleforce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClient_BulkQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	bulkPollInterval = time.Millisecond
+	defer func() { bulkPollInterval = 500 * time.Millisecond }()
+
+	polls := 0
+	state := BulkJobComplete
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[len("/services/data/v47.0/"):]
+
+		switch {
+		case r.Method == http.MethodPost && path == "jobs/query":
+			w.Write([]byte(`{"id": "7505e000001", "operation": "query", "object": "Account", "state": "UploadComplete"}`))
+		case path == "jobs/query/7505e000001":
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"id": "7505e000001", "state": "InProgress"}`))
+				return
+			}
+			w.Write([]byte(`{"id": "7505e000001", "state": "` + state + `", "errorMessage": "INVALID_FIELD"}`))
+		case path == "jobs/query/7505e000001/results":
+			assert.Equal("text/csv", r.Header.Get("Accept"))
+			assert.Equal("10000", r.URL.Query().Get("maxRecords"))
+
+			if r.URL.Query().Get("locator") == "" {
+				w.Header().Set("Sforce-Locator", "MTAwMDA")
+				w.Write([]byte("\"Id\",\"Name\",\"Parent.Name\"\n\"001000000000001AAA\",\"Acme, Inc.\",\"\"\n"))
+				return
+			}
+
+			assert.Equal("MTAwMDA", r.URL.Query().Get("locator"))
+			w.Header().Set("Sforce-Locator", "null")
+			w.Write([]byte("\"Id\",\"Name\",\"Parent.Name\"\n\"001000000000002AAA\",\"Globex\",\"Acme, Inc.\"\n"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, "v47.0")
+
+	var records []*SObject
+	err := client.BulkQuery(context.Background(), "SELECT Id, Name, Parent.Name FROM Account", func(page []*SObject) error {
+		records = append(records, page...)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(2, polls)
+
+	assert.Len(records, 2)
+	assert.Equal("Account", records[0].Type())
+	assert.Equal("Acme, Inc.", records[0].StringField("Name"))
+	assert.Nil(records[0].InterfaceField("Parent.Name"))
+	// Bulk records are read only, so they don't track changes.
+	assert.Nil(records[0].ChangedFields())
+	assert.Equal("001000000000002AAA", records[1].ID())
+	assert.Equal("Acme, Inc.", records[1].StringField("Parent.Name"))
+
+	polls = 0
+	state = BulkJobFailed
+
+	err = client.BulkQuery(context.Background(), "SELECT Id, Name FROM Account", func(page []*SObject) error {
+		return nil
+	})
+	assert.Equal(ErrBulkJob{ID: "7505e000001", State: BulkJobFailed, Message: "INVALID_FIELD"}, err)
+	assert.EqualError(err, "bulk job 7505e000001 failed: INVALID_FIELD")
+}
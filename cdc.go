@@ -0,0 +1,297 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce/internal/atomicfile"
+)
+
+// ChangeType is the operation of a change event.
+type ChangeType string
+
+const (
+	ChangeCreate   ChangeType = "CREATE"
+	ChangeUpdate   ChangeType = "UPDATE"
+	ChangeDelete   ChangeType = "DELETE"
+	ChangeUndelete ChangeType = "UNDELETE"
+	// Gap events report changes salesforce couldn't generate a full event for, such as changes made by database
+	// jobs. They carry no field values; the records must be retrieved to get their state.
+	ChangeGapCreate   ChangeType = "GAP_CREATE"
+	ChangeGapUpdate   ChangeType = "GAP_UPDATE"
+	ChangeGapDelete   ChangeType = "GAP_DELETE"
+	ChangeGapUndelete ChangeType = "GAP_UNDELETE"
+	// ChangeGapOverflow reports that a single transaction changed more records than salesforce generates events for.
+	ChangeGapOverflow ChangeType = "GAP_OVERFLOW"
+)
+
+// ChangeEventHeader describes the change of a change event.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.change_data_capture.meta/change_data_capture/cdc_event_fields_header.htm
+type ChangeEventHeader struct {
+	EntityName     string     `json:"entityName"`
+	RecordIDs      []string   `json:"recordIds"`
+	ChangeType     ChangeType `json:"changeType"`
+	ChangeOrigin   string     `json:"changeOrigin"`
+	TransactionKey string     `json:"transactionKey"`
+	SequenceNumber int        `json:"sequenceNumber"`
+	// CommitTimestamp is the time of the transaction in milliseconds since the epoch. See CommitTime.
+	CommitTimestamp int64    `json:"commitTimestamp"`
+	CommitNumber    int64    `json:"commitNumber"`
+	CommitUser      string   `json:"commitUser"`
+	ChangedFields   []string `json:"changedFields"`
+	DiffFields      []string `json:"diffFields"`
+	NulledFields    []string `json:"nulledFields"`
+}
+
+// CommitTime returns the time of the transaction.
+func (h *ChangeEventHeader) CommitTime() time.Time {
+	return time.Unix(0, h.CommitTimestamp*int64(time.Millisecond)).UTC()
+}
+
+// IsGap reports whether the event is a gap event, without field values.
+func (h *ChangeEventHeader) IsGap() bool {
+	return strings.HasPrefix(string(h.ChangeType), "GAP_")
+}
+
+// ChangeEvent is a Change Data Capture event.
+type ChangeEvent struct {
+	Channel  string
+	ReplayID int64
+	Header   ChangeEventHeader
+	// Fields holds the new values of the changed fields, without the header. It is empty for gap and delete events.
+	Fields *SObject
+}
+
+// DecodeChangeEvent decodes the change event received as a streaming event.
+func DecodeChangeEvent(event *StreamingEvent) (*ChangeEvent, error) {
+	if event.SObject == nil {
+		return nil, ErrStreaming{event.Channel, "event has no change event payload"}
+	}
+
+	change := &ChangeEvent{
+		Channel:  event.Channel,
+		ReplayID: event.ReplayID,
+		Fields:   &SObject{},
+	}
+
+	for key, value := range *event.SObject {
+		if key == "ChangeEventHeader" {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+
+			err = json.Unmarshal(data, &change.Header)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		(*change.Fields)[key] = value
+	}
+
+	if len(change.Header.ChangeType) == 0 {
+		return nil, ErrStreaming{event.Channel, "event has no ChangeEventHeader"}
+	}
+
+	return change, nil
+}
+
+// ReplayStore persists the replay ID of the last event processed on a channel, so subscribers resume after it.
+type ReplayStore interface {
+	// LoadReplayID returns the stored replay ID of a channel. ok is false if none was stored.
+	LoadReplayID(ctx context.Context, channel string) (replayID int64, ok bool, err error)
+	SaveReplayID(ctx context.Context, channel string, replayID int64) error
+}
+
+// MemoryReplayStore is a ReplayStore keeping replay IDs in memory, for tests and subscribers that don't need to
+// resume after restarts.
+type MemoryReplayStore struct {
+	mu        sync.Mutex
+	replayIDs map[string]int64
+}
+
+// NewMemoryReplayStore creates a new empty MemoryReplayStore.
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{replayIDs: make(map[string]int64)}
+}
+
+func (s *MemoryReplayStore) LoadReplayID(ctx context.Context, channel string) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replayID, ok := s.replayIDs[channel]
+	return replayID, ok, nil
+}
+
+func (s *MemoryReplayStore) SaveReplayID(ctx context.Context, channel string, replayID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replayIDs[channel] = replayID
+	return nil
+}
+
+// FileReplayStore is a ReplayStore keeping the replay IDs of all channels in a JSON file. Writes replace the file
+// atomically, so a crash never leaves it corrupt.
+type FileReplayStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileReplayStore creates a FileReplayStore persisting replay IDs to path.
+func NewFileReplayStore(path string) *FileReplayStore {
+	return &FileReplayStore{path: path}
+}
+
+func (s *FileReplayStore) LoadReplayID(ctx context.Context, channel string) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replayIDs, err := s.read()
+	if err != nil {
+		return 0, false, err
+	}
+
+	replayID, ok := replayIDs[channel]
+	return replayID, ok, nil
+}
+
+func (s *FileReplayStore) SaveReplayID(ctx context.Context, channel string, replayID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replayIDs, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	replayIDs[channel] = replayID
+
+	data, err := json.Marshal(replayIDs)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(s.path, data)
+}
+
+func (s *FileReplayStore) read() (map[string]int64, error) {
+	replayIDs := make(map[string]int64)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return replayIDs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &replayIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return replayIDs, nil
+}
+
+// ChangeHandler processes a change event. Returning an error stops the subscriber without saving the replay ID of
+// the event, so it is received again when the subscriber restarts.
+type ChangeHandler func(ctx context.Context, event *ChangeEvent) error
+
+// ChangeSubscriber subscribes to Change Data Capture channels, such as /data/ChangeEvents or
+// /data/AccountChangeEvent, and saves the replay ID of each event once it is handled.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.change_data_capture.meta/change_data_capture/cdc_intro.htm
+type ChangeSubscriber struct {
+	streaming *StreamingClient
+	store     ReplayStore
+}
+
+// NewChangeSubscriber creates a new ChangeSubscriber receiving events through streaming and persisting replay IDs
+// to store.
+func NewChangeSubscriber(streaming *StreamingClient, store ReplayStore) *ChangeSubscriber {
+	return &ChangeSubscriber{
+		streaming: streaming,
+		store:     store,
+	}
+}
+
+// Run subscribes to channels and passes their events to handler, one at a time, until ctx is done, handler returns
+// an error or the streaming client fails. Channels without a stored replay ID start with new events. Events at or
+// before the stored replay ID, redelivered after the streaming session was restored, are skipped.
+//
+// The replay ID is saved after handler returns, so an event is only handled again if the process stops between
+// the two; handlers that must never see an event twice should be idempotent.
+func (s *ChangeSubscriber) Run(ctx context.Context, channels []string, handler ChangeHandler) error {
+	return runReplaySubscriber(ctx, s.streaming, s.store, channels, func(ctx context.Context, event *StreamingEvent) error {
+		change, err := DecodeChangeEvent(event)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, change)
+	})
+}
+
+// runReplaySubscriber subscribes to channels from their stored replay IDs and passes their events to handle,
+// saving the replay ID of each event handled.
+func runReplaySubscriber(ctx context.Context, streaming *StreamingClient, store ReplayStore, channels []string, handle func(ctx context.Context, event *StreamingEvent) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	handled := make(map[string]int64, len(channels))
+
+	for _, channel := range channels {
+		replayID, ok, err := store.LoadReplayID(ctx, channel)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			replayID = ReplayNew
+		}
+
+		handled[channel] = replayID
+
+		err = streaming.Subscribe(ctx, channel, replayID)
+		if err != nil {
+			return err
+		}
+	}
+
+	events := make(chan *StreamingEvent)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- streaming.Run(ctx, events)
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			if last, ok := handled[event.Channel]; ok && last >= 0 && event.ReplayID <= last {
+				continue
+			}
+
+			err := handle(ctx, event)
+			if err == nil {
+				err = store.SaveReplayID(ctx, event.Channel, event.ReplayID)
+			}
+			if err != nil {
+				cancel()
+				<-errc
+				return err
+			}
+
+			handled[event.Channel] = event.ReplayID
+		case err := <-errc:
+			return err
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCometDServer serves the given events on the first connect and then holds connects open. It records the replay
+// IDs of subscriptions.
+func newCometDServer(t *testing.T, events []map[string]interface{}, replayIDs *[]float64) *httptest.Server {
+	var mu sync.Mutex
+	served := false
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msgs []map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msgs))
+		msg := msgs[0]
+
+		res := []map[string]interface{}{{"channel": msg["channel"], "id": msg["id"], "clientId": "client", "successful": true}}
+
+		mu.Lock()
+		switch msg["channel"] {
+		case "/meta/subscribe":
+			replay := msg["ext"].(map[string]interface{})["replay"].(map[string]interface{})
+			*replayIDs = append(*replayIDs, replay[msg["subscription"].(string)].(float64))
+		case "/meta/connect":
+			if served {
+				mu.Unlock()
+				<-r.Context().Done()
+				return
+			}
+			served = true
+			res = append(events, res...)
+		}
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(res)
+	}))
+}
+
+func changeEventData(replayID int, changeType string, fields map[string]interface{}) map[string]interface{} {
+	payload := map[string]interface{}{
+		"ChangeEventHeader": map[string]interface{}{
+			"entityName":      "Account",
+			"recordIds":       []string{"001000000000001AAA"},
+			"changeType":      changeType,
+			"commitTimestamp": 1614834367000,
+			"changedFields":   []string{"Name"},
+		},
+	}
+	for key, value := range fields {
+		payload[key] = value
+	}
+
+	return map[string]interface{}{
+		"channel": "/data/AccountChangeEvent",
+		"data": map[string]interface{}{
+			"event":   map[string]interface{}{"replayId": replayID},
+			"payload": payload,
+		},
+	}
+}
+
+func TestChangeSubscriber_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	var replayIDs []float64
+	ts := newCometDServer(t, []map[string]interface{}{
+		changeEventData(10, "UPDATE", map[string]interface{}{"Name": "Acme"}),
+		changeEventData(11, "GAP_UPDATE", nil),
+		changeEventData(12, "UPDATE", map[string]interface{}{"Name": "Acme Corp"}),
+	}, &replayIDs)
+	defer ts.Close()
+
+	store := NewMemoryReplayStore()
+	assert.NoError(store.SaveReplayID(context.Background(), "/data/AccountChangeEvent", 10))
+
+	subscriber := NewChangeSubscriber(NewStreamingClient(ts.Client(), ts.URL, DefaultAPIVersion), store)
+
+	failure := errors.New("failure")
+
+	var changes []*ChangeEvent
+	err := subscriber.Run(context.Background(), []string{"/data/AccountChangeEvent"}, func(ctx context.Context, event *ChangeEvent) error {
+		changes = append(changes, event)
+		if event.ReplayID == 12 {
+			return failure
+		}
+		return nil
+	})
+	assert.Equal(failure, err)
+
+	// The event at the stored replay ID is skipped.
+	assert.Len(changes, 2)
+	assert.True(changes[0].Header.IsGap())
+	assert.Equal(ChangeGapUpdate, changes[0].Header.ChangeType)
+	assert.Equal("Account", changes[0].Header.EntityName)
+	assert.Equal([]string{"001000000000001AAA"}, changes[0].Header.RecordIDs)
+	assert.Equal(int64(1614834367), changes[0].Header.CommitTime().Unix())
+	assert.Equal("Acme Corp", changes[1].Fields.StringField("Name"))
+	assert.Nil(changes[1].Fields.InterfaceField("ChangeEventHeader"))
+
+	assert.Equal([]float64{10}, replayIDs)
+
+	// The failed event isn't saved, so it is received again.
+	replayID, ok, err := store.LoadReplayID(context.Background(), "/data/AccountChangeEvent")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(int64(11), replayID)
+}
+
+func TestFileReplayStore(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "replay.json")
+
+	store := NewFileReplayStore(path)
+
+	_, ok, err := store.LoadReplayID(ctx, "/data/ChangeEvents")
+	assert.NoError(err)
+	assert.False(ok)
+
+	assert.NoError(store.SaveReplayID(ctx, "/data/ChangeEvents", 42))
+	assert.NoError(store.SaveReplayID(ctx, "/event/Order__e", 7))
+
+	replayID, ok, err := NewFileReplayStore(path).LoadReplayID(ctx, "/data/ChangeEvents")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(int64(42), replayID)
+}
@@ -0,0 +1,80 @@
+package simpleforce
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultUpdateAttempts is the number of attempts UpdateSObjectWithRetry makes when maxAttempts is not positive.
+const DefaultUpdateAttempts = 3
+
+// UpdateSObjectWithRetry applies mutate to the current version of the record identified by the type and ID of sobj
+// and updates it with optimistic concurrency control. If the record is modified by someone else between the read and
+// the write, the record is re-fetched and mutate is applied again, up to maxAttempts times. Only the fields changed
+// by mutate are sent to salesforce. On success sobj holds the mutated record.
+func UpdateSObjectWithRetry(ctx context.Context, client ConditionalUpdater, sobj *SObject, blacklistedFields []string, autoAssign *bool, maxAttempts int, mutate func(*SObject) error) error {
+	if len(sobj.Type()) == 0 {
+		return ErrInvalidSObject{"Type is empty"}
+	}
+
+	if len(sobj.ID()) == 0 {
+		return ErrInvalidSObject{"Id is empty"}
+	}
+
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultUpdateAttempts
+	}
+
+	var err error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		current := NewSObject(sobj.Type()).SetID(sobj.ID())
+
+		var res *GetResult
+		res, err = client.GetSObjectWithOptions(ctx, current, nil)
+		if err != nil {
+			return err
+		}
+
+		precondition := preconditionFor(current, res)
+
+		err = mutate(current)
+		if err != nil {
+			return err
+		}
+
+		err = client.UpdateSObjectIfUnmodified(ctx, current, blacklistedFields, autoAssign, precondition)
+		if err == nil {
+			*sobj = *current
+			return nil
+		}
+
+		if !errors.As(err, &ErrPreconditionFailed{}) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// preconditionFor builds the precondition for updating a record fetched with the given result, preferring the ETag
+// over the modification time. It must be called before the record is mutated.
+func preconditionFor(fetched *SObject, res *GetResult) *Precondition {
+	if res != nil && len(res.ETag) > 0 {
+		return &Precondition{IfMatch: res.ETag}
+	}
+
+	if res != nil && !res.LastModified.IsZero() {
+		return &Precondition{IfUnmodifiedSince: res.LastModified}
+	}
+
+	// Without caching headers, fall back to the LastModifiedDate field of the fetched record.
+	lastModified, err := time.Parse(dateTimeLayout, fetched.StringField(sobjectLastModifiedDateKey))
+	if err != nil {
+		return nil
+	}
+
+	return &Precondition{IfUnmodifiedSince: lastModified}
+}
@@ -0,0 +1,129 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateSObjectWithRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	etags := []string{`"v1"`, `"v2"`}
+	gets := 0
+	patches := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", etags[gets])
+			gets++
+
+			err := json.NewEncoder(w).Encode(NewSObject("Case").
+				SetID("object1").
+				Set("Subject", "foo").
+				Set("Count__c", float64(gets)))
+			assert.NoError(err)
+		case http.MethodPatch:
+			patches++
+
+			o := &SObject{}
+			err := json.NewDecoder(r.Body).Decode(o)
+			assert.NoError(err)
+
+			// Only the mutated field is sent.
+			assert.Equal(SObject{"Count__c": float64(gets + 10)}, *o)
+
+			if r.Header.Get("If-Match") == etags[0] {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+
+			assert.Equal(etags[1], r.Header.Get("If-Match"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").SetID("object1")
+
+	err := UpdateSObjectWithRetry(context.Background(), client, sobj, nil, nil, 0, func(current *SObject) error {
+		current.Set("Count__c", current.InterfaceField("Count__c").(float64)+10)
+		return nil
+	})
+	assert.NoError(err)
+
+	assert.Equal(2, gets)
+	assert.Equal(2, patches)
+	assert.Equal(float64(12), sobj.InterfaceField("Count__c"))
+	assert.Equal("foo", sobj.StringField("Subject"))
+}
+
+func TestUpdateSObjectWithRetry_exhausted(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("ETag", `"v1"`)
+
+			err := json.NewEncoder(w).Encode(NewSObject("Case").SetID("object1"))
+			assert.NoError(err)
+		case http.MethodPatch:
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").SetID("object1")
+
+	err := UpdateSObjectWithRetry(context.Background(), client, sobj, nil, nil, 2, func(current *SObject) error {
+		current.Set("Subject", "bar")
+		return nil
+	})
+	assert.IsType(ErrPreconditionFailed{}, err)
+}
+
+func TestUpdateSObjectWithRetry_lastModifiedDate(t *testing.T) {
+	assert := assert.New(t)
+
+	patches := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			err := json.NewEncoder(w).Encode(NewSObject("Case").
+				SetID("object1").
+				Set("Subject", "foo").
+				Set("LastModifiedDate", "2021-01-02T03:04:05.000+0000"))
+			assert.NoError(err)
+		case http.MethodPatch:
+			patches++
+
+			o := &SObject{}
+			err := json.NewDecoder(r.Body).Decode(o)
+			assert.NoError(err)
+
+			assert.Equal(SObject{"Subject": "bar"}, *o)
+			assert.Equal("Sat, 02 Jan 2021 03:04:05 GMT", r.Header.Get("If-Unmodified-Since"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").SetID("object1")
+
+	err := UpdateSObjectWithRetry(context.Background(), client, sobj, nil, nil, 0, func(current *SObject) error {
+		current.Set("Subject", "bar")
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(1, patches)
+}
@@ -0,0 +1,102 @@
+package simpleforce
+
+import (
+	"encoding/json"
+)
+
+// FieldMeta describes a single field of an SObject as returned by the "describe" API.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/sforce_api_calls_describesobjects_describesobjectresult.htm#field
+type FieldMeta struct {
+	Name               string          `json:"name"`
+	Label              string          `json:"label"`
+	Type               string          `json:"type"`
+	SOAPType           string          `json:"soapType"`
+	Length             int             `json:"length"`
+	Precision          int             `json:"precision"`
+	Scale              int             `json:"scale"`
+	Custom             bool            `json:"custom"`
+	Createable         bool            `json:"createable"`
+	Updateable         bool            `json:"updateable"`
+	Nillable           bool            `json:"nillable"`
+	DefaultedOnCreate  bool            `json:"defaultedOnCreate"`
+	Calculated         bool            `json:"calculated"`
+	ExternalID         bool            `json:"externalId"`
+	IDLookup           bool            `json:"idLookup"`
+	Unique             bool            `json:"unique"`
+	Filterable         bool            `json:"filterable"`
+	Sortable           bool            `json:"sortable"`
+	RestrictedPicklist bool            `json:"restrictedPicklist"`
+	PicklistValues     []PicklistValue `json:"picklistValues"`
+	ReferenceTo        []string        `json:"referenceTo"`
+	RelationshipName   string          `json:"relationshipName"`
+}
+
+// PicklistValue describes a single value of a picklist field.
+type PicklistValue struct {
+	Value        string `json:"value"`
+	Label        string `json:"label"`
+	Active       bool   `json:"active"`
+	DefaultValue bool   `json:"defaultValue"`
+}
+
+// Name returns the name of the described SObject.
+func (m *SObjectMeta) Name() string {
+	name, _ := (*m)["name"].(string)
+	return name
+}
+
+// KeyPrefix returns the three character ID prefix of the described SObject.
+func (m *SObjectMeta) KeyPrefix() string {
+	prefix, _ := (*m)["keyPrefix"].(string)
+	return prefix
+}
+
+// Fields decodes the field metadata of the described SObject.
+func (m *SObjectMeta) Fields() ([]FieldMeta, error) {
+	raw, ok := (*m)["fields"]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []FieldMeta
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// ChildRelationship describes a relationship from another SObject to the described SObject, which can be queried with
+// a subquery such as "SELECT Name, (SELECT Name FROM Contacts) FROM Account".
+type ChildRelationship struct {
+	ChildSObject     string `json:"childSObject"`
+	Field            string `json:"field"`
+	RelationshipName string `json:"relationshipName"`
+}
+
+// ChildRelationships decodes the child relationships of the described SObject.
+func (m *SObjectMeta) ChildRelationships() ([]ChildRelationship, error) {
+	raw, ok := (*m)["childRelationships"]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var relationships []ChildRelationship
+	err = json.Unmarshal(data, &relationships)
+	if err != nil {
+		return nil, err
+	}
+
+	return relationships, nil
+}
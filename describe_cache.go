@@ -0,0 +1,245 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce/internal/atomicfile"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultDescribeMaxAge is how long DescribeCache serves metadata before revalidating it with salesforce.
+const DefaultDescribeMaxAge = time.Hour
+
+// describeTimeout bounds a describe shared by concurrent callers. It runs detached from their contexts, so one caller
+// giving up doesn't fail the others.
+const describeTimeout = time.Minute
+
+// describeGlobalKey is the cache key of the DescribeGlobal result. SObject names can't begin with an underscore.
+const describeGlobalKey = "_global"
+
+// DescribeCache caches describe metadata in memory and, optionally, on disk. Entries are keyed by org ID, API
+// version and SObject type. Stale entries are revalidated with If-Modified-Since so unchanged metadata isn't
+// downloaded again, and concurrent describes of the same SObject share a single request.
+// The returned metadata is shared between callers and must not be modified.
+type DescribeCache struct {
+	client     ConditionalDescriber
+	orgID      string
+	apiVersion string
+	dir        string
+	maxAge     time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]*describeCacheEntry
+	group   singleflight.Group
+}
+
+type describeCacheEntry struct {
+	Meta         *SObjectMeta `json:"meta"`
+	LastModified time.Time    `json:"lastModified"`
+	CheckedAt    time.Time    `json:"checkedAt"`
+}
+
+var _ SObjectDescriber = (*DescribeCache)(nil)
+
+// NewDescribeCache creates a new DescribeCache. If dir is not empty, entries are persisted as JSON files below dir
+// and survive restarts. If maxAge is not positive, DefaultDescribeMaxAge is used.
+func NewDescribeCache(client ConditionalDescriber, orgID, apiVersion, dir string, maxAge time.Duration) *DescribeCache {
+	if maxAge <= 0 {
+		maxAge = DefaultDescribeMaxAge
+	}
+
+	return &DescribeCache{
+		client:     client,
+		orgID:      orgID,
+		apiVersion: apiVersion,
+		dir:        dir,
+		maxAge:     maxAge,
+		entries:    make(map[string]*describeCacheEntry),
+	}
+}
+
+// DescribeSObject returns the cached metadata of an SObject, describing it if needed.
+func (c *DescribeCache) DescribeSObject(ctx context.Context, sobj *SObject) (*SObjectMeta, error) {
+	if len(sobj.Type()) == 0 {
+		return nil, ErrInvalidSObject{"Type is empty"}
+	}
+
+	return c.get(ctx, sobj.Type(), func(ctx context.Context, since time.Time) (*DescribeResult, error) {
+		return c.client.DescribeSObjectIfModified(ctx, sobj, since)
+	})
+}
+
+// DescribeGlobal returns the cached list of all available objects, describing them if needed.
+func (c *DescribeCache) DescribeGlobal(ctx context.Context) (*SObjectMeta, error) {
+	return c.get(ctx, describeGlobalKey, func(ctx context.Context, since time.Time) (*DescribeResult, error) {
+		return c.client.DescribeGlobalIfModified(ctx, since)
+	})
+}
+
+// Invalidate drops the cached metadata of an SObject type, both in memory and on disk.
+func (c *DescribeCache) Invalidate(typeName string) error {
+	c.mu.Lock()
+	delete(c.entries, typeName)
+	c.mu.Unlock()
+
+	path, ok := c.path(typeName)
+	if !ok {
+		return nil
+	}
+
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *DescribeCache) get(ctx context.Context, key string, describe func(ctx context.Context, since time.Time) (*DescribeResult, error)) (*SObjectMeta, error) {
+	entry := c.lookup(key)
+	if entry != nil && time.Since(entry.CheckedAt) < c.maxAge {
+		return entry.Meta, nil
+	}
+
+	results := c.group.DoChan(key, func() (interface{}, error) {
+		var since time.Time
+		if entry != nil {
+			since = entry.LastModified
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), describeTimeout)
+		defer cancel()
+
+		res, err := describe(ctx, since)
+		if err != nil {
+			return nil, err
+		}
+
+		next := &describeCacheEntry{
+			Meta:         res.Meta,
+			LastModified: res.LastModified,
+			CheckedAt:    time.Now(),
+		}
+
+		if res.NotModified && entry != nil {
+			next.Meta = entry.Meta
+			next.LastModified = entry.LastModified
+		}
+
+		if next.LastModified.IsZero() {
+			next.LastModified = next.CheckedAt
+		}
+
+		c.store(key, next)
+
+		return next.Meta, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-results:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		return res.Val.(*SObjectMeta), nil
+	}
+}
+
+// lookup returns the cached entry for key from memory, falling back to disk.
+func (c *DescribeCache) lookup(key string) *describeCacheEntry {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if ok {
+		return entry
+	}
+
+	path, ok := c.path(key)
+	if !ok {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	entry = &describeCacheEntry{}
+	// A corrupt file is treated as a cache miss and overwritten by the next describe.
+	if json.Unmarshal(data, entry) != nil || entry.Meta == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	return entry
+}
+
+// store saves entry in memory and, if enabled, on disk. Failing to write to disk doesn't fail the describe.
+func (c *DescribeCache) store(key string, entry *describeCacheEntry) {
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	path, ok := c.path(key)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return
+	}
+
+	// Processes sharing the directory each write a temporary file of their own, so readers never see a partial file.
+	_ = atomicfile.Write(path, data)
+}
+
+// path returns the location of the cache file for key. ok is false if entries aren't persisted, or if the org ID, API
+// version or key can't be used as a file name, so a type name never addresses a file outside of the directory.
+func (c *DescribeCache) path(key string) (path string, ok bool) {
+	if len(c.dir) == 0 {
+		return "", false
+	}
+
+	for _, segment := range []string{c.orgID, c.apiVersion, key} {
+		if !isCacheSegment(segment) {
+			return "", false
+		}
+	}
+
+	return filepath.Join(c.dir, c.orgID, c.apiVersion, key+".json"), true
+}
+
+// isCacheSegment reports whether s only holds the letters, digits, underscores, dots and dashes found in org IDs, API
+// versions and SObject names, and isn't a relative path.
+func isCacheSegment(s string) bool {
+	if len(s) == 0 || s == "." || s == ".." {
+		return false
+	}
+
+	for _, r := range s {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_', r == '.', r == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,159 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDescribeServer(t *testing.T, requests *int32) *httptest.Server {
+	lastModified := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		// Give concurrent describes a chance to pile up.
+		time.Sleep(10 * time.Millisecond)
+
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+		err := json.NewEncoder(w).Encode(&SObjectMeta{"name": "Case"})
+		assert.NoError(t, err)
+	}))
+}
+
+func TestDescribeCache_DescribeSObject(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int32
+	ts := newDescribeServer(t, &requests)
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	cache := NewDescribeCache(client, "org1", DefaultAPIVersion, "", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			meta, err := cache.DescribeSObject(context.Background(), NewSObject("Case"))
+			assert.NoError(err)
+			assert.Equal("Case", meta.Name())
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestDescribeCache_revalidate(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int32
+	ts := newDescribeServer(t, &requests)
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	cache := NewDescribeCache(client, "org1", DefaultAPIVersion, "", time.Nanosecond)
+
+	meta, err := cache.DescribeSObject(context.Background(), NewSObject("Case"))
+	assert.NoError(err)
+
+	revalidated, err := cache.DescribeSObject(context.Background(), NewSObject("Case"))
+	assert.NoError(err)
+
+	// The second describe was answered with 304 and served from the cache.
+	assert.Equal(int32(2), atomic.LoadInt32(&requests))
+	assert.Same(meta, revalidated)
+}
+
+func TestDescribeCache_dir(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int32
+	ts := newDescribeServer(t, &requests)
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	dir := t.TempDir()
+
+	_, err := NewDescribeCache(client, "org1", DefaultAPIVersion, dir, 0).DescribeGlobal(context.Background())
+	assert.NoError(err)
+
+	// A new cache sharing the directory doesn't describe again.
+	cache := NewDescribeCache(client, "org1", DefaultAPIVersion, dir, 0)
+
+	meta, err := cache.DescribeGlobal(context.Background())
+	assert.NoError(err)
+	assert.Equal("Case", meta.Name())
+	assert.Equal(int32(1), atomic.LoadInt32(&requests))
+
+	// A different org doesn't share entries.
+	_, err = NewDescribeCache(client, "org2", DefaultAPIVersion, dir, 0).DescribeGlobal(context.Background())
+	assert.NoError(err)
+	assert.Equal(int32(2), atomic.LoadInt32(&requests))
+
+	err = cache.Invalidate(describeGlobalKey)
+	assert.NoError(err)
+
+	_, err = cache.DescribeGlobal(context.Background())
+	assert.NoError(err)
+	assert.Equal(int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestDescribeCache_cancelled_caller(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int32
+	ts := newDescribeServer(t, &requests)
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	cache := NewDescribeCache(client, "org1", DefaultAPIVersion, "", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err := cache.DescribeSObject(ctx, NewSObject("Case"))
+	assert.Equal(context.DeadlineExceeded, err)
+
+	// The describe started by the cancelled caller still completes and is shared.
+	meta, err := cache.DescribeSObject(context.Background(), NewSObject("Case"))
+	assert.NoError(err)
+	assert.Equal("Case", meta.Name())
+	assert.Equal(int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestDescribeCache_unsafe_type_name(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests int32
+	ts := newDescribeServer(t, &requests)
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache := NewDescribeCache(client, "org1", DefaultAPIVersion, dir, 0)
+
+	_, err := cache.DescribeSObject(context.Background(), NewSObject("../../../Case"))
+	assert.NoError(err)
+
+	// The entry is only cached in memory.
+	_, err = os.Stat(filepath.Join(dir, "..", "Case.json"))
+	assert.True(os.IsNotExist(err))
+
+	_, err = cache.DescribeSObject(context.Background(), NewSObject("../../../Case"))
+	assert.NoError(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&requests))
+}
@@ -4,6 +4,11 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -21,6 +26,150 @@ func (e ErrInvalidSObject) Error() string {
 	return fmt.Sprintf("invalid sobject: %s", e.msg)
 }
 
+// ErrInvalidField is returned when a field of an SObject can't be accessed as the requested type.
+type ErrInvalidField struct {
+	field string
+	msg   string
+}
+
+func (e ErrInvalidField) Error() string {
+	return fmt.Sprintf("invalid field %s: %s", e.field, e.msg)
+}
+
+// ErrInvalidID is returned when a string isn't a well-formed salesforce ID.
+type ErrInvalidID struct {
+	id string
+}
+
+func (e ErrInvalidID) Error() string {
+	return fmt.Sprintf("invalid salesforce ID: %q", e.id)
+}
+
+// ErrUnknownKeyPrefix is returned when no SObject type has the key prefix of an ID.
+type ErrUnknownKeyPrefix struct {
+	prefix string
+}
+
+func (e ErrUnknownKeyPrefix) Error() string {
+	return fmt.Sprintf("unknown key prefix: %s", e.prefix)
+}
+
+// ErrUnresolvedReferences is returned by FetchReferences for IDs whose SObject type couldn't be resolved. Errors holds
+// the error of each ID.
+type ErrUnresolvedReferences struct {
+	Errors map[ID]error
+}
+
+func (e ErrUnresolvedReferences) Error() string {
+	ids := make([]string, 0, len(e.Errors))
+	for id := range e.Errors {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+
+	msgs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		msgs = append(msgs, id+": "+e.Errors[ID(id)].Error())
+	}
+
+	return fmt.Sprintf("unresolved references: %s", strings.Join(msgs, "; "))
+}
+
+func (e *ErrUnresolvedReferences) add(id ID, err error) {
+	if e.Errors == nil {
+		e.Errors = make(map[ID]error)
+	}
+	e.Errors[id] = err
+}
+
+// ErrMultipleChoices is returned when an external ID matches more than one record.
+// IDs holds the salesforce IDs of the matching records.
+type ErrMultipleChoices struct {
+	IDs []string
+}
+
+func (e ErrMultipleChoices) Error() string {
+	return fmt.Sprintf("external ID matches multiple records: %s", strings.Join(e.IDs, ", "))
+}
+
+// ErrPreconditionFailed is returned when a conditional request is rejected because the record was modified.
+// Message holds the message returned by salesforce.
+type ErrPreconditionFailed struct {
+	Message string
+}
+
+func (e ErrPreconditionFailed) Error() string {
+	return fmt.Sprintf("precondition failed: %s", e.Message)
+}
+
+// ErrAPI is returned for error responses of the salesforce API. StatusCode is the HTTP status of the response, and
+// ErrorCode and Message describe the first error it reports, such as REQUIRED_FIELD_MISSING.
+type ErrAPI struct {
+	StatusCode int
+	ErrorCode  string
+	Message    string
+}
+
+func (e ErrAPI) Error() string {
+	return fmt.Sprintf(logPrefix+" Error. http code: %v Error Message:  %v Error Code: %v", e.StatusCode, e.Message, e.ErrorCode)
+}
+
+// ErrStreaming is returned when the streaming API rejects a message, such as a handshake or subscription.
+type ErrStreaming struct {
+	Channel string
+	Message string
+}
+
+func (e ErrStreaming) Error() string {
+	return fmt.Sprintf("streaming %s failed: %s", e.Channel, e.Message)
+}
+
+// ErrBulkJob is returned when a Bulk API job fails or is aborted.
+type ErrBulkJob struct {
+	ID      string
+	State   string
+	Message string
+}
+
+func (e ErrBulkJob) Error() string {
+	return fmt.Sprintf("bulk job %s %s: %s", e.ID, strings.ToLower(e.State), e.Message)
+}
+
+// ErrReplicationWindow is returned by GetUpdated and GetDeleted for time windows salesforce would reject.
+type ErrReplicationWindow struct {
+	Start   time.Time
+	End     time.Time
+	Message string
+}
+
+func (e ErrReplicationWindow) Error() string {
+	return fmt.Sprintf("invalid replication window %s to %s: %s", e.Start.Format(time.RFC3339), e.End.Format(time.RFC3339), e.Message)
+}
+
+// FieldError describes a problem with a single field of an SObject.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ErrValidation is returned when an SObject fails validation against its describe metadata.
+type ErrValidation struct {
+	Errors []FieldError
+}
+
+func (e ErrValidation) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+
+	return fmt.Sprintf("validation failed: %s", strings.Join(msgs, "; "))
+}
+
+func (e *ErrValidation) add(field, msg string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: msg})
+}
+
 type jsonError []struct {
 	Message   string `json:"message"`
 	ErrorCode string `json:"errorCode"`
@@ -32,6 +181,21 @@ type xmlError struct {
 }
 
 func parseSalesforceError(statusCode int, responseBody []byte) (err error) {
+	if statusCode == http.StatusMultipleChoices {
+		return parseMultipleChoices(responseBody)
+	}
+
+	if statusCode == http.StatusPreconditionFailed {
+		msg := "record was modified"
+
+		jsonError := jsonError{}
+		if json.Unmarshal(responseBody, &jsonError) == nil && len(jsonError) > 0 {
+			msg = jsonError[0].Message
+		}
+
+		return ErrPreconditionFailed{Message: msg}
+	}
+
 	jsonError := jsonError{}
 	xmlError := xmlError{}
 	err = json.Unmarshal(responseBody, &jsonError)
@@ -42,13 +206,25 @@ func parseSalesforceError(statusCode int, responseBody []byte) (err error) {
 			return ErrFailure
 		}
 		//successfully parsed XML:
-		message := fmt.Sprintf(logPrefix+" Error. http code: %v Error Message:  %v Error Code: %v", statusCode, xmlError.Message, xmlError.ErrorCode)
-		err = errors.New(message)
-		return err
+		return ErrAPI{StatusCode: statusCode, ErrorCode: xmlError.ErrorCode, Message: xmlError.Message}
 	} else {
 		//Successfully parsed json error:
-		message := fmt.Sprintf(logPrefix+" Error. http code: %v Error Message:  %v Error Code: %v", statusCode, jsonError[0].Message, jsonError[0].ErrorCode)
-		err = errors.New(message)
-		return err
+		return ErrAPI{StatusCode: statusCode, ErrorCode: jsonError[0].ErrorCode, Message: jsonError[0].Message}
+	}
+}
+
+// parseMultipleChoices decodes the list of record URLs returned with a 300 response.
+func parseMultipleChoices(responseBody []byte) error {
+	var urls []string
+	err := json.Unmarshal(responseBody, &urls)
+	if err != nil {
+		return ErrFailure
+	}
+
+	e := ErrMultipleChoices{}
+	for _, u := range urls {
+		e.IDs = append(e.IDs, path.Base(u))
 	}
+
+	return e
 }
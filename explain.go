@@ -0,0 +1,84 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// Leading operation types of a QueryPlan.
+const (
+	PlanIndex     = "Index"
+	PlanOther     = "Other"
+	PlanSharing   = "Sharing"
+	PlanTableScan = "TableScan"
+)
+
+// QueryPlan is one of the plans salesforce considered for a query.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_query_explain.htm
+type QueryPlan struct {
+	// Cardinality is the estimated number of records the leading operation returns.
+	Cardinality int `json:"cardinality"`
+	// Fields are the indexed fields used by the plan, if the leading operation is Index.
+	Fields []string `json:"fields"`
+	// LeadingOperationType is the primary operation of the plan: Index, Other, Sharing or TableScan.
+	LeadingOperationType string          `json:"leadingOperationType"`
+	Notes                []QueryPlanNote `json:"notes"`
+	// RelativeCost is the cost of the plan relative to the selectivity threshold. Plans above 1 are not selective.
+	RelativeCost       float64 `json:"relativeCost"`
+	SObjectCardinality int     `json:"sobjectCardinality"`
+	SObjectType        string  `json:"sobjectType"`
+}
+
+// QueryPlanNote explains why an optimization wasn't used by a plan, such as a filter on an unindexed field.
+type QueryPlanNote struct {
+	Description   string   `json:"description"`
+	Fields        []string `json:"fields"`
+	TableEnumOrID string   `json:"tableEnumOrId"`
+}
+
+// ExplainResult holds the plans salesforce considered for a query, sorted from the cheapest.
+type ExplainResult struct {
+	Plans       []QueryPlan `json:"plans"`
+	SourceQuery string      `json:"sourceQuery"`
+}
+
+// BestPlan returns the plan with the lowest relative cost, which is the one salesforce uses. Nil is returned if there
+// are no plans.
+func (r *ExplainResult) BestPlan() *QueryPlan {
+	var best *QueryPlan
+	for i := range r.Plans {
+		if best == nil || r.Plans[i].RelativeCost < best.RelativeCost {
+			best = &r.Plans[i]
+		}
+	}
+
+	return best
+}
+
+// Explainer explains how salesforce runs queries. HTTPClient implements Explainer.
+type Explainer interface {
+	Explain(ctx context.Context, query string) (*ExplainResult, error)
+}
+
+// Explain returns the plans salesforce considers for an SOQL query without running it. The ID of a report or list
+// view can be given instead of a query.
+func (h *HTTPClient) Explain(ctx context.Context, query string) (*ExplainResult, error) {
+	url := h.makeURL("query/?explain=" + url.QueryEscape(query))
+
+	res, err := h.request(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	result := &ExplainResult{}
+
+	err = json.NewDecoder(res.Body).Decode(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package simpleforce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClient_Explain(t *testing.T) {
+	assert := assert.New(t)
+
+	query := "SELECT Id FROM Account WHERE Industry = 'Energy'"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodGet, r.Method)
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/query/", r.URL.Path)
+		assert.Equal(query, r.URL.Query().Get("explain"))
+
+		w.Write([]byte(`{
+			"plans": [
+				{"cardinality": 10000, "fields": [], "leadingOperationType": "TableScan", "notes": [
+					{"description": "Not considering filter for optimization because unindexed", "fields": ["Industry"], "tableEnumOrId": "Account"}
+				], "relativeCost": 2.8, "sobjectCardinality": 100000, "sobjectType": "Account"},
+				{"cardinality": 500, "fields": ["Industry"], "leadingOperationType": "Index", "notes": [],
+					"relativeCost": 0.3, "sobjectCardinality": 100000, "sobjectType": "Account"}
+			],
+			"sourceQuery": "SELECT Id FROM Account WHERE Industry = 'Energy'"
+		}`))
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	result, err := client.Explain(context.Background(), query)
+	assert.NoError(err)
+	assert.Equal(query, result.SourceQuery)
+	assert.Len(result.Plans, 2)
+	assert.Equal([]QueryPlanNote{{
+		Description:   "Not considering filter for optimization because unindexed",
+		Fields:        []string{"Industry"},
+		TableEnumOrID: "Account",
+	}}, result.Plans[0].Notes)
+
+	best := result.BestPlan()
+	assert.Equal(PlanIndex, best.LeadingOperationType)
+	assert.Equal([]string{"Industry"}, best.Fields)
+	assert.Equal(500, best.Cardinality)
+	assert.Equal(100000, best.SObjectCardinality)
+
+	assert.Nil((&ExplainResult{}).BestPlan())
+}
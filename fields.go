@@ -0,0 +1,393 @@
+package simpleforce
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// dateLayout is the format salesforce uses for date fields.
+	dateLayout = "2006-01-02"
+	// timeOfDayLayout is the format salesforce uses for time fields.
+	timeOfDayLayout = "15:04:05.000Z"
+)
+
+// Address is the value of a compound address field.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/compound_fields_address.htm
+type Address struct {
+	Street          string   `json:"street,omitempty"`
+	City            string   `json:"city,omitempty"`
+	State           string   `json:"state,omitempty"`
+	StateCode       string   `json:"stateCode,omitempty"`
+	PostalCode      string   `json:"postalCode,omitempty"`
+	Country         string   `json:"country,omitempty"`
+	CountryCode     string   `json:"countryCode,omitempty"`
+	Latitude        *float64 `json:"latitude,omitempty"`
+	Longitude       *float64 `json:"longitude,omitempty"`
+	GeocodeAccuracy string   `json:"geocodeAccuracy,omitempty"`
+}
+
+// Location is the value of a compound geolocation field.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/compound_fields_geolocation.htm
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// BoolField accesses a checkbox field in the SObject as bool. False is returned if the field doesn't exist or isn't a
+// bool.
+func (s *SObject) BoolField(key string) bool {
+	v, _ := s.BoolFieldE(key)
+	return v
+}
+
+// BoolFieldE accesses a checkbox field in the SObject as bool. False is returned if the field doesn't exist.
+func (s *SObject) BoolFieldE(key string) (bool, error) {
+	switch v := s.InterfaceField(key).(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fieldTypeError(key, v, "bool")
+	}
+}
+
+// SetBool sets a checkbox field.
+func (s *SObject) SetBool(key string, value bool) *SObject {
+	return s.Set(key, value)
+}
+
+// IntField accesses a number field without decimal places in the SObject as int. Zero is returned if the field
+// doesn't exist or isn't an integer.
+func (s *SObject) IntField(key string) int {
+	v, _ := s.IntFieldE(key)
+	return v
+}
+
+// IntFieldE accesses a number field without decimal places in the SObject as int. Zero is returned if the field
+// doesn't exist.
+func (s *SObject) IntFieldE(key string) (int, error) {
+	f, err := s.Float64FieldE(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if f != math.Trunc(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, fieldTypeError(key, s.InterfaceField(key), "int")
+	}
+
+	return int(f), nil
+}
+
+// SetInt sets a number field without decimal places.
+func (s *SObject) SetInt(key string, value int) *SObject {
+	return s.Set(key, value)
+}
+
+// Float64Field accesses a number, currency or percent field in the SObject as float64. Zero is returned if the field
+// doesn't exist or isn't a number.
+func (s *SObject) Float64Field(key string) float64 {
+	v, _ := s.Float64FieldE(key)
+	return v
+}
+
+// Float64FieldE accesses a number, currency or percent field in the SObject as float64. Zero is returned if the field
+// doesn't exist.
+func (s *SObject) Float64FieldE(key string) (float64, error) {
+	switch v := s.InterfaceField(key).(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fieldTypeError(key, v, "float64")
+		}
+		return f, nil
+	default:
+		return 0, fieldTypeError(key, v, "float64")
+	}
+}
+
+// SetFloat64 sets a number, currency or percent field.
+func (s *SObject) SetFloat64(key string, value float64) *SObject {
+	return s.Set(key, value)
+}
+
+// DecimalField accesses a number, currency or percent field in the SObject as a decimal string, such as "1234.56",
+// without the rounding artifacts of formatting a float64. Empty string is returned if the field doesn't exist or
+// isn't a number. Records are decoded with numbers as float64, which only holds about 15 significant digits; values
+// set with SetDecimal or decoded as json.Number are returned verbatim.
+func (s *SObject) DecimalField(key string) string {
+	v, _ := s.DecimalFieldE(key)
+	return v
+}
+
+// DecimalFieldE accesses a number, currency or percent field in the SObject as a decimal string. Empty string is
+// returned if the field doesn't exist.
+func (s *SObject) DecimalFieldE(key string) (string, error) {
+	switch v := s.InterfaceField(key).(type) {
+	case nil:
+		return "", nil
+	case json.Number:
+		return v.String(), nil
+	case string:
+		_, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", fieldTypeError(key, v, "decimal")
+		}
+		return v, nil
+	default:
+		f, err := s.Float64FieldE(key)
+		if err != nil {
+			return "", fieldTypeError(key, v, "decimal")
+		}
+		// The shortest representation that round trips avoids artifacts such as 0.30000000000000004. It matches the
+		// decimal salesforce sent unless that had more digits than float64 holds.
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+}
+
+// SetDecimal sets a number, currency or percent field from a decimal string, such as "1234.56", which is sent to
+// salesforce verbatim. Writing the SObject fails if value isn't a valid number.
+func (s *SObject) SetDecimal(key string, value string) *SObject {
+	return s.Set(key, json.Number(value))
+}
+
+// DateField accesses a date field in the SObject. The zero time is returned if the field doesn't exist or isn't a
+// date.
+func (s *SObject) DateField(key string) time.Time {
+	v, _ := s.DateFieldE(key)
+	return v
+}
+
+// DateFieldE accesses a date field in the SObject. The zero time is returned if the field doesn't exist.
+func (s *SObject) DateFieldE(key string) (time.Time, error) {
+	return s.parseTimeField(key, "date", dateLayout)
+}
+
+// SetDate sets a date field. Only the date of value is sent to salesforce.
+func (s *SObject) SetDate(key string, value time.Time) *SObject {
+	return s.Set(key, value.Format(dateLayout))
+}
+
+// DateTimeField accesses a datetime field in the SObject. The zero time is returned if the field doesn't exist or
+// isn't a datetime.
+func (s *SObject) DateTimeField(key string) time.Time {
+	v, _ := s.DateTimeFieldE(key)
+	return v
+}
+
+// DateTimeFieldE accesses a datetime field in the SObject. The zero time is returned if the field doesn't exist.
+func (s *SObject) DateTimeFieldE(key string) (time.Time, error) {
+	return s.parseTimeField(key, "datetime", dateTimeLayout, time.RFC3339Nano)
+}
+
+// SetDateTime sets a datetime field. The value is sent to salesforce in UTC.
+func (s *SObject) SetDateTime(key string, value time.Time) *SObject {
+	return s.Set(key, value.UTC().Format(dateTimeLayout))
+}
+
+// TimeOfDayField accesses a time field in the SObject. The returned time is on January 1, year 0, UTC. The zero time
+// is returned if the field doesn't exist or isn't a time.
+func (s *SObject) TimeOfDayField(key string) time.Time {
+	v, _ := s.TimeOfDayFieldE(key)
+	return v
+}
+
+// TimeOfDayFieldE accesses a time field in the SObject. The returned time is on January 1, year 0, UTC. The zero
+// time is returned if the field doesn't exist.
+func (s *SObject) TimeOfDayFieldE(key string) (time.Time, error) {
+	return s.parseTimeField(key, "time", timeOfDayLayout, "15:04:05Z", "15:04:05")
+}
+
+// SetTimeOfDay sets a time field. Only the time of day of value is sent to salesforce, in UTC.
+func (s *SObject) SetTimeOfDay(key string, value time.Time) *SObject {
+	return s.Set(key, value.UTC().Format(timeOfDayLayout))
+}
+
+// MultiPicklistField accesses a multi-select picklist field in the SObject as the list of selected values. Nil is
+// returned if the field doesn't exist or isn't a multi-select picklist.
+func (s *SObject) MultiPicklistField(key string) []string {
+	v, _ := s.MultiPicklistFieldE(key)
+	return v
+}
+
+// MultiPicklistFieldE accesses a multi-select picklist field in the SObject as the list of selected values. Nil is
+// returned if the field doesn't exist.
+func (s *SObject) MultiPicklistFieldE(key string) ([]string, error) {
+	switch v := s.InterfaceField(key).(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		return strings.Split(v, ";"), nil
+	default:
+		return nil, fieldTypeError(key, v, "multi-select picklist")
+	}
+}
+
+// SetMultiPicklist sets a multi-select picklist field to the given values.
+func (s *SObject) SetMultiPicklist(key string, values []string) *SObject {
+	return s.Set(key, strings.Join(values, ";"))
+}
+
+// AddressField accesses a compound address field, such as MailingAddress, in the SObject. Nil is returned if the
+// field doesn't exist or isn't an address.
+func (s *SObject) AddressField(key string) *Address {
+	v, _ := s.AddressFieldE(key)
+	return v
+}
+
+// AddressFieldE accesses a compound address field, such as MailingAddress, in the SObject. Nil is returned if the
+// field doesn't exist.
+func (s *SObject) AddressFieldE(key string) (*Address, error) {
+	addr := &Address{}
+
+	ok, err := s.decodeCompoundField(key, addr)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	return addr, nil
+}
+
+// SetAddress sets the component fields of a compound address field, such as MailingStreet and MailingCity for
+// MailingAddress. Compound fields are read-only, so the address is written through its components. Empty components
+// aren't set, so they keep their value on salesforce; clear them with SetNull.
+func (s *SObject) SetAddress(key string, value *Address) *SObject {
+	prefix := strings.TrimSuffix(key, "Address")
+
+	components := []struct {
+		name  string
+		value string
+	}{
+		{"Street", value.Street},
+		{"City", value.City},
+		{"State", value.State},
+		{"StateCode", value.StateCode},
+		{"PostalCode", value.PostalCode},
+		{"Country", value.Country},
+		{"CountryCode", value.CountryCode},
+	}
+
+	for _, component := range components {
+		if len(component.value) > 0 {
+			s.Set(prefix+component.name, component.value)
+		}
+	}
+
+	if value.Latitude != nil && value.Longitude != nil {
+		s.Set(prefix+"Latitude", *value.Latitude).
+			Set(prefix+"Longitude", *value.Longitude)
+	}
+	if len(value.GeocodeAccuracy) > 0 {
+		s.Set(prefix+"GeocodeAccuracy", value.GeocodeAccuracy)
+	}
+
+	return s
+}
+
+// GeolocationField accesses a compound geolocation field in the SObject. Nil is returned if the field doesn't exist
+// or isn't a geolocation.
+func (s *SObject) GeolocationField(key string) *Location {
+	v, _ := s.GeolocationFieldE(key)
+	return v
+}
+
+// GeolocationFieldE accesses a compound geolocation field in the SObject. Nil is returned if the field doesn't exist.
+func (s *SObject) GeolocationFieldE(key string) (*Location, error) {
+	loc := &Location{}
+
+	ok, err := s.decodeCompoundField(key, loc)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	return loc, nil
+}
+
+// SetGeolocation sets the component fields of a compound geolocation field, such as Location__Latitude__s and
+// Location__Longitude__s for Location__c. Compound fields are read-only, so the location is written through its
+// components.
+func (s *SObject) SetGeolocation(key string, value *Location) *SObject {
+	latitude, longitude := "Latitude", "Longitude"
+
+	prefix := key
+	if strings.HasSuffix(key, "__c") {
+		prefix = strings.TrimSuffix(key, "c")
+		latitude, longitude = "Latitude__s", "Longitude__s"
+	}
+
+	return s.Set(prefix+latitude, value.Latitude).
+		Set(prefix+longitude, value.Longitude)
+}
+
+// parseTimeField parses a string field with the first of layouts that matches.
+func (s *SObject) parseTimeField(key, typeName string, layouts ...string) (time.Time, error) {
+	switch v := s.InterfaceField(key).(type) {
+	case nil:
+		return time.Time{}, nil
+	case string:
+		if len(v) == 0 {
+			return time.Time{}, nil
+		}
+
+		for _, layout := range layouts {
+			t, err := time.Parse(layout, v)
+			if err == nil {
+				return t, nil
+			}
+		}
+
+		return time.Time{}, fieldTypeError(key, v, typeName)
+	case time.Time:
+		return v, nil
+	default:
+		return time.Time{}, fieldTypeError(key, v, typeName)
+	}
+}
+
+// decodeCompoundField decodes a compound field into out. False is returned if the field doesn't exist.
+func (s *SObject) decodeCompoundField(key string, out interface{}) (bool, error) {
+	v := s.InterfaceField(key)
+	if v == nil {
+		return false, nil
+	}
+
+	if _, ok := v.(map[string]interface{}); !ok {
+		return false, fieldTypeError(key, v, "compound field")
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	err = json.Unmarshal(data, out)
+	if err != nil {
+		return false, fieldTypeError(key, v, "compound field")
+	}
+
+	return true, nil
+}
+
+func fieldTypeError(key string, value interface{}, typeName string) error {
+	return ErrInvalidField{key, fmt.Sprintf("cannot convert %v (%T) to %s", value, value, typeName)}
+}
@@ -0,0 +1,133 @@
+package simpleforce
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeSObject(t *testing.T, data string) *SObject {
+	obj := &SObject{}
+
+	err := json.Unmarshal([]byte(data), obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return obj
+}
+
+func TestSObject_scalarFields(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := decodeSObject(t, `{
+		"IsClosed": true,
+		"NumberOfEmployees": 120,
+		"Amount": 1234.56,
+		"Big__c": 12345678901234.57,
+		"Name": "Acme"
+	}`)
+
+	assert.True(obj.BoolField("IsClosed"))
+	assert.Equal(120, obj.IntField("NumberOfEmployees"))
+	assert.Equal(1234.56, obj.Float64Field("Amount"))
+	assert.Equal("1234.56", obj.DecimalField("Amount"))
+	assert.Equal("12345678901234.57", obj.DecimalField("Big__c"))
+
+	_, err := obj.IntFieldE("Amount")
+	assert.IsType(ErrInvalidField{}, err)
+
+	_, err = obj.BoolFieldE("Name")
+	assert.IsType(ErrInvalidField{}, err)
+
+	v, err := obj.Float64FieldE("Missing")
+	assert.NoError(err)
+	assert.Zero(v)
+
+	obj.SetDecimal("Amount", "0.10")
+	data, err := json.Marshal(obj)
+	assert.NoError(err)
+	assert.Contains(string(data), `"Amount":0.10`)
+}
+
+func TestSObject_timeFields(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := decodeSObject(t, `{
+		"ActivityDate": "2021-08-01",
+		"CreatedDate": "2021-08-01T12:30:15.000+0000",
+		"Start__c": "09:15:00.000Z",
+		"Bad__c": "yesterday"
+	}`)
+
+	assert.Equal(time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC), obj.DateField("ActivityDate"))
+	assert.True(time.Date(2021, 8, 1, 12, 30, 15, 0, time.UTC).Equal(obj.DateTimeField("CreatedDate")))
+	assert.Equal(time.Date(0, 1, 1, 9, 15, 0, 0, time.UTC), obj.TimeOfDayField("Start__c"))
+
+	_, err := obj.DateTimeFieldE("Bad__c")
+	assert.IsType(ErrInvalidField{}, err)
+
+	est := time.FixedZone("EST", -5*60*60)
+	obj.SetDateTime("CreatedDate", time.Date(2021, 8, 1, 7, 30, 15, 0, est)).
+		SetDate("ActivityDate", time.Date(2021, 9, 2, 23, 0, 0, 0, est)).
+		SetTimeOfDay("Start__c", time.Date(0, 1, 1, 12, 5, 0, 0, est))
+
+	assert.Equal("2021-08-01T12:30:15.000+0000", obj.StringField("CreatedDate"))
+	assert.Equal("2021-09-02", obj.StringField("ActivityDate"))
+	assert.Equal("17:05:00.000Z", obj.StringField("Start__c"))
+}
+
+func TestSObject_MultiPicklistField(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := NewSObject("Contact").
+		SetMultiPicklist("Languages__c", []string{"English", "Spanish"})
+
+	assert.Equal("English;Spanish", obj.StringField("Languages__c"))
+	assert.Equal([]string{"English", "Spanish"}, obj.MultiPicklistField("Languages__c"))
+	assert.Nil(obj.MultiPicklistField("Missing__c"))
+}
+
+func TestSObject_compoundFields(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := decodeSObject(t, `{
+		"MailingAddress": {
+			"street": "1 Main St",
+			"city": "Boston",
+			"state": "MA",
+			"postalCode": "02110",
+			"country": "USA",
+			"latitude": 42.35,
+			"longitude": -71.05
+		},
+		"Office__c": {"latitude": 42.35, "longitude": -71.05},
+		"Name": "Acme"
+	}`)
+
+	addr := obj.AddressField("MailingAddress")
+	assert.Equal("Boston", addr.City)
+	assert.Equal(42.35, *addr.Latitude)
+
+	assert.Equal(&Location{Latitude: 42.35, Longitude: -71.05}, obj.GeolocationField("Office__c"))
+
+	_, err := obj.AddressFieldE("Name")
+	assert.IsType(ErrInvalidField{}, err)
+
+	out := NewSObject("Contact").
+		SetAddress("MailingAddress", addr).
+		SetGeolocation("Office__c", &Location{Latitude: 1, Longitude: 2})
+
+	assert.Equal("1 Main St", out.StringField("MailingStreet"))
+	assert.Equal("02110", out.StringField("MailingPostalCode"))
+	assert.NotContains(*out, "MailingCountryCode")
+
+	partial := NewSObject("Contact").SetAddress("MailingAddress", &Address{Street: "2 Main St"})
+	assert.Equal("2 Main St", partial.StringField("MailingStreet"))
+	assert.NotContains(*partial, "MailingCity")
+	assert.Equal(42.35, out.Float64Field("MailingLatitude"))
+	assert.Equal(1.0, out.Float64Field("Office__Latitude__s"))
+	assert.Equal(2.0, out.Float64Field("Office__Longitude__s"))
+}
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
 const (
@@ -29,20 +30,33 @@ type Client interface {
 	CreateSObject(ctx context.Context, sobj *SObject, blacklistedFields []string, allowDuplicates bool, autoAssign *bool) error
 	GetSObject(ctx context.Context, sobj *SObject) error
 	UpdateSObject(ctx context.Context, sobj *SObject, blacklistedFields []string, autoAssign *bool) error
-	UpsertSObject(ctx context.Context, sobject *SObject, idField, idValue string, blacklistedFields []string) error
+	UpsertSObject(ctx context.Context, sobject *SObject, idField, idValue string, blacklistedFields []string, allowDuplicates bool, autoAssign *bool) (*UpsertResult, error)
 	DeleteSObject(ctx context.Context, sobj *SObject) error
 
 	DescribeGlobal(ctx context.Context) (*SObjectMeta, error)
 	DownloadFile(ctx context.Context, contentVersionID string, filepath string) error
 }
 
-var _ Client = (*HTTPClient)(nil)
+var (
+	_ Client               = (*HTTPClient)(nil)
+	_ BulkQuerier          = (*HTTPClient)(nil)
+	_ ReplicationLister    = (*HTTPClient)(nil)
+	_ ConditionalDescriber = (*HTTPClient)(nil)
+	_ ExternalIDClient     = (*HTTPClient)(nil)
+	_ ConditionalGetter    = (*HTTPClient)(nil)
+	_ ConditionalUpdater   = (*HTTPClient)(nil)
+	_ Searcher             = (*HTTPClient)(nil)
+	_ Explainer            = (*HTTPClient)(nil)
+	_ EventPublisher       = (*HTTPClient)(nil)
+)
 
 // HTTPClient is the main instance to access salesforce.
 type HTTPClient struct {
 	httpClient *http.Client
 	baseURL    string
 	apiVersion string
+	validator  *Validator
+	now        func() time.Time
 }
 
 // NewHTTPClient creates a new instance of the client.
@@ -54,9 +68,17 @@ func NewHTTPClient(httpClient *http.Client, baseURL, apiVersion string) *HTTPCli
 		httpClient: httpClient,
 		baseURL:    baseURL,
 		apiVersion: apiVersion,
+		now:        time.Now,
 	}
 }
 
+// SetValidator makes the client validate SObjects with v before they are created, updated or upserted. Fields that
+// can't be written are dropped, or rejected if v is strict, and ErrValidation is returned without sending the request
+// if any field is invalid. Pass nil to stop validating.
+func (h *HTTPClient) SetValidator(v *Validator) {
+	h.validator = v
+}
+
 // QueryResult holds the response data from an SOQL query.
 type QueryResult struct {
 	TotalSize      int        `json:"totalSize"`
@@ -74,7 +96,7 @@ func (h *HTTPClient) Query(ctx context.Context, query, nextRecordsURL string) (*
 		path = nextRecordsURL
 	} else {
 		format := "/services/data/%s/query?q=%s"
-		path = fmt.Sprintf(format, h.apiVersion, url.PathEscape(query))
+		path = fmt.Sprintf(format, h.apiVersion, url.QueryEscape(query))
 	}
 
 	url := fmt.Sprintf("%s%s", h.baseURL, path)
@@ -92,31 +114,50 @@ func (h *HTTPClient) Query(ctx context.Context, query, nextRecordsURL string) (*
 		return nil, err
 	}
 
+	for _, record := range result.Records {
+		record.ClearChanges()
+	}
+
 	return result, nil
 }
 
 // DescribeSObject queries the metadata of an SObject using the "describe" API.
 // Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/resources_sobject_describe.htm
 func (h *HTTPClient) DescribeSObject(ctx context.Context, sobj *SObject) (*SObjectMeta, error) {
-	if len(sobj.Type()) == 0 {
-		return nil, ErrInvalidSObject{"Type is empty"}
-	}
-
-	url := h.makeURL("sobjects/" + sobj.Type() + "/describe")
-
-	res, err := h.request(ctx, http.MethodGet, url, nil, nil)
+	res, err := h.DescribeSObjectIfModified(ctx, sobj, time.Time{})
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	var meta SObjectMeta
-	err = json.NewDecoder(res.Body).Decode(&meta)
-	if err != nil {
-		return nil, err
+	return res.Meta, nil
+}
+
+// DescribeResult holds the response of a conditional describe.
+type DescribeResult struct {
+	// Meta is nil if NotModified is true.
+	Meta *SObjectMeta
+	// NotModified is true if the metadata didn't change since the given time.
+	NotModified  bool
+	LastModified time.Time
+}
+
+// ConditionalDescriber describes SObjects only if their metadata changed. HTTPClient implements
+// ConditionalDescriber.
+type ConditionalDescriber interface {
+	DescribeSObjectIfModified(ctx context.Context, sobj *SObject, since time.Time) (*DescribeResult, error)
+	DescribeGlobalIfModified(ctx context.Context, since time.Time) (*DescribeResult, error)
+}
+
+// DescribeSObjectIfModified queries the metadata of an SObject if it changed after since. If since is zero, the
+// metadata is always returned.
+func (h *HTTPClient) DescribeSObjectIfModified(ctx context.Context, sobj *SObject, since time.Time) (*DescribeResult, error) {
+	if len(sobj.Type()) == 0 {
+		return nil, ErrInvalidSObject{"Type is empty"}
 	}
 
-	return &meta, nil
+	url := h.makeURL("sobjects/" + sobj.Type() + "/describe")
+
+	return h.describe(ctx, url, since)
 }
 
 type createSObjectResponse struct {
@@ -132,6 +173,16 @@ func (h *HTTPClient) CreateSObject(ctx context.Context, sobj *SObject, blacklist
 		return ErrInvalidSObject{"Type is empty"}
 	}
 
+	if h.validator != nil {
+		dropped, err := h.validator.ValidateCreate(ctx, sobj)
+		if err != nil {
+			return err
+		}
+		blacklistedFields = append(dropped, blacklistedFields...)
+	} else {
+		blacklistedFields = withBlacklistedUpdateFields(blacklistedFields)
+	}
+
 	// Make a copy of the incoming SObject, skipping certain metadata fields as they're not understood by salesforce.
 	reqObj := sobj.makeCopy(blacklistedFields)
 	reqData, err := json.Marshal(reqObj)
@@ -141,18 +192,7 @@ func (h *HTTPClient) CreateSObject(ctx context.Context, sobj *SObject, blacklist
 
 	url := h.makeURL("sobjects/" + sobj.Type() + "/")
 
-	headers := http.Header{}
-	if allowDuplicates {
-		headers.Set(duplicateRuleHeader, "allowSave=true")
-	}
-	if autoAssign != nil {
-		autoAssignValue := "FALSE"
-		if *autoAssign {
-			autoAssignValue = "TRUE"
-		}
-
-		headers.Set(autoAssignRuleHeader, autoAssignValue)
-	}
+	headers := ruleHeaders(allowDuplicates, autoAssign)
 
 	res, err := h.request(ctx, http.MethodPost, url, bytes.NewReader(reqData), headers)
 	if err != nil {
@@ -176,17 +216,116 @@ func (h *HTTPClient) CreateSObject(ctx context.Context, sobj *SObject, blacklist
 	return nil
 }
 
+// GetOptions controls which fields of an SObject are retrieved and makes the request conditional.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/dome_get_field_values.htm
+type GetOptions struct {
+	// Fields limits the retrieved fields. All fields are retrieved if empty.
+	Fields []string
+	// IfModifiedSince skips retrieval unless the record changed after the given time.
+	IfModifiedSince time.Time
+	// IfNoneMatch skips retrieval if the record still matches the given ETag.
+	IfNoneMatch string
+}
+
+// GetResult holds the response metadata of a GetSObjectWithOptions call.
+type GetResult struct {
+	// NotModified is true if a conditional request found the record unchanged. The SObject is left untouched.
+	NotModified  bool
+	ETag         string
+	LastModified time.Time
+}
+
 // GetSObject retrieves all the data fields of an SObject.
 func (h *HTTPClient) GetSObject(ctx context.Context, sobj *SObject) error {
+	_, err := h.GetSObjectWithOptions(ctx, sobj, nil)
+
+	return err
+}
+
+// ConditionalGetter retrieves selected fields of SObjects, optionally only if they changed. HTTPClient implements
+// ConditionalGetter.
+type ConditionalGetter interface {
+	GetSObjectWithOptions(ctx context.Context, sobj *SObject, opts *GetOptions) (*GetResult, error)
+}
+
+// GetSObjectWithOptions retrieves the data fields of an SObject as configured by opts.
+func (h *HTTPClient) GetSObjectWithOptions(ctx context.Context, sobj *SObject, opts *GetOptions) (*GetResult, error) {
 	if len(sobj.Type()) == 0 {
-		return ErrInvalidSObject{"Type is empty"}
+		return nil, ErrInvalidSObject{"Type is empty"}
 	}
 
 	if len(sobj.ID()) == 0 {
-		return ErrInvalidSObject{"Id is empty"}
+		return nil, ErrInvalidSObject{"Id is empty"}
+	}
+
+	if opts == nil {
+		opts = &GetOptions{}
 	}
 
 	url := h.makeURL("sobjects/" + sobj.Type() + "/" + sobj.ID())
+	if len(opts.Fields) > 0 {
+		url += "?fields=" + strings.Join(opts.Fields, ",")
+	}
+
+	headers := http.Header{}
+	if !opts.IfModifiedSince.IsZero() {
+		headers.Set("If-Modified-Since", opts.IfModifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if len(opts.IfNoneMatch) > 0 {
+		headers.Set("If-None-Match", opts.IfNoneMatch)
+	}
+
+	res, err := h.request(ctx, http.MethodGet, url, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	result := &GetResult{
+		NotModified: res.StatusCode == http.StatusNotModified,
+		ETag:        res.Header.Get("ETag"),
+	}
+
+	lastModified, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	if err == nil {
+		result.LastModified = lastModified
+	}
+
+	if result.NotModified {
+		return result, nil
+	}
+
+	err = json.NewDecoder(res.Body).Decode(sobj)
+	if err != nil {
+		return nil, err
+	}
+
+	sobj.ClearChanges()
+
+	return result, nil
+}
+
+// ExternalIDClient reads and deletes SObjects by external ID. HTTPClient implements ExternalIDClient.
+type ExternalIDClient interface {
+	GetSObjectByExternalID(ctx context.Context, sobj *SObject, idField, idValue string) error
+	DeleteSObjectByExternalID(ctx context.Context, sobj *SObject, idField, idValue string) error
+}
+
+// GetSObjectByExternalID retrieves all the data fields of an SObject identified by the external ID field idField.
+// If idValue is empty, the value of idField on the SObject is used. ErrMultipleChoices is returned if the external
+// ID matches more than one record.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/dome_upsert.htm
+func (h *HTTPClient) GetSObjectByExternalID(ctx context.Context, sobj *SObject, idField, idValue string) error {
+	if len(sobj.Type()) == 0 {
+		return ErrInvalidSObject{"Type is empty"}
+	}
+
+	path, err := externalIDPath(sobj, idField, idValue)
+	if err != nil {
+		return err
+	}
+
+	url := h.makeURL(path)
 
 	res, err := h.request(ctx, http.MethodGet, url, nil, nil)
 	if err != nil {
@@ -199,11 +338,58 @@ func (h *HTTPClient) GetSObject(ctx context.Context, sobj *SObject) error {
 		return err
 	}
 
+	sobj.ClearChanges()
+
 	return nil
 }
 
-// UpdateSObject updates SObject in place.
+// UpdateSObject updates SObject in place. If the SObject is tracking changes, only the changed fields are sent.
 func (h *HTTPClient) UpdateSObject(ctx context.Context, sobj *SObject, blacklistedFields []string, autoAssign *bool) error {
+	return h.update(ctx, sobj, blacklistedFields, ruleHeaders(false, autoAssign))
+}
+
+// Precondition describes the state a record must still be in for a conditional update to be applied.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_conditional.htm
+type Precondition struct {
+	// IfUnmodifiedSince rejects the update if the record changed after the given time.
+	IfUnmodifiedSince time.Time
+	// IfMatch rejects the update unless the record still matches the given ETag.
+	IfMatch string
+}
+
+// ConditionalUpdater reads SObjects with their version and updates them only if they are unmodified. HTTPClient
+// implements ConditionalUpdater.
+type ConditionalUpdater interface {
+	ConditionalGetter
+	UpdateSObjectIfUnmodified(ctx context.Context, sobj *SObject, blacklistedFields []string, autoAssign *bool, precondition *Precondition) error
+}
+
+// UpdateSObjectIfUnmodified updates SObject in place only if the record on salesforce satisfies precondition.
+// If precondition is nil, the LastModifiedDate field of the SObject is used. ErrPreconditionFailed is returned if
+// the record was modified in the meantime.
+func (h *HTTPClient) UpdateSObjectIfUnmodified(ctx context.Context, sobj *SObject, blacklistedFields []string, autoAssign *bool, precondition *Precondition) error {
+	if precondition == nil {
+		lastModified, err := sobj.DateTimeFieldE(sobjectLastModifiedDateKey)
+		if err != nil || lastModified.IsZero() {
+			return ErrInvalidSObject{"LastModifiedDate is empty or invalid"}
+		}
+
+		precondition = &Precondition{IfUnmodifiedSince: lastModified}
+	}
+
+	headers := ruleHeaders(false, autoAssign)
+	if !precondition.IfUnmodifiedSince.IsZero() {
+		headers.Set("If-Unmodified-Since", precondition.IfUnmodifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if len(precondition.IfMatch) > 0 {
+		headers.Set("If-Match", precondition.IfMatch)
+	}
+
+	return h.update(ctx, sobj, blacklistedFields, headers)
+}
+
+// update PATCHes the fields of SObject to salesforce.
+func (h *HTTPClient) update(ctx context.Context, sobj *SObject, blacklistedFields []string, headers http.Header) error {
 	if len(sobj.Type()) == 0 {
 		return ErrInvalidSObject{"Type is empty"}
 	}
@@ -212,8 +398,18 @@ func (h *HTTPClient) UpdateSObject(ctx context.Context, sobj *SObject, blacklist
 		return ErrInvalidSObject{"Id is empty"}
 	}
 
+	if h.validator != nil {
+		dropped, err := h.validator.ValidateUpdate(ctx, sobj)
+		if err != nil {
+			return err
+		}
+		blacklistedFields = append(dropped, blacklistedFields...)
+	} else {
+		blacklistedFields = withBlacklistedUpdateFields(blacklistedFields)
+	}
+
 	// Make a copy of the incoming SObject, but skip certain metadata fields as they're not understood by salesforce.
-	reqObj := sobj.makeCopy(blacklistedFields)
+	reqObj := sobj.makeUpdateCopy(blacklistedFields)
 	reqData, err := json.Marshal(reqObj)
 	if err != nil {
 		return err
@@ -221,47 +417,98 @@ func (h *HTTPClient) UpdateSObject(ctx context.Context, sobj *SObject, blacklist
 
 	url := h.makeURL("sobjects/" + sobj.Type() + "/" + sobj.ID())
 
-	headers := http.Header{}
-	if autoAssign != nil {
-		autoAssignValue := "FALSE"
-		if *autoAssign {
-			autoAssignValue = "TRUE"
-		}
-
-		headers.Set(autoAssignRuleHeader, autoAssignValue)
-	}
-
 	res, err := h.request(ctx, http.MethodPatch, url, bytes.NewReader(reqData), headers)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
 
+	if sobj.changes() != nil {
+		sobj.ClearChanges()
+	}
+
 	return nil
 }
 
-// UpsertSObject upserts SObject.
-func (h *HTTPClient) UpsertSObject(ctx context.Context, sobj *SObject, idField, idValue string, blacklistedFields []string) error {
+// SaveError describes an error reported by salesforce for a single record write.
+type SaveError struct {
+	StatusCode string   `json:"statusCode"`
+	Message    string   `json:"message"`
+	Fields     []string `json:"fields"`
+}
+
+// UpsertResult holds the response data from an upsert.
+// Created is true if the upsert inserted a new record and false if an existing record was updated.
+type UpsertResult struct {
+	ID      string      `json:"id"`
+	Success bool        `json:"success"`
+	Created bool        `json:"created"`
+	Errors  []SaveError `json:"errors"`
+}
+
+// UpsertSObject upserts SObject using idField as the external ID field. If idValue is empty, the value of idField
+// on the SObject is used. If the upsert is successful, the ID of the SObject instance is updated with the ID returned.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/dome_upsert.htm
+func (h *HTTPClient) UpsertSObject(ctx context.Context, sobj *SObject, idField, idValue string, blacklistedFields []string, allowDuplicates bool, autoAssign *bool) (*UpsertResult, error) {
 	if len(sobj.Type()) == 0 {
-		return ErrInvalidSObject{"Type is empty"}
+		return nil, ErrInvalidSObject{"Type is empty"}
+	}
+
+	path, err := externalIDPath(sobj, idField, idValue)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.validator != nil {
+		dropped, err := h.validator.ValidateUpsert(ctx, sobj)
+		if err != nil {
+			return nil, err
+		}
+		blacklistedFields = append(dropped, blacklistedFields...)
+	} else {
+		blacklistedFields = withBlacklistedUpdateFields(blacklistedFields)
 	}
 
 	// Make a copy of the incoming SObject, but skip certain metadata fields as they're not understood by salesforce.
-	reqObj := sobj.makeCopy(blacklistedFields)
+	// The external ID is part of the URL and must not be repeated in the body.
+	reqObj := sobj.makeCopy(append([]string{idField}, blacklistedFields...))
 	reqData, err := json.Marshal(reqObj)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	url := h.makeURL("sobjects/" + sobj.Type() + "/" + idField + "/" + idValue)
+	url := h.makeURL(path)
+
+	headers := ruleHeaders(allowDuplicates, autoAssign)
 
-	res, err := h.request(ctx, http.MethodPatch, url, bytes.NewReader(reqData), nil)
+	res, err := h.request(ctx, http.MethodPatch, url, bytes.NewReader(reqData), headers)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer res.Body.Close()
 
-	return nil
+	result := &UpsertResult{
+		Success: true,
+		Created: res.StatusCode == http.StatusCreated,
+	}
+
+	// API versions prior to v46.0 respond to updates with 204 No Content.
+	if res.StatusCode != http.StatusNoContent {
+		err = json.NewDecoder(res.Body).Decode(result)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	if !result.Success {
+		return result, ErrFailure
+	}
+
+	if len(result.ID) > 0 {
+		sobj.SetID(result.ID)
+	}
+
+	return result, nil
 }
 
 // DeleteSObject deletes an SObject record.
@@ -284,6 +531,48 @@ func (h *HTTPClient) DeleteSObject(ctx context.Context, sobj *SObject) error {
 	return nil
 }
 
+// DeleteSObjectByExternalID deletes an SObject record identified by the external ID field idField.
+// If idValue is empty, the value of idField on the SObject is used. ErrMultipleChoices is returned if the external
+// ID matches more than one record.
+func (h *HTTPClient) DeleteSObjectByExternalID(ctx context.Context, sobj *SObject, idField, idValue string) error {
+	if len(sobj.Type()) == 0 {
+		return ErrInvalidSObject{"Type is empty"}
+	}
+
+	path, err := externalIDPath(sobj, idField, idValue)
+	if err != nil {
+		return err
+	}
+
+	url := h.makeURL(path)
+
+	res, err := h.request(ctx, http.MethodDelete, url, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return nil
+}
+
+// externalIDPath builds the sobjects path addressing a record by external ID. If idValue is empty, the value of
+// idField on the SObject is used.
+func externalIDPath(sobj *SObject, idField, idValue string) (string, error) {
+	if len(idField) == 0 {
+		return "", ErrInvalidSObject{"External ID field is empty"}
+	}
+
+	if len(idValue) == 0 {
+		idValue = sobj.StringField(idField)
+	}
+
+	if len(idValue) == 0 {
+		return "", ErrInvalidSObject{"External ID value is empty"}
+	}
+
+	return "sobjects/" + sobj.Type() + "/" + url.PathEscape(idField) + "/" + url.PathEscape(idValue), nil
+}
+
 // httpRequest executes an HTTP request to the salesforce server and returns the HTTP response.
 func (h *HTTPClient) request(ctx context.Context, method, url string, body io.Reader, headers http.Header) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
@@ -306,7 +595,8 @@ func (h *HTTPClient) request(ctx context.Context, method, url string, body io.Re
 		return nil, err
 	}
 
-	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+	// 304 is only returned to conditional requests, which handle it themselves.
+	if res.StatusCode < http.StatusOK || (res.StatusCode >= http.StatusMultipleChoices && res.StatusCode != http.StatusNotModified) {
 		body, err := io.ReadAll(res.Body)
 		if err != nil {
 			return nil, err
@@ -322,6 +612,32 @@ func (h *HTTPClient) request(ctx context.Context, method, url string, body io.Re
 	return res, nil
 }
 
+// withBlacklistedUpdateFields adds the fields of blacklistedUpdateFields to blacklistedFields.
+func withBlacklistedUpdateFields(blacklistedFields []string) []string {
+	fields := make([]string, 0, len(blacklistedUpdateFields)+len(blacklistedFields))
+	fields = append(fields, blacklistedUpdateFields...)
+
+	return append(fields, blacklistedFields...)
+}
+
+// ruleHeaders builds the duplicate rule and assignment rule headers used when writing records.
+func ruleHeaders(allowDuplicates bool, autoAssign *bool) http.Header {
+	headers := http.Header{}
+	if allowDuplicates {
+		headers.Set(duplicateRuleHeader, "allowSave=true")
+	}
+	if autoAssign != nil {
+		autoAssignValue := "FALSE"
+		if *autoAssign {
+			autoAssignValue = "TRUE"
+		}
+
+		headers.Set(autoAssignRuleHeader, autoAssignValue)
+	}
+
+	return headers
+}
+
 // makeURL generates a REST API URL based on baseURL and APIVersion of the client.
 func (h *HTTPClient) makeURL(url string) string {
 	return fmt.Sprintf("%s/services/data/%s/%s", h.baseURL, h.apiVersion, url)
@@ -355,12 +671,31 @@ func (h *HTTPClient) DownloadFile(ctx context.Context, contentVersionID string,
 
 // DescribeGlobal lists all available objects and their metadata.
 func (h *HTTPClient) DescribeGlobal(ctx context.Context) (*SObjectMeta, error) {
+	res, err := h.DescribeGlobalIfModified(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Meta, nil
+}
+
+// DescribeGlobalIfModified lists all available objects and their metadata if any changed after since. If since is
+// zero, the metadata is always returned.
+func (h *HTTPClient) DescribeGlobalIfModified(ctx context.Context, since time.Time) (*DescribeResult, error) {
 	path := fmt.Sprintf("/services/data/%s/sobjects", h.apiVersion)
 	url := fmt.Sprintf("%s%s", h.baseURL, path)
 
+	return h.describe(ctx, url, since)
+}
+
+// describe runs a conditional describe request.
+func (h *HTTPClient) describe(ctx context.Context, url string, since time.Time) (*DescribeResult, error) {
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json; charset=UTF-8")
 	headers.Set("Accept", "application/json")
+	if !since.IsZero() {
+		headers.Set("If-Modified-Since", since.UTC().Format(http.TimeFormat))
+	}
 
 	res, err := h.request(ctx, http.MethodGet, url, nil, headers)
 	if err != nil {
@@ -368,6 +703,19 @@ func (h *HTTPClient) DescribeGlobal(ctx context.Context) (*SObjectMeta, error) {
 	}
 	defer res.Body.Close()
 
+	result := &DescribeResult{
+		NotModified: res.StatusCode == http.StatusNotModified,
+	}
+
+	lastModified, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	if err == nil {
+		result.LastModified = lastModified
+	}
+
+	if result.NotModified {
+		return result, nil
+	}
+
 	var meta SObjectMeta
 
 	err = json.NewDecoder(res.Body).Decode(&meta)
@@ -375,5 +723,7 @@ func (h *HTTPClient) DescribeGlobal(ctx context.Context) (*SObjectMeta, error) {
 		return nil, err
 	}
 
-	return &meta, nil
+	result.Meta = &meta
+
+	return result, nil
 }
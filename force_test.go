@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -29,6 +30,9 @@ func TestHTTPClient_Query(t *testing.T) {
 	sObj := NewSObject("").
 		Set("Foo", "bar")
 
+	// Query results track changes.
+	sObj.ClearChanges()
+
 	res = &QueryResult{
 		Records: []*SObject{sObj},
 	}
@@ -38,6 +42,25 @@ func TestHTTPClient_Query(t *testing.T) {
 	assert.Equal(res, actualRes)
 }
 
+func TestHTTPClient_Query_escaping(t *testing.T) {
+	assert := assert.New(t)
+
+	query := "SELECT Id FROM Contact WHERE Name = 'O''Brien & Sons' AND Title LIKE '100% #1+'"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(query, r.URL.Query().Get("q"))
+		assert.Len(r.URL.Query(), 1)
+
+		err := json.NewEncoder(w).Encode(&QueryResult{Done: true})
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	_, err := client.Query(context.Background(), query, "")
+	assert.NoError(err)
+}
+
 func TestHTTPClient_Query_nextRecordsURL(t *testing.T) {
 	assert := assert.New(t)
 
@@ -59,6 +82,9 @@ func TestHTTPClient_Query_nextRecordsURL(t *testing.T) {
 	sObj := NewSObject("").
 		Set("Foo", "bar")
 
+	// Query results track changes.
+	sObj.ClearChanges()
+
 	res = &QueryResult{
 		NextRecordsURL: nextRecordsURL,
 		Records:        []*SObject{sObj},
@@ -160,6 +186,44 @@ func TestHTTPClient_Create(t *testing.T) {
 	assert.Equal(objType, sobj.Type())
 }
 
+func TestHTTPClient_Create_read_only_fields(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"totalSize": 1, "done": true, "records": [{
+				"attributes": {"type": "Case"},
+				"Id": "5003000000D8cuIAAR",
+				"Subject": "foo",
+				"CaseNumber": "00001001",
+				"CreatedDate": "2021-03-04T05:06:07.000+0000",
+				"SystemModstamp": "2021-03-04T05:06:07.000+0000"
+			}]}`))
+			return
+		}
+
+		body := map[string]interface{}{}
+		assert.NoError(json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(map[string]interface{}{"Subject": "foo"}, body, r.Method)
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "5003000000D8cuJAAR", "success": true, "created": true}`))
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	result, err := client.Query(context.Background(), "SELECT FIELDS(ALL) FROM Case LIMIT 1", "")
+	assert.NoError(err)
+
+	// Without a validator, the read only fields of the queried records are left out of writes.
+	err = client.CreateSObject(context.Background(), result.Records[0], nil, false, nil)
+	assert.NoError(err)
+
+	_, err = client.UpsertSObject(context.Background(), result.Records[0], "Id", "5003000000D8cuJAAR", nil, false, nil)
+	assert.NoError(err)
+}
+
 func TestHTTPClient_Create_allow_duplicates(t *testing.T) {
 	assert := assert.New(t)
 
@@ -341,6 +405,7 @@ func TestHTTPClient_Update_auto_assign(t *testing.T) {
 func TestHTTPClient_Upsert(t *testing.T) {
 	assert := assert.New(t)
 
+	id := "object1"
 	ownerID := "owner1"
 	objType := "Case"
 
@@ -353,6 +418,7 @@ func TestHTTPClient_Upsert(t *testing.T) {
 
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(r.Method, http.MethodPatch)
+		assert.NotContains(r.Header, duplicateRuleHeader)
 
 		o := &SObject{}
 		err := json.NewDecoder(r.Body).Decode(o)
@@ -362,17 +428,101 @@ func TestHTTPClient_Upsert(t *testing.T) {
 
 		assert.Equal(sobj.StringField("OwnerId"), o.StringField("OwnerId"))
 		assert.Equal("bar", o.StringField("Foo"))
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(&UpsertResult{
+			ID:      id,
+			Success: true,
+			Created: true,
+		})
+		assert.NoError(err)
 	}))
 
 	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
 
-	err := client.UpsertSObject(context.Background(), sobj, idField, idValue, nil)
+	res, err := client.UpsertSObject(context.Background(), sobj, idField, idValue, nil, false, nil)
 	assert.NoError(err)
+	assert.True(res.Created)
+	assert.Equal(id, res.ID)
 
+	assert.Equal(id, sobj.ID())
 	assert.Equal(ownerID, sobj.StringField("OwnerId"))
 	assert.Equal(objType, sobj.Type())
 }
 
+func TestHTTPClient_Upsert_updated(t *testing.T) {
+	assert := assert.New(t)
+
+	id := "object1"
+	objType := "Case"
+
+	idField := "Baz"
+
+	sobj := NewSObject(objType).
+		Set(idField, "cat").
+		Set("Foo", "bar")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(r.Method, http.MethodPatch)
+		assert.Contains(r.URL.Path, "sobjects/"+objType+"/"+idField+"/cat")
+		assert.Equal(r.Header.Get(duplicateRuleHeader), "allowSave=true")
+		assert.Equal(r.Header.Get(autoAssignRuleHeader), "TRUE")
+
+		o := &SObject{}
+		err := json.NewDecoder(r.Body).Decode(o)
+		assert.NoError(err)
+
+		assert.Nil(o.InterfaceField(idField))
+		assert.Equal("bar", o.StringField("Foo"))
+
+		err = json.NewEncoder(w).Encode(&UpsertResult{
+			ID:      id,
+			Success: true,
+			Created: false,
+		})
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	autoAssign := true
+	res, err := client.UpsertSObject(context.Background(), sobj, idField, "", nil, true, &autoAssign)
+	assert.NoError(err)
+	assert.False(res.Created)
+	assert.Equal(id, res.ID)
+	assert.Equal(id, sobj.ID())
+}
+
+func TestHTTPClient_Upsert_no_content(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").
+		Set("Foo", "bar")
+
+	res, err := client.UpsertSObject(context.Background(), sobj, "Baz", "cat", nil, false, nil)
+	assert.NoError(err)
+	assert.True(res.Success)
+	assert.False(res.Created)
+	assert.Empty(sobj.ID())
+}
+
+func TestHTTPClient_Upsert_missing_id_value(t *testing.T) {
+	assert := assert.New(t)
+
+	client := NewHTTPClient(http.DefaultClient, "http://localhost", DefaultAPIVersion)
+
+	sobj := NewSObject("Case")
+
+	_, err := client.UpsertSObject(context.Background(), sobj, "Baz", "", nil, false, nil)
+	assert.IsType(ErrInvalidSObject{}, err)
+}
+
 func TestHTTPClient_Delete(t *testing.T) {
 	assert := assert.New(t)
 
@@ -392,3 +542,220 @@ func TestHTTPClient_Delete(t *testing.T) {
 	err := client.DeleteSObject(context.Background(), sobj)
 	assert.NoError(err)
 }
+
+func TestHTTPClient_GetByExternalID(t *testing.T) {
+	assert := assert.New(t)
+
+	objType := "Contact"
+	idField := "Patient_ID__c"
+	idValue := "abc/123 o'neil"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(r.Method, http.MethodGet)
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/sobjects/"+objType+"/"+idField+"/abc%2F123%20o%27neil", r.URL.EscapedPath())
+
+		err := json.NewEncoder(w).Encode(NewSObject(objType).SetID("object1").Set(idField, idValue))
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject(objType)
+
+	err := client.GetSObjectByExternalID(context.Background(), sobj, idField, idValue)
+	assert.NoError(err)
+
+	assert.Equal("object1", sobj.ID())
+	assert.Equal(idValue, sobj.StringField(idField))
+}
+
+func TestHTTPClient_GetByExternalID_multiple_choices(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultipleChoices)
+
+		err := json.NewEncoder(w).Encode([]string{
+			"/services/data/" + DefaultAPIVersion + "/sobjects/Contact/object1",
+			"/services/data/" + DefaultAPIVersion + "/sobjects/Contact/object2",
+		})
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Contact").Set("Patient_ID__c", "123")
+
+	err := client.GetSObjectByExternalID(context.Background(), sobj, "Patient_ID__c", "")
+	assert.Equal(ErrMultipleChoices{IDs: []string{"object1", "object2"}}, err)
+}
+
+func TestHTTPClient_DeleteByExternalID(t *testing.T) {
+	assert := assert.New(t)
+
+	objType := "Contact"
+	idField := "Patient_ID__c"
+	idValue := "123"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(r.Method, http.MethodDelete)
+		assert.Contains(r.URL.Path, "sobjects/"+objType+"/"+idField+"/"+idValue)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject(objType).Set(idField, idValue)
+
+	err := client.DeleteSObjectByExternalID(context.Background(), sobj, idField, "")
+	assert.NoError(err)
+}
+
+func TestHTTPClient_GetWithOptions_fields(t *testing.T) {
+	assert := assert.New(t)
+
+	etag := `"abc123--gzip"`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(r.Method, http.MethodGet)
+		assert.Equal("Name,OwnerId", r.URL.Query().Get("fields"))
+		assert.Empty(r.Header.Get("If-Modified-Since"))
+		assert.Empty(r.Header.Get("If-None-Match"))
+
+		w.Header().Set("ETag", etag)
+
+		err := json.NewEncoder(w).Encode(NewSObject("Case").SetID("object1").Set("Name", "foo"))
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").SetID("object1")
+
+	res, err := client.GetSObjectWithOptions(context.Background(), sobj, &GetOptions{
+		Fields: []string{"Name", "OwnerId"},
+	})
+	assert.NoError(err)
+	assert.False(res.NotModified)
+	assert.Equal(etag, res.ETag)
+
+	assert.Equal("foo", sobj.StringField("Name"))
+}
+
+func TestHTTPClient_GetWithOptions_not_modified(t *testing.T) {
+	assert := assert.New(t)
+
+	since := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	etag := `"abc123--gzip"`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("Sun, 01 Aug 2021 12:00:00 GMT", r.Header.Get("If-Modified-Since"))
+		assert.Equal(etag, r.Header.Get("If-None-Match"))
+
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").SetID("object1").Set("Name", "foo")
+
+	res, err := client.GetSObjectWithOptions(context.Background(), sobj, &GetOptions{
+		IfModifiedSince: since,
+		IfNoneMatch:     etag,
+	})
+	assert.NoError(err)
+	assert.True(res.NotModified)
+
+	assert.Equal("foo", sobj.StringField("Name"))
+}
+
+func TestHTTPClient_UpdateIfUnmodified(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(r.Method, http.MethodPatch)
+		assert.Equal("Sun, 01 Aug 2021 12:00:00 GMT", r.Header.Get("If-Unmodified-Since"))
+		assert.Empty(r.Header.Get("If-Match"))
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").
+		SetID("object1").
+		Set("LastModifiedDate", "2021-08-01T12:00:00.000+0000").
+		Set("Subject", "foo")
+
+	err := client.UpdateSObjectIfUnmodified(context.Background(), sobj, nil, nil, nil)
+	assert.NoError(err)
+}
+
+func TestHTTPClient_UpdateIfUnmodified_precondition_failed(t *testing.T) {
+	assert := assert.New(t)
+
+	etag := `"abc123--gzip"`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(etag, r.Header.Get("If-Match"))
+
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, err := w.Write([]byte(`[{"errorCode":"PRECONDITION_FAILED","message":"The entity has been modified"}]`))
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject("Case").
+		SetID("object1").
+		Set("Subject", "foo")
+
+	err := client.UpdateSObjectIfUnmodified(context.Background(), sobj, nil, nil, &Precondition{IfMatch: etag})
+	assert.Equal(ErrPreconditionFailed{Message: "The entity has been modified"}, err)
+}
+
+func TestHTTPClient_Update_changed_fields(t *testing.T) {
+	assert := assert.New(t)
+
+	id := "object1"
+	objType := "Case"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			err := json.NewEncoder(w).Encode(NewSObject(objType).
+				SetID(id).
+				Set("Subject", "foo").
+				Set("Description", "bar").
+				Set("Formula__c", "read only"))
+			assert.NoError(err)
+		case http.MethodPatch:
+			o := map[string]interface{}{}
+			err := json.NewDecoder(r.Body).Decode(&o)
+			assert.NoError(err)
+
+			assert.Equal(map[string]interface{}{
+				"Subject":     "baz",
+				"Description": nil,
+			}, o)
+
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	sobj := NewSObject(objType).SetID(id)
+
+	err := client.GetSObject(context.Background(), sobj)
+	assert.NoError(err)
+
+	sobj.Set("Subject", "baz").
+		SetNull("Description")
+
+	err = client.UpdateSObject(context.Background(), sobj, nil, nil)
+	assert.NoError(err)
+
+	assert.Empty(sobj.ChangedFields())
+}
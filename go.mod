@@ -3,8 +3,9 @@ module github.com/eleanorhealth/simpleforce
 go 1.16
 
 require (
-	github.com/davecgh/go-spew v1.1.0
+	github.com/kr/pretty v0.1.0 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.7.0
-	golang.org/x/oauth2 v0.0.0-20210819190943-2bc19b11175f
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
@@ -0,0 +1,174 @@
+package simpleforce
+
+import (
+	"strings"
+)
+
+// idSuffixAlphabet maps the case bits of each 5 character chunk of a 15 character ID to its checksum character.
+const idSuffixAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"
+
+// ID is a salesforce record ID. Salesforce IDs come in a case-sensitive 15 character form and a case-insensitive 18
+// character form, which appends a checksum encoding the case of the first 15 characters.
+// Ref: https://help.salesforce.com/s/articleView?id=000385585&type=1
+type ID string
+
+// ParseID validates a 15 or 18 character ID and returns it in its 18 character form. The checksum of a mixed case 18
+// character ID must match its first 15 characters. The case of an all lower or all upper case 18 character ID is
+// restored from its checksum, so IDs that went through case-insensitive systems are accepted.
+func ParseID(s string) (ID, error) {
+	switch len(s) {
+	case 15:
+		if !isAlphanumeric(s) {
+			return "", ErrInvalidID{s}
+		}
+
+		return ID(s + idSuffix(s)), nil
+
+	case 18:
+		if !isAlphanumeric(s) {
+			return "", ErrInvalidID{s}
+		}
+
+		if !isSingleCase(s) {
+			if s[15:] != idSuffix(s[:15]) {
+				return "", ErrInvalidID{s}
+			}
+
+			return ID(s), nil
+		}
+
+		id := []byte(s[:15])
+
+		for chunk := 0; chunk < 3; chunk++ {
+			bits := strings.IndexByte(idSuffixAlphabet, upper(s[15+chunk]))
+			if bits < 0 {
+				return "", ErrInvalidID{s}
+			}
+
+			for i := 0; i < 5; i++ {
+				c := &id[chunk*5+i]
+				isUpper := bits&(1<<i) != 0
+
+				switch {
+				case isUpper && '0' <= *c && *c <= '9':
+					return "", ErrInvalidID{s}
+				case isUpper:
+					*c = upper(*c)
+				default:
+					*c = lower(*c)
+				}
+			}
+		}
+
+		return ID(string(id) + idSuffix(string(id))), nil
+
+	default:
+		return "", ErrInvalidID{s}
+	}
+}
+
+// Valid reports whether the ID is a well-formed 15 or 18 character ID.
+func (id ID) Valid() bool {
+	_, err := ParseID(string(id))
+	return err == nil
+}
+
+// To18 returns the 18 character form of the ID. Invalid IDs are returned unchanged.
+func (id ID) To18() ID {
+	parsed, err := ParseID(string(id))
+	if err != nil {
+		return id
+	}
+
+	return parsed
+}
+
+// To15 returns the case-sensitive 15 character form of the ID. Invalid IDs are returned unchanged.
+func (id ID) To15() ID {
+	parsed, err := ParseID(string(id))
+	if err != nil {
+		return id
+	}
+
+	return parsed[:15]
+}
+
+// Equal reports whether two IDs refer to the same record, regardless of their form.
+func (id ID) Equal(other ID) bool {
+	a, b := id.To18(), other.To18()
+	if a.Valid() && b.Valid() {
+		return a == b
+	}
+
+	return id == other
+}
+
+// KeyPrefix returns the first three characters of the ID, which identify the SObject type of the record.
+func (id ID) KeyPrefix() string {
+	if len(id) < 3 {
+		return ""
+	}
+
+	return string(id[:3])
+}
+
+func (id ID) String() string {
+	return string(id)
+}
+
+// IDField accesses a field holding a salesforce ID, such as AccountId, in the SObject. The ID is returned in its 18
+// character form. Empty ID is returned if the field doesn't exist.
+func (s *SObject) IDField(key string) ID {
+	return ID(s.StringField(key)).To18()
+}
+
+// idSuffix computes the 3 character checksum of a 15 character ID.
+func idSuffix(id string) string {
+	var suffix [3]byte
+
+	for chunk := 0; chunk < 3; chunk++ {
+		bits := 0
+		for i := 0; i < 5; i++ {
+			c := id[chunk*5+i]
+			if 'A' <= c && c <= 'Z' {
+				bits |= 1 << i
+			}
+		}
+
+		suffix[chunk] = idSuffixAlphabet[bits]
+	}
+
+	return string(suffix[:])
+}
+
+// isSingleCase reports whether s doesn't contain both lower and upper case letters.
+func isSingleCase(s string) bool {
+	return strings.ToLower(s) == s || strings.ToUpper(s) == s
+}
+
+func isAlphanumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func upper(c byte) byte {
+	if 'a' <= c && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+
+	return c
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c - 'A' + 'a'
+	}
+
+	return c
+}
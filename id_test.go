@@ -0,0 +1,68 @@
+package simpleforce
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseID(t *testing.T) {
+	assert := assert.New(t)
+
+	id, err := ParseID("0015000000Gv7qJ")
+	assert.NoError(err)
+	assert.Equal(ID("0015000000Gv7qJAAR"), id)
+
+	id, err = ParseID("0015000000Gv7qJAAR")
+	assert.NoError(err)
+	assert.Equal(ID("0015000000Gv7qJAAR"), id)
+
+	// The case of the first 15 characters is restored from the checksum.
+	id, err = ParseID("0015000000gv7qjaar")
+	assert.NoError(err)
+	assert.Equal(ID("0015000000Gv7qJAAR"), id)
+
+	id, err = ParseID("0015000000GV7QJAAR")
+	assert.NoError(err)
+	assert.Equal(ID("0015000000Gv7qJAAR"), id)
+
+	// The checksum of a mixed case ID must match.
+	invalid := []string{"", "object1", "0015000000Gv7q!", "0015000000Gv7qJAA9", "001500000000000AAB", "0015000000Gv7qjAAR"}
+	for _, s := range invalid {
+		_, err = ParseID(s)
+		assert.IsType(ErrInvalidID{}, err, s)
+	}
+}
+
+func TestID(t *testing.T) {
+	assert := assert.New(t)
+
+	id := ID("0015000000Gv7qJ")
+
+	assert.True(id.Valid())
+	assert.Equal(ID("0015000000Gv7qJAAR"), id.To18())
+	assert.Equal(id, id.To18().To15())
+	assert.Equal("001", id.KeyPrefix())
+
+	assert.True(id.Equal("0015000000Gv7qJAAR"))
+	assert.True(ID("0015000000gv7qjaar").Equal(id))
+	assert.False(id.Equal("0015000000Gv7qj"))
+
+	assert.False(ID("object1").Valid())
+	assert.Equal(ID("object1"), ID("object1").To18())
+	assert.True(ID("object1").Equal("object1"))
+}
+
+func TestSObject_ID(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := NewSObject("Account").SetID("0015000000Gv7qJ")
+	assert.Equal("0015000000Gv7qJAAR", obj.StringField("Id"))
+	assert.Equal("0015000000Gv7qJAAR", obj.ID())
+
+	(*obj)["Id"] = "0015000000Gv7qJ"
+	assert.Equal("0015000000Gv7qJAAR", obj.ID())
+
+	obj.Set("ParentId", "0015000000gv7qjaar")
+	assert.Equal(ID("0015000000Gv7qJAAR"), obj.IDField("ParentId"))
+}
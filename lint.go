@@ -0,0 +1,455 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eleanorhealth/simpleforce/soql"
+	"github.com/pkg/errors"
+)
+
+// LintSeverity is the severity of a LintIssue.
+type LintSeverity int
+
+const (
+	// LintError marks queries salesforce will reject.
+	LintError LintSeverity = iota
+	// LintWarning marks queries that are valid but likely to perform poorly or fail on large data volumes.
+	LintWarning
+)
+
+func (s LintSeverity) String() string {
+	if s == LintWarning {
+		return "warning"
+	}
+
+	return "error"
+}
+
+// Lint rules.
+const (
+	LintRuleSyntax             = "syntax"
+	LintRuleUnknownField       = "unknown-field"
+	LintRuleNonSelectiveFilter = "non-selective-filter"
+	LintRuleUnboundedQuery     = "unbounded-query"
+	LintRuleAPIVersion         = "api-version"
+)
+
+// LintIssue is a problem found in a query by Linter.
+type LintIssue struct {
+	Severity LintSeverity
+	Rule     string
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.Severity, i.Message, i.Rule)
+}
+
+// aggregateFunctions are the SOQL functions that make a query return aggregated results.
+var aggregateFunctions = map[string]bool{
+	"AVG": true, "COUNT": true, "COUNT_DISTINCT": true, "MIN": true, "MAX": true, "SUM": true,
+}
+
+// indexedStandardFields are the standard fields salesforce indexes on every SObject, besides ID and reference fields.
+var indexedStandardFields = map[string]bool{
+	"name": true, "createddate": true, "systemmodstamp": true, "recordtypeid": true,
+}
+
+// Linter checks SOQL queries before they are sent to salesforce: syntax, fields against describe metadata, selectivity
+// of filters on large objects, unbounded queries and constructs unsupported by the API version. Pass a DescribeCache
+// as the describer to avoid describing SObjects on every query.
+type Linter struct {
+	describer    SObjectDescriber
+	apiVersion   float64
+	largeObjects map[string]bool
+}
+
+// NewLinter creates a new Linter. apiVersion is the version queries are sent with, such as DefaultAPIVersion; it may
+// be empty to skip version checks. Filters on largeObjects, the SObjects with enough records for salesforce to require
+// selective queries, must use an indexed field.
+func NewLinter(describer SObjectDescriber, apiVersion string, largeObjects []string) *Linter {
+	version, _ := strconv.ParseFloat(strings.TrimPrefix(apiVersion, "v"), 64)
+
+	large := make(map[string]bool, len(largeObjects))
+	for _, name := range largeObjects {
+		large[strings.ToLower(name)] = true
+	}
+
+	return &Linter{
+		describer:    describer,
+		apiVersion:   version,
+		largeObjects: large,
+	}
+}
+
+// Lint checks the query and returns the issues found. An error is returned only if the describe metadata needed for
+// the checks couldn't be retrieved; syntax errors are reported as issues.
+func (l *Linter) Lint(ctx context.Context, query string) ([]LintIssue, error) {
+	stmt, err := soql.Parse(query)
+	if err != nil {
+		var syntaxErr *soql.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return []LintIssue{{Severity: LintError, Rule: LintRuleSyntax, Message: syntaxErr.Error()}}, nil
+		}
+
+		return nil, err
+	}
+
+	run := &lintRun{
+		Linter: l,
+		ctx:    ctx,
+		fields: make(map[string]map[string]*FieldMeta),
+		metas:  make(map[string]*SObjectMeta),
+	}
+
+	err = run.statement(stmt, stmt.From, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return run.issues, nil
+}
+
+// lintRun holds the state of a single Lint call.
+type lintRun struct {
+	*Linter
+	ctx    context.Context
+	metas  map[string]*SObjectMeta
+	fields map[string]map[string]*FieldMeta
+	issues []LintIssue
+}
+
+func (r *lintRun) report(severity LintSeverity, rule, format string, args ...interface{}) {
+	r.issues = append(r.issues, LintIssue{Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *lintRun) describe(typeName string) (*SObjectMeta, map[string]*FieldMeta, error) {
+	key := strings.ToLower(typeName)
+	if meta, ok := r.metas[key]; ok {
+		return meta, r.fields[key], nil
+	}
+
+	meta, err := r.describer.DescribeSObject(r.ctx, NewSObject(typeName))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields, err := meta.Fields()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	indexed := make(map[string]*FieldMeta, len(fields))
+	for i := range fields {
+		indexed[strings.ToLower(fields[i].Name)] = &fields[i]
+	}
+
+	r.metas[key] = meta
+	r.fields[key] = indexed
+
+	return meta, indexed, nil
+}
+
+// statement lints a query against typeName. Only top level queries are checked for selectivity and bounds, since
+// subqueries are limited by their parent records.
+func (r *lintRun) statement(stmt *soql.Statement, typeName string, topLevel bool) error {
+	aliases := make(map[string]bool)
+	aggregate := len(stmt.GroupBy) > 0
+
+	for _, item := range stmt.Select {
+		if item.Field != nil {
+			if len(item.Field.Alias) > 0 {
+				aliases[strings.ToLower(item.Field.Alias)] = true
+			}
+			if aggregateFunctions[strings.ToUpper(item.Field.Function)] {
+				aggregate = true
+			}
+		}
+	}
+
+	r.versions(stmt)
+
+	for _, item := range stmt.Select {
+		var err error
+
+		switch {
+		case item.Field != nil:
+			_, err = r.field(typeName, stmt.Alias, *item.Field, aliases)
+		case item.Subquery != nil:
+			err = r.subquery(typeName, item.Subquery)
+		case item.TypeOf != nil:
+			err = r.typeOf(typeName, item.TypeOf)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, expr := range []soql.Expr{stmt.Where, stmt.Having} {
+		err := r.expr(typeName, stmt.Alias, expr, aliases)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, field := range stmt.GroupBy {
+		_, err := r.field(typeName, stmt.Alias, field, aliases)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, item := range stmt.OrderBy {
+		_, err := r.field(typeName, stmt.Alias, item.Field, aliases)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !topLevel {
+		return nil
+	}
+
+	if stmt.Where == nil && stmt.Limit == nil && !aggregate {
+		r.report(LintWarning, LintRuleUnboundedQuery, "query on %s has neither WHERE nor LIMIT", typeName)
+	}
+
+	if stmt.Where != nil && r.largeObjects[strings.ToLower(typeName)] {
+		selective, err := r.selective(typeName, stmt.Alias, stmt.Where)
+		if err != nil {
+			return err
+		}
+
+		if !selective {
+			r.report(LintWarning, LintRuleNonSelectiveFilter, "filter on %s doesn't use an indexed field", typeName)
+		}
+	}
+
+	return nil
+}
+
+// field resolves a field or relationship path on typeName, reporting unknown fields. Nil is returned for fields that
+// can't be resolved, including fields of polymorphic relationships and aliases.
+func (r *lintRun) field(typeName, alias string, field soql.Field, aliases map[string]bool) (*FieldMeta, error) {
+	if len(field.Name) == 0 || strings.EqualFold(field.Function, "FIELDS") || aliases[strings.ToLower(field.Name)] {
+		return nil, nil
+	}
+
+	path := strings.Split(field.Name, ".")
+	if len(path) > 1 && len(alias) > 0 && strings.EqualFold(path[0], alias) {
+		path = path[1:]
+	}
+
+	for i, name := range path {
+		_, fields, err := r.describe(typeName)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == len(path)-1 {
+			meta, ok := fields[strings.ToLower(name)]
+			if !ok {
+				r.report(LintError, LintRuleUnknownField, "no such field %s on %s", name, typeName)
+				return nil, nil
+			}
+
+			return meta, nil
+		}
+
+		parent := r.relationship(fields, name)
+		if parent == nil {
+			r.report(LintError, LintRuleUnknownField, "no such relationship %s on %s", name, typeName)
+			return nil, nil
+		}
+
+		if len(parent.ReferenceTo) != 1 {
+			return nil, nil
+		}
+
+		typeName = parent.ReferenceTo[0]
+	}
+
+	return nil, nil
+}
+
+// relationship returns the reference field of the parent relationship name.
+func (r *lintRun) relationship(fields map[string]*FieldMeta, name string) *FieldMeta {
+	for _, field := range fields {
+		if len(field.RelationshipName) > 0 && strings.EqualFold(field.RelationshipName, name) {
+			return field
+		}
+	}
+
+	return nil
+}
+
+func (r *lintRun) subquery(typeName string, sub *soql.Statement) error {
+	meta, _, err := r.describe(typeName)
+	if err != nil {
+		return err
+	}
+
+	relationships, err := meta.ChildRelationships()
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range relationships {
+		if strings.EqualFold(rel.RelationshipName, sub.From) {
+			return r.statement(sub, rel.ChildSObject, false)
+		}
+	}
+
+	r.report(LintError, LintRuleUnknownField, "no such child relationship %s on %s", sub.From, typeName)
+
+	return nil
+}
+
+func (r *lintRun) typeOf(typeName string, typeOf *soql.TypeOf) error {
+	_, fields, err := r.describe(typeName)
+	if err != nil {
+		return err
+	}
+
+	if r.relationship(fields, typeOf.Relationship) == nil {
+		r.report(LintError, LintRuleUnknownField, "no such relationship %s on %s", typeOf.Relationship, typeName)
+		return nil
+	}
+
+	for _, when := range typeOf.When {
+		for _, name := range when.Fields {
+			_, err := r.field(when.Type, "", soql.Field{Name: name}, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *lintRun) expr(typeName, alias string, expr soql.Expr, aliases map[string]bool) error {
+	switch e := expr.(type) {
+	case *soql.LogicalExpr:
+		for _, operand := range e.Operands {
+			err := r.expr(typeName, alias, operand, aliases)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *soql.NotExpr:
+		return r.expr(typeName, alias, e.Operand, aliases)
+
+	case *soql.Comparison:
+		_, err := r.field(typeName, alias, e.Field, aliases)
+		if err != nil {
+			return err
+		}
+
+		if e.Value.Kind == soql.SubqueryValue {
+			return r.statement(e.Value.Subquery, e.Value.Subquery.From, false)
+		}
+	}
+
+	return nil
+}
+
+// selective reports whether the filter can use an index: a condition on an indexed field that isn't negative, null
+// or a LIKE with a leading wildcard. Salesforce can use an index for AND if any operand is selective, and for OR only
+// if all operands are.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.salesforce_large_data_volumes_bp.meta/salesforce_large_data_volumes_bp/ldv_deployments_infrastructure_indexes.htm
+func (r *lintRun) selective(typeName, alias string, expr soql.Expr) (bool, error) {
+	switch e := expr.(type) {
+	case *soql.LogicalExpr:
+		and := e.Operator == "AND"
+
+		for _, operand := range e.Operands {
+			selective, err := r.selective(typeName, alias, operand)
+			if err != nil {
+				return false, err
+			}
+
+			if and && selective {
+				return true, nil
+			}
+			if !and && !selective {
+				return false, nil
+			}
+		}
+
+		return !and, nil
+
+	case *soql.Comparison:
+		if len(e.Field.Function) > 0 || strings.Contains(e.Field.Name, ".") {
+			return false, nil
+		}
+
+		switch e.Operator {
+		case "!=", "NOT IN", "EXCLUDES":
+			return false, nil
+		case "LIKE":
+			if strings.HasPrefix(e.Value.Text, "%") {
+				return false, nil
+			}
+		}
+
+		if e.Value.Kind == soql.NullValue {
+			return false, nil
+		}
+
+		// Unknown fields were already reported with the WHERE clause.
+		_, fields, err := r.describe(typeName)
+		if err != nil {
+			return false, err
+		}
+
+		meta, ok := fields[strings.ToLower(e.Field.Name)]
+		if !ok {
+			return false, nil
+		}
+
+		indexed := meta.Type == "id" || meta.Type == "reference" || meta.ExternalID || meta.Unique ||
+			indexedStandardFields[strings.ToLower(meta.Name)]
+
+		return indexed, nil
+	}
+
+	return false, nil
+}
+
+// versions reports constructs of the statement the API version doesn't support.
+func (r *lintRun) versions(stmt *soql.Statement) {
+	if r.apiVersion == 0 {
+		return
+	}
+
+	require := func(construct string, version float64) {
+		if r.apiVersion < version {
+			r.report(LintError, LintRuleAPIVersion, "%s requires API version %.1f or later", construct, version)
+		}
+	}
+
+	for _, item := range stmt.Select {
+		if item.Field != nil && strings.EqualFold(item.Field.Function, "FIELDS") {
+			require("FIELDS()", 51)
+		}
+		if item.TypeOf != nil {
+			require("TYPEOF", 46)
+		}
+	}
+
+	switch stmt.With {
+	case "SECURITY_ENFORCED":
+		require("WITH SECURITY_ENFORCED", 48)
+	case "USER_MODE", "SYSTEM_MODE":
+		require("WITH "+stmt.With, 57)
+	}
+
+	if stmt.Offset != nil {
+		require("OFFSET", 24)
+	}
+}
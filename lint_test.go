@@ -0,0 +1,109 @@
+package simpleforce
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var lintDescriber = staticDescriber{
+	"Account": `{
+		"name": "Account",
+		"fields": [
+			{"name": "Id", "type": "id"},
+			{"name": "Name", "type": "string"},
+			{"name": "Industry", "type": "picklist"},
+			{"name": "OwnerId", "type": "reference", "referenceTo": ["User"], "relationshipName": "Owner"}
+		],
+		"childRelationships": [{"childSObject": "Contact", "field": "AccountId", "relationshipName": "Contacts"}]
+	}`,
+	"Contact": `{
+		"name": "Contact",
+		"fields": [
+			{"name": "Id", "type": "id"},
+			{"name": "LastName", "type": "string"},
+			{"name": "Email", "type": "email", "idLookup": true},
+			{"name": "External__c", "type": "string", "externalId": true},
+			{"name": "AccountId", "type": "reference", "referenceTo": ["Account"], "relationshipName": "Account"}
+		]
+	}`,
+	"User": `{"name": "User", "fields": [{"name": "Id", "type": "id"}, {"name": "Username", "type": "string"}]}`,
+}
+
+func TestLinter_Lint(t *testing.T) {
+	assert := assert.New(t)
+
+	l := NewLinter(lintDescriber, DefaultAPIVersion, []string{"Contact"})
+
+	issues, err := l.Lint(context.Background(), "SELECT Id, c.Account.Owner.Username, Account.Industry FROM Contact c "+
+		"WHERE External__c = 'x' AND LastName != 'Smith' ORDER BY LastName")
+	assert.NoError(err)
+	assert.Empty(issues)
+
+	issues, err = l.Lint(context.Background(), "SELECT Name, COUNT(Id) total, (SELECT LastName FROM Contacts) FROM Account "+
+		"GROUP BY Name ORDER BY total")
+	assert.NoError(err)
+	assert.Empty(issues)
+}
+
+func TestLinter_Lint_issues(t *testing.T) {
+	assert := assert.New(t)
+
+	l := NewLinter(lintDescriber, DefaultAPIVersion, []string{"Contact"})
+
+	tests := []struct {
+		query  string
+		issues []LintIssue
+	}{
+		{
+			"SELECT Id FROM Contact WHERE",
+			[]LintIssue{{LintError, LintRuleSyntax, `soql: syntax error at position 28: expected field name, got ""`}},
+		},
+		{
+			"SELECT Id, Nme, Account.Onwer.Name, (SELECT Id FROM Cases) FROM Contact WHERE Id IN (SELECT Foo FROM Account)",
+			[]LintIssue{
+				{LintError, LintRuleUnknownField, "no such field Nme on Contact"},
+				{LintError, LintRuleUnknownField, "no such relationship Onwer on Account"},
+				{LintError, LintRuleUnknownField, "no such child relationship Cases on Contact"},
+				{LintError, LintRuleUnknownField, "no such field Foo on Account"},
+			},
+		},
+		{
+			"SELECT Id FROM Contact",
+			[]LintIssue{{LintWarning, LintRuleUnboundedQuery, "query on Contact has neither WHERE nor LIMIT"}},
+		},
+		{
+			"SELECT Id FROM Contact WHERE LastName = 'Smith' OR Email = 'a@example.com'",
+			[]LintIssue{{LintWarning, LintRuleNonSelectiveFilter, "filter on Contact doesn't use an indexed field"}},
+		},
+		{
+			"SELECT Id FROM Contact WHERE Nme = 'Smith'",
+			[]LintIssue{
+				{LintError, LintRuleUnknownField, "no such field Nme on Contact"},
+				{LintWarning, LintRuleNonSelectiveFilter, "filter on Contact doesn't use an indexed field"},
+			},
+		},
+		{
+			"SELECT Id FROM Contact WHERE External__c LIKE '%x' AND AccountId != null",
+			[]LintIssue{{LintWarning, LintRuleNonSelectiveFilter, "filter on Contact doesn't use an indexed field"}},
+		},
+		{
+			"SELECT FIELDS(ALL) FROM Account WITH SECURITY_ENFORCED LIMIT 10",
+			[]LintIssue{
+				{LintError, LintRuleAPIVersion, "FIELDS() requires API version 51.0 or later"},
+				{LintError, LintRuleAPIVersion, "WITH SECURITY_ENFORCED requires API version 48.0 or later"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		issues, err := l.Lint(context.Background(), test.query)
+		assert.NoError(err, test.query)
+		assert.Equal(test.issues, issues, test.query)
+	}
+
+	issues, err := NewLinter(lintDescriber, "v52.0", nil).Lint(context.Background(), "SELECT FIELDS(ALL) FROM Account LIMIT 10")
+	assert.NoError(err)
+	assert.Empty(issues)
+}
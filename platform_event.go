@@ -0,0 +1,204 @@
+package simpleforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxCollectionSize is the number of records an sObject collection request accepts.
+const maxCollectionSize = 200
+
+// PublishResult holds the result of publishing a platform event. Success only means salesforce queued the event.
+type PublishResult struct {
+	ID      string      `json:"id"`
+	Success bool        `json:"success"`
+	Errors  []SaveError `json:"errors"`
+}
+
+// EventPublisher publishes platform events. HTTPClient implements EventPublisher.
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, event *SObject) (*PublishResult, error)
+	PublishEvents(ctx context.Context, events []*SObject) ([]PublishResult, error)
+}
+
+// PublishEvent publishes a platform event, an SObject whose type ends with __e.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.platform_events.meta/platform_events/platform_events_publish_api.htm
+func (h *HTTPClient) PublishEvent(ctx context.Context, event *SObject) (*PublishResult, error) {
+	if !isPlatformEvent(event) {
+		return nil, ErrInvalidSObject{"Type is not a platform event"}
+	}
+
+	reqData, err := json.Marshal(event.makeCopy(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	url := h.makeURL("sobjects/" + event.Type() + "/")
+
+	res, err := h.request(ctx, http.MethodPost, url, bytes.NewReader(reqData), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	result := &PublishResult{}
+
+	err = json.NewDecoder(res.Body).Decode(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Success {
+		return result, ErrFailure
+	}
+
+	return result, nil
+}
+
+// PublishEvents publishes platform events in batches using sObject collections. Each event is published or fails
+// on its own; the results are in the order of events. If a batch can't be published, the results of the batches
+// published before it are returned with the error, so only the events from len(results) on need publishing again.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_create.htm
+func (h *HTTPClient) PublishEvents(ctx context.Context, events []*SObject) ([]PublishResult, error) {
+	for _, event := range events {
+		if !isPlatformEvent(event) {
+			return nil, ErrInvalidSObject{"Type is not a platform event"}
+		}
+	}
+
+	results := make([]PublishResult, 0, len(events))
+
+	for start := 0; start < len(events); start += maxCollectionSize {
+		end := start + maxCollectionSize
+		if end > len(events) {
+			end = len(events)
+		}
+
+		records := make([]map[string]interface{}, 0, end-start)
+		for _, event := range events[start:end] {
+			record := event.makeCopy(nil)
+			record[sobjectAttributesKey] = map[string]string{"type": event.Type()}
+			records = append(records, record)
+		}
+
+		reqData, err := json.Marshal(map[string]interface{}{
+			"allOrNone": false,
+			"records":   records,
+		})
+		if err != nil {
+			return results, err
+		}
+
+		url := h.makeURL("composite/sobjects")
+
+		res, err := h.request(ctx, http.MethodPost, url, bytes.NewReader(reqData), nil)
+		if err != nil {
+			return results, err
+		}
+
+		var batch []PublishResult
+		err = json.NewDecoder(res.Body).Decode(&batch)
+		res.Body.Close()
+		if err != nil {
+			return results, err
+		}
+
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
+
+func isPlatformEvent(sobj *SObject) bool {
+	return strings.HasSuffix(sobj.Type(), "__e")
+}
+
+// PlatformEvent is a platform event received from the streaming API.
+type PlatformEvent struct {
+	Channel  string
+	ReplayID int64
+	// EventUUID identifies the event across replays. It is empty before API version 52.0.
+	EventUUID   string
+	CreatedDate time.Time
+	CreatedByID string
+	// Fields holds the fields of the event, including CreatedDate and CreatedById.
+	Fields *SObject
+}
+
+// Decode decodes the fields of the event into v, such as a struct with json tags named after the event fields.
+func (e *PlatformEvent) Decode(v interface{}) error {
+	data, err := json.Marshal(e.Fields)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// DecodePlatformEvent decodes the platform event received as a streaming event.
+func DecodePlatformEvent(event *StreamingEvent) (*PlatformEvent, error) {
+	if event.SObject == nil {
+		return nil, ErrStreaming{event.Channel, "event has no platform event payload"}
+	}
+
+	var data struct {
+		Event struct {
+			EventUUID string `json:"EventUuid"`
+		} `json:"event"`
+	}
+
+	err := json.Unmarshal(event.Data, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PlatformEvent{
+		Channel:     event.Channel,
+		ReplayID:    event.ReplayID,
+		EventUUID:   data.Event.EventUUID,
+		CreatedDate: event.SObject.DateTimeField("CreatedDate"),
+		CreatedByID: event.SObject.StringField("CreatedById"),
+		Fields:      event.SObject,
+	}, nil
+}
+
+// PlatformEventHandler processes a platform event. Returning an error stops the subscriber without saving the
+// replay ID of the event, so it is received again when the subscriber restarts.
+type PlatformEventHandler func(ctx context.Context, event *PlatformEvent) error
+
+// PlatformEventSubscriber subscribes to platform events and saves the replay ID of each event once it is handled.
+type PlatformEventSubscriber struct {
+	streaming *StreamingClient
+	store     ReplayStore
+}
+
+// NewPlatformEventSubscriber creates a new PlatformEventSubscriber receiving events through streaming and
+// persisting replay IDs to store.
+func NewPlatformEventSubscriber(streaming *StreamingClient, store ReplayStore) *PlatformEventSubscriber {
+	return &PlatformEventSubscriber{
+		streaming: streaming,
+		store:     store,
+	}
+}
+
+// Run subscribes to the platform events named by eventTypes, such as Order_Placed__e, and passes them to handler
+// one at a time, with the same delivery guarantees as ChangeSubscriber.Run.
+func (s *PlatformEventSubscriber) Run(ctx context.Context, eventTypes []string, handler PlatformEventHandler) error {
+	channels := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		channels = append(channels, "/event/"+eventType)
+	}
+
+	return runReplaySubscriber(ctx, s.streaming, s.store, channels, func(ctx context.Context, event *StreamingEvent) error {
+		platformEvent, err := DecodePlatformEvent(event)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, platformEvent)
+	})
+}
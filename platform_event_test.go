@@ -0,0 +1,185 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClient_PublishEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/sobjects/Order_Placed__e/", r.URL.Path)
+
+		var body map[string]interface{}
+		assert.NoError(json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(map[string]interface{}{"Order_Number__c": "O-1"}, body)
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "e00000000000001AAA", "success": true, "errors": []}`))
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	result, err := client.PublishEvent(context.Background(), NewSObject("Order_Placed__e").Set("Order_Number__c", "O-1"))
+	assert.NoError(err)
+	assert.Equal("e00000000000001AAA", result.ID)
+
+	_, err = client.PublishEvent(context.Background(), NewSObject("Account"))
+	assert.Error(err)
+}
+
+func TestHTTPClient_PublishEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	var batches []int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/composite/sobjects", r.URL.Path)
+
+		var body struct {
+			AllOrNone bool
+			Records   []map[string]interface{}
+		}
+		assert.NoError(json.NewDecoder(r.Body).Decode(&body))
+		assert.False(body.AllOrNone)
+		batches = append(batches, len(body.Records))
+
+		var results []map[string]interface{}
+		for _, record := range body.Records {
+			assert.Equal(map[string]interface{}{"type": "Order_Placed__e"}, record["attributes"])
+
+			if record["Order_Number__c"] == "" {
+				results = append(results, map[string]interface{}{"success": false, "errors": []map[string]interface{}{
+					{"statusCode": "REQUIRED_FIELD_MISSING", "message": "Required fields are missing: [Order_Number__c]", "fields": []string{"Order_Number__c"}},
+				}})
+				continue
+			}
+
+			results = append(results, map[string]interface{}{"id": "e00" + record["Order_Number__c"].(string), "success": true, "errors": []string{}})
+		}
+
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	var events []*SObject
+	for i := 0; i < 250; i++ {
+		events = append(events, NewSObject("Order_Placed__e").Set("Order_Number__c", fmt.Sprint(i)))
+	}
+	events[210].Set("Order_Number__c", "")
+
+	results, err := client.PublishEvents(context.Background(), events)
+	assert.NoError(err)
+	assert.Equal([]int{200, 50}, batches)
+	assert.Len(results, 250)
+	assert.Equal("e00249", results[249].ID)
+	assert.False(results[210].Success)
+	assert.Equal("REQUIRED_FIELD_MISSING", results[210].Errors[0].StatusCode)
+}
+
+func TestHTTPClient_PublishEvents_failedBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	batches := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batches++
+		if batches > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`[{"message": "Server is temporarily unavailable", "errorCode": "SERVER_UNAVAILABLE"}]`))
+			return
+		}
+
+		var body struct {
+			Records []map[string]interface{}
+		}
+		assert.NoError(json.NewDecoder(r.Body).Decode(&body))
+
+		var results []map[string]interface{}
+		for _, record := range body.Records {
+			results = append(results, map[string]interface{}{"id": "e00" + record["Order_Number__c"].(string), "success": true, "errors": []string{}})
+		}
+
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	var events []*SObject
+	for i := 0; i < 450; i++ {
+		events = append(events, NewSObject("Order_Placed__e").Set("Order_Number__c", fmt.Sprint(i)))
+	}
+
+	// The events of the first batch went out, so their results are returned with the error.
+	results, err := client.PublishEvents(context.Background(), events)
+	assert.Error(err)
+	assert.Equal(2, batches)
+	assert.Len(results, 200)
+	assert.Equal("e00199", results[199].ID)
+}
+
+func TestPlatformEventSubscriber_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	var replayIDs []float64
+	ts := newCometDServer(t, []map[string]interface{}{{
+		"channel": "/event/Order_Placed__e",
+		"data": map[string]interface{}{
+			"event": map[string]interface{}{"replayId": 3, "EventUuid": "b1c2"},
+			"payload": map[string]interface{}{
+				"CreatedDate":     "2021-03-04T05:06:07.000Z",
+				"CreatedById":     "005000000000001AAA",
+				"Order_Number__c": "O-1",
+				"Amount__c":       12.5,
+			},
+		},
+	}}, &replayIDs)
+	defer ts.Close()
+
+	store := NewMemoryReplayStore()
+	subscriber := NewPlatformEventSubscriber(NewStreamingClient(ts.Client(), ts.URL, DefaultAPIVersion), store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type orderPlaced struct {
+		OrderNumber string  `json:"Order_Number__c"`
+		Amount      float64 `json:"Amount__c"`
+	}
+
+	var orders []orderPlaced
+	err := subscriber.Run(ctx, []string{"Order_Placed__e"}, func(ctx context.Context, event *PlatformEvent) error {
+		assert.Equal("b1c2", event.EventUUID)
+		assert.Equal("005000000000001AAA", event.CreatedByID)
+		assert.Equal(2021, event.CreatedDate.Year())
+
+		var order orderPlaced
+		assert.NoError(event.Decode(&order))
+		orders = append(orders, order)
+
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(err, context.Canceled)
+
+	assert.Equal([]orderPlaced{{OrderNumber: "O-1", Amount: 12.5}}, orders)
+	assert.Equal([]float64{-1}, replayIDs)
+
+	replayID, ok, err := store.LoadReplayID(context.Background(), "/event/Order_Placed__e")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(int64(3), replayID)
+}
@@ -0,0 +1,649 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the Avro schema of the events of a topic.
+// Ref: https://avro.apache.org/docs/1.10.2/spec.html
+type Schema struct {
+	ID string
+	// JSON is the schema definition.
+	JSON string
+
+	root *avroType
+}
+
+// Name returns the name of the record described by the schema, such as Order_Placed__e or AccountChangeEvent.
+func (s *Schema) Name() string {
+	return s.root.name
+}
+
+// avroType is a parsed Avro schema. Named types are shared, so recursive records point to themselves.
+type avroType struct {
+	kind        string
+	name        string
+	logicalType string
+	fields      []*avroField
+	symbols     []string
+	items       *avroType
+	values      *avroType
+	branches    []*avroType
+	size        int
+}
+
+type avroField struct {
+	name       string
+	typ        *avroType
+	defaultVal interface{}
+	hasDefault bool
+}
+
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true, "float": true, "double": true, "bytes": true, "string": true,
+}
+
+// ParseSchema parses an Avro schema definition.
+func ParseSchema(id, schemaJSON string) (*Schema, error) {
+	var def interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(schemaJSON))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&def)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseAvroType(def, "", make(map[string]*avroType))
+	if err != nil {
+		return nil, err
+	}
+
+	if root.kind != "record" {
+		return nil, fmt.Errorf("avro: schema %s is a %s, not a record", id, root.kind)
+	}
+
+	return &Schema{ID: id, JSON: schemaJSON, root: root}, nil
+}
+
+func parseAvroType(def interface{}, namespace string, names map[string]*avroType) (*avroType, error) {
+	switch def := def.(type) {
+	case string:
+		if avroPrimitives[def] {
+			return &avroType{kind: def}, nil
+		}
+
+		if t, ok := names[fullName(def, namespace)]; ok {
+			return t, nil
+		}
+		if t, ok := names[def]; ok {
+			return t, nil
+		}
+
+		return nil, fmt.Errorf("avro: unknown type %s", def)
+	case []interface{}:
+		t := &avroType{kind: "union"}
+		for _, branch := range def {
+			branchType, err := parseAvroType(branch, namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			t.branches = append(t.branches, branchType)
+		}
+		return t, nil
+	case map[string]interface{}:
+		return parseAvroComplexType(def, namespace, names)
+	default:
+		return nil, fmt.Errorf("avro: invalid type definition %v", def)
+	}
+}
+
+func parseAvroComplexType(def map[string]interface{}, namespace string, names map[string]*avroType) (*avroType, error) {
+	kind, _ := def["type"].(string)
+	logicalType, _ := def["logicalType"].(string)
+
+	if avroPrimitives[kind] {
+		return &avroType{kind: kind, logicalType: logicalType}, nil
+	}
+
+	t := &avroType{kind: kind, logicalType: logicalType}
+
+	switch kind {
+	case "record", "enum", "fixed":
+		name, _ := def["name"].(string)
+		if ns, ok := def["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+
+		full := fullName(name, namespace)
+		if i := strings.LastIndex(full, "."); i >= 0 {
+			namespace = full[:i]
+		}
+
+		t.name = name[strings.LastIndex(name, ".")+1:]
+		names[full] = t
+	}
+
+	switch kind {
+	case "record":
+		fields, _ := def["fields"].([]interface{})
+		for _, fieldDef := range fields {
+			fieldDef, ok := fieldDef.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("avro: invalid field of record %s", t.name)
+			}
+
+			field := &avroField{}
+			field.name, _ = fieldDef["name"].(string)
+			field.defaultVal, field.hasDefault = fieldDef["default"]
+
+			var err error
+			field.typ, err = parseAvroType(fieldDef["type"], namespace, names)
+			if err != nil {
+				return nil, err
+			}
+
+			t.fields = append(t.fields, field)
+		}
+	case "enum":
+		symbols, _ := def["symbols"].([]interface{})
+		for _, symbol := range symbols {
+			s, _ := symbol.(string)
+			t.symbols = append(t.symbols, s)
+		}
+	case "fixed":
+		size, _ := def["size"].(json.Number)
+		n, err := size.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("avro: invalid size of fixed %s", t.name)
+		}
+		t.size = int(n)
+	case "array":
+		items, err := parseAvroType(def["items"], namespace, names)
+		if err != nil {
+			return nil, err
+		}
+		t.items = items
+	case "map":
+		values, err := parseAvroType(def["values"], namespace, names)
+		if err != nil {
+			return nil, err
+		}
+		t.values = values
+	default:
+		return nil, fmt.Errorf("avro: unknown type %s", kind)
+	}
+
+	return t, nil
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || len(namespace) == 0 {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+// Decode decodes an event payload encoded with the schema. Records are decoded to maps, unions to the value of
+// their branch, enums to their symbol, ints and longs to int64, and bytes and fixed to []byte.
+func (s *Schema) Decode(payload []byte) (map[string]interface{}, error) {
+	r := bytes.NewReader(payload)
+
+	v, err := decodeAvro(r, s.root)
+	if err != nil {
+		return nil, fmt.Errorf("avro: decoding %s: %w", s.root.name, err)
+	}
+
+	return v.(map[string]interface{}), nil
+}
+
+func decodeAvro(r *bytes.Reader, t *avroType) (interface{}, error) {
+	switch t.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.ReadByte()
+		return b != 0, err
+	case "int", "long":
+		return binary.ReadVarint(r)
+	case "float":
+		var bits uint32
+		err := binary.Read(r, binary.LittleEndian, &bits)
+		return math.Float32frombits(bits), err
+	case "double":
+		var bits uint64
+		err := binary.Read(r, binary.LittleEndian, &bits)
+		return math.Float64frombits(bits), err
+	case "bytes", "string":
+		n, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || n > int64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		if t.kind == "string" {
+			return string(b), err
+		}
+		return b, err
+	case "fixed":
+		b := make([]byte, t.size)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	case "enum":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(t.symbols)) {
+			return nil, fmt.Errorf("invalid symbol %d of enum %s", i, t.name)
+		}
+		return t.symbols[i], nil
+	case "union":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(t.branches)) {
+			return nil, fmt.Errorf("invalid union branch %d", i)
+		}
+		return decodeAvro(r, t.branches[i])
+	case "record":
+		record := make(map[string]interface{}, len(t.fields))
+		for _, field := range t.fields {
+			v, err := decodeAvro(r, field.typ)
+			if err != nil {
+				return nil, err
+			}
+			record[field.name] = v
+		}
+		return record, nil
+	case "array":
+		items := []interface{}{}
+		err := decodeAvroBlocks(r, func() error {
+			v, err := decodeAvro(r, t.items)
+			items = append(items, v)
+			return err
+		})
+		return items, err
+	case "map":
+		values := map[string]interface{}{}
+		err := decodeAvroBlocks(r, func() error {
+			key, err := decodeAvro(r, &avroType{kind: "string"})
+			if err != nil {
+				return err
+			}
+			v, err := decodeAvro(r, t.values)
+			values[key.(string)] = v
+			return err
+		})
+		return values, err
+	default:
+		return nil, fmt.Errorf("unknown type %s", t.kind)
+	}
+}
+
+// decodeAvroBlocks calls fn for each item of an array or map, which are encoded as blocks of items ending with an
+// empty block. A negative count is followed by the size of the block in bytes.
+func decodeAvroBlocks(r *bytes.Reader, fn func() error) error {
+	for {
+		count, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			_, err = binary.ReadVarint(r)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i := int64(0); i < count; i++ {
+			err = fn()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// FieldNames expands the bitmaps of the changedFields, nulledFields and diffFields of a change event header into
+// field names. Bit i of a bitmap such as 0x0842 stands for field i of the schema. A bitmap prefixed with the index of
+// a compound field, such as 4-0x02 for BillingAddress, stands for the fields of its record, named like
+// BillingAddress.City.
+// Ref: https://developer.salesforce.com/docs/platform/pub-sub-api/guide/event-deserialization-considerations.html
+func (s *Schema) FieldNames(bitmaps []string) ([]string, error) {
+	var names []string
+
+	for _, bitmap := range bitmaps {
+		record := s.root
+		prefix := ""
+
+		if i := strings.Index(bitmap, "-"); i >= 0 {
+			index, err := strconv.Atoi(bitmap[:i])
+			if err != nil || index < 0 || index >= len(s.root.fields) {
+				return nil, fmt.Errorf("avro: invalid field bitmap %s", bitmap)
+			}
+
+			field := s.root.fields[index]
+			record = recordBranch(field.typ)
+			if record == nil {
+				return nil, fmt.Errorf("avro: field bitmap %s: %s is not a record", bitmap, field.name)
+			}
+
+			prefix = field.name + "."
+			bitmap = bitmap[i+1:]
+		}
+
+		bits, ok := new(big.Int).SetString(strings.TrimPrefix(bitmap, "0x"), 16)
+		if !ok || !strings.HasPrefix(bitmap, "0x") {
+			return nil, fmt.Errorf("avro: invalid field bitmap %s", bitmap)
+		}
+		if bits.BitLen() > len(record.fields) {
+			return nil, fmt.Errorf("avro: field bitmap %s exceeds the %d fields of %s", bitmap, len(record.fields), record.name)
+		}
+
+		for i, field := range record.fields {
+			if bits.Bit(i) == 1 {
+				names = append(names, prefix+field.name)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// recordBranch returns t if it is a record, or its record branch if it is a union such as ["null", "Address"].
+func recordBranch(t *avroType) *avroType {
+	if t.kind == "record" {
+		return t
+	}
+
+	for _, branch := range t.branches {
+		if branch.kind == "record" {
+			return branch
+		}
+	}
+
+	return nil
+}
+
+// Encode encodes an event with the schema. Missing fields take their default value, and time.Time values are
+// accepted for timestamp-millis longs.
+func (s *Schema) Encode(record map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := encodeAvro(&buf, s.root, record)
+	if err != nil {
+		return nil, fmt.Errorf("avro: encoding %s: %w", s.root.name, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeAvro(buf *bytes.Buffer, t *avroType, v interface{}) error {
+	switch t.kind {
+	case "null":
+		if v != nil {
+			return fmt.Errorf("%v is not null", v)
+		}
+		return nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%v is not a boolean", v)
+		}
+		if b {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		return nil
+	case "int", "long":
+		n, ok := avroInt(v)
+		if !ok {
+			return fmt.Errorf("%v is not an integer", v)
+		}
+		writeVarint(buf, n)
+		return nil
+	case "float", "double":
+		f, ok := avroFloat(v)
+		if !ok {
+			return fmt.Errorf("%v is not a number", v)
+		}
+		if t.kind == "float" {
+			return binary.Write(buf, binary.LittleEndian, math.Float32bits(float32(f)))
+		}
+		return binary.Write(buf, binary.LittleEndian, math.Float64bits(f))
+	case "bytes", "string":
+		var b []byte
+		switch v := v.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		default:
+			return fmt.Errorf("%v is not a %s", v, t.kind)
+		}
+		writeVarint(buf, int64(len(b)))
+		buf.Write(b)
+		return nil
+	case "fixed":
+		b, ok := v.([]byte)
+		if !ok || len(b) != t.size {
+			return fmt.Errorf("%v is not a fixed of size %d", v, t.size)
+		}
+		buf.Write(b)
+		return nil
+	case "enum":
+		s, _ := v.(string)
+		for i, symbol := range t.symbols {
+			if symbol == s {
+				writeVarint(buf, int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("%v is not a symbol of enum %s", v, t.name)
+	case "union":
+		for i, branch := range t.branches {
+			if avroMatches(branch, v) {
+				writeVarint(buf, int64(i))
+				return encodeAvro(buf, branch, v)
+			}
+		}
+		return fmt.Errorf("%v matches no branch of union", v)
+	case "record":
+		record, ok := avroRecord(v)
+		if !ok {
+			return fmt.Errorf("%v is not a record", v)
+		}
+		for _, field := range t.fields {
+			value, ok := record[field.name]
+			if !ok && field.hasDefault {
+				value = avroDefault(field)
+			}
+
+			err := encodeAvro(buf, field.typ, value)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+		}
+		return nil
+	case "array":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return fmt.Errorf("%v is not an array", v)
+		}
+		if rv.Len() > 0 {
+			writeVarint(buf, int64(rv.Len()))
+			for i := 0; i < rv.Len(); i++ {
+				err := encodeAvro(buf, t.items, rv.Index(i).Interface())
+				if err != nil {
+					return err
+				}
+			}
+		}
+		writeVarint(buf, 0)
+		return nil
+	case "map":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%v is not a map", v)
+		}
+		if rv.Len() > 0 {
+			writeVarint(buf, int64(rv.Len()))
+			iter := rv.MapRange()
+			for iter.Next() {
+				key := iter.Key().String()
+				writeVarint(buf, int64(len(key)))
+				buf.WriteString(key)
+
+				err := encodeAvro(buf, t.values, iter.Value().Interface())
+				if err != nil {
+					return err
+				}
+			}
+		}
+		writeVarint(buf, 0)
+		return nil
+	default:
+		return fmt.Errorf("unknown type %s", t.kind)
+	}
+}
+
+// avroDefault returns the default value of a field. The default of a union is a value of its first branch.
+func avroDefault(field *avroField) interface{} {
+	v := field.defaultVal
+
+	if n, ok := v.(json.Number); ok {
+		t := field.typ
+		if t.kind == "union" && len(t.branches) > 0 {
+			t = t.branches[0]
+		}
+
+		switch t.kind {
+		case "int", "long":
+			i, _ := n.Int64()
+			return i
+		default:
+			f, _ := n.Float64()
+			return f
+		}
+	}
+
+	return v
+}
+
+// avroMatches reports whether v can be encoded as a value of t, to choose the branch of a union.
+func avroMatches(t *avroType, v interface{}) bool {
+	switch t.kind {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "int", "long":
+		_, ok := avroInt(v)
+		return ok
+	case "float", "double":
+		_, ok := avroFloat(v)
+		return ok
+	case "string", "enum":
+		_, ok := v.(string)
+		return ok
+	case "bytes", "fixed":
+		_, ok := v.([]byte)
+		return ok
+	case "record":
+		_, ok := avroRecord(v)
+		return ok
+	case "array":
+		return v != nil && reflect.TypeOf(v).Kind() == reflect.Slice
+	case "map":
+		return v != nil && reflect.TypeOf(v).Kind() == reflect.Map
+	default:
+		return false
+	}
+}
+
+func avroInt(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case time.Time:
+		return v.UnixNano() / int64(time.Millisecond), true
+	default:
+		return 0, false
+	}
+}
+
+func avroFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func avroRecord(v interface{}) (map[string]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	mapType := reflect.TypeOf(map[string]interface{}{})
+	if rv.Kind() != reflect.Map || !rv.Type().ConvertibleTo(mapType) {
+		return nil, false
+	}
+
+	return rv.Convert(mapType).Interface().(map[string]interface{}), true
+}
+
+func writeVarint(buf *bytes.Buffer, n int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], n)])
+}
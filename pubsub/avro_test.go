@@ -0,0 +1,165 @@
+package pubsub
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const changeEventSchema = `{
+	"type": "record",
+	"name": "AccountChangeEvent",
+	"namespace": "com.sforce.eventbus",
+	"fields": [
+		{"name": "ChangeEventHeader", "type": {
+			"type": "record",
+			"name": "ChangeEventHeader",
+			"fields": [
+				{"name": "entityName", "type": "string"},
+				{"name": "recordIds", "type": {"type": "array", "items": "string"}},
+				{"name": "changeType", "type": {"type": "enum", "name": "ChangeType", "symbols": ["CREATE", "UPDATE", "DELETE"]}},
+				{"name": "commitTimestamp", "type": "long"},
+				{"name": "changedFields", "type": {"type": "array", "items": "string"}}
+			]
+		}},
+		{"name": "Name", "type": ["null", "string"], "default": null},
+		{"name": "AnnualRevenue", "type": ["null", "double"], "default": null},
+		{"name": "NumberOfEmployees", "type": ["null", "int"], "default": null},
+		{"name": "IsActive__c", "type": "boolean", "default": true},
+		{"name": "Tags__c", "type": {"type": "map", "values": "string"}, "default": {}},
+		{"name": "Parent", "type": ["null", "com.sforce.eventbus.ChangeEventHeader"], "default": null}
+	]
+}`
+
+func TestSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	schema, err := ParseSchema("schema1", changeEventSchema)
+	assert.NoError(err)
+	assert.Equal("AccountChangeEvent", schema.Name())
+
+	committed := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	payload, err := schema.Encode(map[string]interface{}{
+		"ChangeEventHeader": map[string]interface{}{
+			"entityName":      "Account",
+			"recordIds":       []string{"001000000000001AAA"},
+			"changeType":      "UPDATE",
+			"commitTimestamp": committed,
+			"changedFields":   []interface{}{"Name", "AnnualRevenue"},
+		},
+		"Name":              "Acme",
+		"AnnualRevenue":     1500.5,
+		"NumberOfEmployees": 12,
+		"Tags__c":           map[string]interface{}{"tier": "gold"},
+	})
+	assert.NoError(err)
+
+	record, err := schema.Decode(payload)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{
+		"ChangeEventHeader": map[string]interface{}{
+			"entityName":      "Account",
+			"recordIds":       []interface{}{"001000000000001AAA"},
+			"changeType":      "UPDATE",
+			"commitTimestamp": committed.UnixNano() / int64(time.Millisecond),
+			"changedFields":   []interface{}{"Name", "AnnualRevenue"},
+		},
+		"Name":              "Acme",
+		"AnnualRevenue":     1500.5,
+		"NumberOfEmployees": int64(12),
+		"IsActive__c":       true,
+		"Tags__c":           map[string]interface{}{"tier": "gold"},
+		"Parent":            nil,
+	}, record)
+
+	_, err = schema.Encode(map[string]interface{}{"Name": "Acme"})
+	assert.EqualError(err, "avro: encoding AccountChangeEvent: field ChangeEventHeader: <nil> is not a record")
+
+	_, err = schema.Decode(payload[:10])
+	assert.Error(err)
+
+	_, err = ParseSchema("schema2", `{"type": "record", "name": "Event__e", "fields": [{"name": "Unknown", "type": "Missing"}]}`)
+	assert.EqualError(err, "avro: unknown type Missing")
+}
+
+// The golden payload is encoded by goavro, see testdata/gen.
+func TestSchema_golden(t *testing.T) {
+	assert := assert.New(t)
+
+	schemaJSON, err := os.ReadFile(filepath.Join("testdata", "account_change_event.avsc"))
+	assert.NoError(err)
+	golden, err := os.ReadFile(filepath.Join("testdata", "account_change_event.avro"))
+	assert.NoError(err)
+
+	schema, err := ParseSchema("schema1", string(schemaJSON))
+	assert.NoError(err)
+
+	record := map[string]interface{}{
+		"ChangeEventHeader": map[string]interface{}{
+			"entityName":      "Account",
+			"recordIds":       []interface{}{"001000000000001AAA"},
+			"changeType":      "UPDATE",
+			"changeOrigin":    "com/salesforce/api/soap/53.0;client=SfdcInternalAPI/",
+			"transactionKey":  "0004f1c5-3c4a-4a51-9e8c-9a3d2c2e5f10",
+			"sequenceNumber":  int64(1),
+			"commitTimestamp": int64(1630497600000),
+			"commitNumber":    int64(10957366839372),
+			"commitUser":      "005000000000001AAA",
+			"nulledFields":    []interface{}{"0x40"},
+			"diffFields":      []interface{}{},
+			"changedFields":   []interface{}{"0x0842", "4-0x02"},
+		},
+		"Name":     "Acme Corp",
+		"Type":     nil,
+		"ParentId": nil,
+		"BillingAddress": map[string]interface{}{
+			"Street":          nil,
+			"City":            "Boston",
+			"State":           nil,
+			"PostalCode":      nil,
+			"Country":         nil,
+			"StateCode":       nil,
+			"CountryCode":     nil,
+			"Latitude":        nil,
+			"Longitude":       nil,
+			"GeocodeAccuracy": nil,
+		},
+		"Phone":             nil,
+		"AnnualRevenue":     nil,
+		"NumberOfEmployees": nil,
+		"OwnerId":           nil,
+		"CreatedDate":       nil,
+		"CreatedById":       nil,
+		"LastModifiedDate":  int64(1630497600000),
+		"LastModifiedById":  "005000000000001AAA",
+	}
+
+	decoded, err := schema.Decode(golden)
+	assert.NoError(err)
+	assert.Equal(record, decoded)
+
+	payload, err := schema.Encode(record)
+	assert.NoError(err)
+	assert.Equal(golden, payload)
+
+	changed, err := schema.FieldNames([]string{"0x0842", "4-0x02"})
+	assert.NoError(err)
+	assert.Equal([]string{"Name", "AnnualRevenue", "LastModifiedDate", "BillingAddress.City"}, changed)
+
+	nulled, err := schema.FieldNames([]string{"0x40"})
+	assert.NoError(err)
+	assert.Equal([]string{"AnnualRevenue"}, nulled)
+
+	_, err = schema.FieldNames([]string{"Name"})
+	assert.EqualError(err, "avro: invalid field bitmap Name")
+
+	_, err = schema.FieldNames([]string{"1-0x01"})
+	assert.EqualError(err, "avro: field bitmap 1-0x01: Name is not a record")
+
+	_, err = schema.FieldNames([]string{"0x4000"})
+	assert.EqualError(err, "avro: field bitmap 0x4000 exceeds the 13 fields of AccountChangeEvent")
+}
@@ -0,0 +1,354 @@
+// Package pubsub is a client of the salesforce Pub/Sub API, which publishes and subscribes to platform events and
+// change events over gRPC. Events are encoded with Avro, using schemas fetched from the API.
+// Ref: https://developer.salesforce.com/docs/platform/pub-sub-api/overview
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/eleanorhealth/simpleforce"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Endpoint is the address of the Pub/Sub API. Connections require TLS.
+const Endpoint = "api.pubsub.salesforce.com:7443"
+
+const servicePrefix = "/eventbus.v1.PubSub/"
+
+// DefaultBatchSize is the number of events a subscription requests at a time when no batch size is given.
+const DefaultBatchSize = 100
+
+// ErrNoCredentials is returned when the token of the token source lacks the instance URL or the identity URL that
+// the API requires with the access token.
+var ErrNoCredentials = errors.New("token has no instance_url or id")
+
+// Client publishes and subscribes to topics of the Pub/Sub API.
+type Client struct {
+	conn   grpc.ClientConnInterface
+	tokens oauth2.TokenSource
+
+	mu      sync.Mutex
+	schemas map[string]*Schema
+}
+
+// NewClient creates a new Client calling the API through conn, usually dialed to Endpoint. Calls are authenticated
+// with tokens from tokens, the token source of the oauth2 configuration used for simpleforce.NewHTTPClient, whose
+// tokens hold the instance URL and identity URL of the org.
+func NewClient(conn grpc.ClientConnInterface, tokens oauth2.TokenSource) *Client {
+	return &Client{
+		conn:    conn,
+		tokens:  tokens,
+		schemas: make(map[string]*Schema),
+	}
+}
+
+// authenticate adds the access token, instance URL and tenant ID of the org to the metadata of ctx.
+func (c *Client) authenticate(ctx context.Context) (context.Context, error) {
+	token, err := c.tokens.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	instanceURL, _ := token.Extra("instance_url").(string)
+	identityURL, _ := token.Extra("id").(string)
+
+	tenantID, err := tenantID(identityURL)
+	if len(instanceURL) == 0 || err != nil {
+		return nil, ErrNoCredentials
+	}
+
+	return metadata.AppendToOutgoingContext(ctx,
+		"accesstoken", token.AccessToken,
+		"instanceurl", instanceURL,
+		"tenantid", tenantID,
+	), nil
+}
+
+// tenantID returns the org ID from an identity URL, such as https://login.salesforce.com/id/{orgID}/{userID}.
+func tenantID(identityURL string) (string, error) {
+	u, err := url.Parse(identityURL)
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "id" {
+		return "", fmt.Errorf("invalid identity URL %s", identityURL)
+	}
+
+	return parts[1], nil
+}
+
+func (c *Client) invoke(ctx context.Context, method string, req, res message) error {
+	ctx, err := c.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.conn.Invoke(ctx, servicePrefix+method, req, res, grpc.ForceCodec(codec{}))
+}
+
+// GetTopic returns the topic named topicName, such as /event/Order_Placed__e or /data/AccountChangeEvent.
+func (c *Client) GetTopic(ctx context.Context, topicName string) (*TopicInfo, error) {
+	topic := &TopicInfo{}
+
+	err := c.invoke(ctx, "GetTopic", &topicRequest{topicName: topicName}, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	return topic, nil
+}
+
+// GetSchema returns the schema with ID schemaID. Schemas never change, so they are cached for the lifetime of the
+// client.
+func (c *Client) GetSchema(ctx context.Context, schemaID string) (*Schema, error) {
+	c.mu.Lock()
+	schema, ok := c.schemas[schemaID]
+	c.mu.Unlock()
+
+	if ok {
+		return schema, nil
+	}
+
+	info := &schemaInfo{}
+
+	err := c.invoke(ctx, "GetSchema", &schemaRequest{schemaID: schemaID}, info)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err = ParseSchema(schemaID, info.schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.schemas[schemaID] = schema
+	c.mu.Unlock()
+
+	return schema, nil
+}
+
+// Event is an event received from a topic.
+type Event struct {
+	Topic string
+	// ReplayID identifies the position of the event in the topic. Subscribing with ReplayCustom and the replay ID of
+	// the last event handled resumes after it.
+	ReplayID []byte
+	// ID is the UUID of the event, generated by its publisher.
+	ID       string
+	SchemaID string
+	// Fields holds the decoded payload of the event. Its type is the name of the schema, such as Order_Placed__e.
+	// Nested records, such as the ChangeEventHeader of change events, are maps. The changedFields, nulledFields and
+	// diffFields bitmaps of a ChangeEventHeader are expanded into field names, see Schema.FieldNames.
+	Fields *simpleforce.SObject
+}
+
+// Handler processes an event. Returning an error stops the subscription.
+type Handler func(ctx context.Context, event *Event) error
+
+// Subscribe receives the events of topicName, starting at preset, and passes them to handler one at a time until
+// ctx is done, handler returns an error or the stream fails. replayID is the replay ID to start after when preset is
+// ReplayCustom.
+//
+// Events are requested batchSize at a time, and the next batch is only requested once the events of the previous
+// batch have been handled, so a slow handler never has more than batchSize events waiting.
+func (c *Client) Subscribe(ctx context.Context, topicName string, preset ReplayPreset, replayID []byte, batchSize int32, handler Handler) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	authCtx, err := c.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	desc := &grpc.StreamDesc{StreamName: "Subscribe", ServerStreams: true, ClientStreams: true}
+
+	stream, err := c.conn.NewStream(authCtx, desc, servicePrefix+"Subscribe", grpc.ForceCodec(codec{}))
+	if err != nil {
+		return err
+	}
+
+	err = stream.SendMsg(&fetchRequest{
+		topicName:    topicName,
+		replayPreset: preset,
+		replayID:     replayID,
+		numRequested: batchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		res := &fetchResponse{}
+
+		err = stream.RecvMsg(res)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		for _, consumed := range res.events {
+			event, err := c.decodeEvent(ctx, topicName, consumed)
+			if err != nil {
+				return err
+			}
+
+			err = handler(ctx, event)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Responses without events keep the stream alive while events are pending.
+		if res.pendingNumRequested == 0 {
+			err = stream.SendMsg(&fetchRequest{topicName: topicName, numRequested: batchSize})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Client) decodeEvent(ctx context.Context, topicName string, consumed *consumerEvent) (*Event, error) {
+	schema, err := c.GetSchema(ctx, consumed.event.schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := schema.Decode(consumed.event.payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if header, ok := fields["ChangeEventHeader"].(map[string]interface{}); ok {
+		for _, key := range []string{"changedFields", "nulledFields", "diffFields"} {
+			items, ok := header[key].([]interface{})
+			if !ok {
+				continue
+			}
+
+			bitmaps := make([]string, 0, len(items))
+			for _, item := range items {
+				bitmap, _ := item.(string)
+				bitmaps = append(bitmaps, bitmap)
+			}
+
+			names, err := schema.FieldNames(bitmaps)
+			if err != nil {
+				return nil, err
+			}
+
+			expanded := make([]interface{}, len(names))
+			for i, name := range names {
+				expanded[i] = name
+			}
+			header[key] = expanded
+		}
+	}
+
+	sobj := simpleforce.NewSObject(schema.Name())
+	for key, value := range fields {
+		(*sobj)[key] = value
+	}
+
+	return &Event{
+		Topic:    topicName,
+		ReplayID: consumed.replayID,
+		ID:       consumed.event.id,
+		SchemaID: schema.ID,
+		Fields:   sobj,
+	}, nil
+}
+
+// Publish publishes events to topicName, encoded with the latest schema of the topic. Fields missing from an event
+// take their default value; platform events also require CreatedDate and CreatedById. Each event is published or
+// fails on its own; the results are in the order of events, correlated by the IDs generated for the events.
+func (c *Client) Publish(ctx context.Context, topicName string, events []*simpleforce.SObject) ([]*PublishResult, error) {
+	topic, err := c.GetTopic(ctx, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := c.GetSchema(ctx, topic.SchemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &publishRequest{topicName: topicName}
+
+	for _, event := range events {
+		payload, err := schema.Encode(*event)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		req.events = append(req.events, &producerEvent{
+			id:       id,
+			schemaID: schema.ID,
+			payload:  payload,
+		})
+	}
+
+	res := &publishResponse{}
+
+	err = c.invoke(ctx, "Publish", req, res)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*PublishResult, len(res.results))
+	for _, result := range res.results {
+		byKey[result.CorrelationKey] = result
+	}
+
+	results := make([]*PublishResult, 0, len(req.events))
+	for _, event := range req.events {
+		result, ok := byKey[event.id]
+		if !ok {
+			result = &PublishResult{CorrelationKey: event.id, Error: &PublishError{Message: "no result returned"}}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
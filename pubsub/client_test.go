@@ -0,0 +1,301 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const orderPlacedSchema = `{
+	"type": "record",
+	"name": "Order_Placed__e",
+	"namespace": "com.sforce.eventbus",
+	"fields": [
+		{"name": "CreatedDate", "type": "long"},
+		{"name": "CreatedById", "type": "string"},
+		{"name": "Amount__c", "type": ["null", "double"], "default": null}
+	]
+}`
+
+// fakePubSub is an in-process Pub/Sub API serving a single topic, /event/Order_Placed__e.
+type fakePubSub struct {
+	schema *Schema
+
+	mu          sync.Mutex
+	metadata    []metadata.MD
+	schemaCalls int
+	fetches     []*fetchRequest
+	events      []*producerEvent
+}
+
+func (s *fakePubSub) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	s.mu.Lock()
+	s.metadata = append(s.metadata, md)
+	s.mu.Unlock()
+}
+
+func unaryHandler(handle func(s *fakePubSub, ctx context.Context, dec func(interface{}) error) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		s := srv.(*fakePubSub)
+		s.record(ctx)
+		return handle(s, ctx, dec)
+	}
+}
+
+var fakePubSubDesc = grpc.ServiceDesc{
+	ServiceName: "eventbus.v1.PubSub",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTopic",
+			Handler: unaryHandler(func(s *fakePubSub, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+				req := &topicRequest{}
+				err := dec(req)
+				if err != nil {
+					return nil, err
+				}
+
+				return &TopicInfo{TopicName: req.topicName, CanPublish: true, CanSubscribe: true, SchemaID: s.schema.ID}, nil
+			}),
+		},
+		{
+			MethodName: "GetSchema",
+			Handler: unaryHandler(func(s *fakePubSub, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+				req := &schemaRequest{}
+				err := dec(req)
+				if err != nil {
+					return nil, err
+				}
+
+				s.mu.Lock()
+				s.schemaCalls++
+				s.mu.Unlock()
+
+				return &schemaInfo{schemaID: req.schemaID, schemaJSON: s.schema.JSON}, nil
+			}),
+		},
+		{
+			MethodName: "Publish",
+			Handler: unaryHandler(func(s *fakePubSub, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+				req := &publishRequest{}
+				err := dec(req)
+				if err != nil {
+					return nil, err
+				}
+
+				res := &publishResponse{schemaID: s.schema.ID}
+
+				s.mu.Lock()
+				defer s.mu.Unlock()
+
+				for _, event := range req.events {
+					if _, err := s.schema.Decode(event.payload); err != nil {
+						res.results = append(res.results, &PublishResult{CorrelationKey: event.id, Error: &PublishError{Code: 1, Message: err.Error()}})
+						continue
+					}
+
+					s.events = append(s.events, event)
+					res.results = append(res.results, &PublishResult{CorrelationKey: event.id, ReplayID: []byte{byte(len(s.events))}})
+				}
+
+				return res, nil
+			}),
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				s := srv.(*fakePubSub)
+				s.record(stream.Context())
+
+				next := 0
+
+				for {
+					req := &fetchRequest{}
+					err := stream.RecvMsg(req)
+					if err != nil {
+						return nil
+					}
+
+					s.mu.Lock()
+					s.fetches = append(s.fetches, req)
+					if req.replayPreset == ReplayCustom {
+						next = int(req.replayID[0])
+					}
+
+					res := &fetchResponse{}
+					for len(res.events) < int(req.numRequested) && next < len(s.events) {
+						res.events = append(res.events, &consumerEvent{event: *s.events[next], replayID: []byte{byte(next + 1)}})
+						next++
+					}
+					res.pendingNumRequested = req.numRequested - int32(len(res.events))
+					s.mu.Unlock()
+
+					err = stream.SendMsg(res)
+					if err != nil {
+						return err
+					}
+				}
+			},
+		},
+	},
+}
+
+func newTestClient(t *testing.T) (*Client, *fakePubSub) {
+	schema, err := ParseSchema("schema1", orderPlacedSchema)
+	assert.NoError(t, err)
+
+	fake := &fakePubSub{schema: schema}
+
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer(grpc.ForceServerCodec(codec{}))
+	server.RegisterService(&fakePubSubDesc, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	token := (&oauth2.Token{AccessToken: "token"}).WithExtra(map[string]interface{}{
+		"instance_url": "https://example.my.salesforce.com",
+		"id":           "https://login.salesforce.com/id/00Dxx0000001gPLEAY/005xx000001Sv6AAAS",
+	})
+
+	return NewClient(conn, oauth2.StaticTokenSource(token)), fake
+}
+
+func TestClientPublishSubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client, fake := newTestClient(t)
+
+	topic, err := client.GetTopic(ctx, "/event/Order_Placed__e")
+	assert.NoError(err)
+	assert.Equal("schema1", topic.SchemaID)
+	assert.True(topic.CanPublish)
+
+	events := []*simpleforce.SObject{
+		simpleforce.NewSObject("Order_Placed__e").Set("CreatedDate", 1630497600000).Set("CreatedById", "005xx000001Sv6AAAS").Set("Amount__c", 10.5),
+		simpleforce.NewSObject("Order_Placed__e").Set("CreatedDate", 1630497600000).Set("CreatedById", "005xx000001Sv6AAAS"),
+		simpleforce.NewSObject("Order_Placed__e").Set("CreatedDate", 1630497600000).Set("CreatedById", "005xx000001Sv6AAAS").Set("Amount__c", 7),
+	}
+
+	results, err := client.Publish(ctx, "/event/Order_Placed__e", events)
+	assert.NoError(err)
+	assert.Len(results, 3)
+	for i, result := range results {
+		assert.Nil(result.Error)
+		assert.Equal([]byte{byte(i + 1)}, result.ReplayID)
+	}
+
+	_, err = client.Publish(ctx, "/event/Order_Placed__e", []*simpleforce.SObject{simpleforce.NewSObject("Order_Placed__e")})
+	assert.EqualError(err, "avro: encoding Order_Placed__e: field CreatedDate: <nil> is not an integer")
+
+	errStop := errors.New("stop")
+
+	var received []*Event
+	err = client.Subscribe(ctx, "/event/Order_Placed__e", ReplayEarliest, nil, 2, func(ctx context.Context, event *Event) error {
+		received = append(received, event)
+		if len(received) == 3 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(errStop, err)
+
+	assert.Len(received, 3)
+	assert.Equal("Order_Placed__e", received[0].Fields.Type())
+	assert.Equal(10.5, received[0].Fields.Float64Field("Amount__c"))
+	assert.Nil(received[1].Fields.InterfaceField("Amount__c"))
+	assert.Equal("005xx000001Sv6AAAS", received[2].Fields.StringField("CreatedById"))
+	assert.Equal(results[2].CorrelationKey, received[2].ID)
+	assert.Equal([]byte{3}, received[2].ReplayID)
+
+	fake.mu.Lock()
+	assert.Len(fake.fetches, 2)
+	assert.Equal(ReplayEarliest, fake.fetches[0].replayPreset)
+	assert.Equal(int32(2), fake.fetches[1].numRequested)
+	// The schema is fetched once and cached.
+	assert.Equal(1, fake.schemaCalls)
+	md := fake.metadata[0]
+	fake.mu.Unlock()
+
+	assert.Equal([]string{"token"}, md.Get("accesstoken"))
+	assert.Equal([]string{"https://example.my.salesforce.com"}, md.Get("instanceurl"))
+	assert.Equal([]string{"00Dxx0000001gPLEAY"}, md.Get("tenantid"))
+
+	// Subscriptions resume after a replay ID.
+	received = nil
+	err = client.Subscribe(ctx, "/event/Order_Placed__e", ReplayCustom, []byte{2}, 0, func(ctx context.Context, event *Event) error {
+		received = append(received, event)
+		return errStop
+	})
+	assert.Equal(errStop, err)
+	assert.Len(received, 1)
+	assert.Equal(7.0, received[0].Fields.Float64Field("Amount__c"))
+}
+
+func TestClientCredentials(t *testing.T) {
+	client := NewClient(nil, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}))
+
+	_, err := client.GetTopic(context.Background(), "/event/Order_Placed__e")
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+func TestClientSubscribe_changeEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client, fake := newTestClient(t)
+
+	schemaJSON, err := os.ReadFile(filepath.Join("testdata", "account_change_event.avsc"))
+	assert.NoError(err)
+	payload, err := os.ReadFile(filepath.Join("testdata", "account_change_event.avro"))
+	assert.NoError(err)
+
+	fake.schema, err = ParseSchema("schema1", string(schemaJSON))
+	assert.NoError(err)
+	fake.events = []*producerEvent{{id: "6a3b2c1d-0000-4000-8000-000000000001", schemaID: "schema1", payload: payload}}
+
+	errStop := errors.New("stop")
+
+	var received *Event
+	err = client.Subscribe(ctx, "/data/AccountChangeEvent", ReplayEarliest, nil, 1, func(ctx context.Context, event *Event) error {
+		received = event
+		return errStop
+	})
+	assert.Equal(errStop, err)
+
+	assert.Equal("AccountChangeEvent", received.Fields.Type())
+	assert.Equal("Acme Corp", received.Fields.StringField("Name"))
+
+	header := received.Fields.InterfaceField("ChangeEventHeader").(map[string]interface{})
+	assert.Equal("UPDATE", header["changeType"])
+	assert.Equal([]interface{}{"Name", "AnnualRevenue", "LastModifiedDate", "BillingAddress.City"}, header["changedFields"])
+	assert.Equal([]interface{}{"AnnualRevenue"}, header["nulledFields"])
+	assert.Equal([]interface{}{}, header["diffFields"])
+}
@@ -0,0 +1,27 @@
+module github.com/eleanorhealth/simpleforce/pubsub
+
+go 1.17
+
+require (
+	github.com/eleanorhealth/simpleforce v0.0.0
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/oauth2 v0.7.0
+	google.golang.org/grpc v1.56.3
+	google.golang.org/protobuf v1.33.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
+replace github.com/eleanorhealth/simpleforce => ../
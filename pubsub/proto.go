@@ -0,0 +1,512 @@
+package pubsub
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of the eventbus.v1 protocol, encoded by hand so the package doesn't depend on generated code.
+// Ref: https://github.com/developerforce/pub-sub-api/blob/main/pubsub_api.proto
+
+// message is a protocol buffers message of the eventbus.v1 protocol.
+type message interface {
+	marshal() []byte
+	unmarshal(data []byte) error
+}
+
+// codec encodes messages for gRPC. It is named proto, as salesforce only accepts the application/grpc+proto content
+// type.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("pubsub: cannot marshal %T", v)
+	}
+
+	return m.marshal(), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("pubsub: cannot unmarshal %T", v)
+	}
+
+	return m.unmarshal(data)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+// field is a field read from an encoded message. Varint holds the value of varint fields and Bytes the value of
+// length-delimited fields.
+type field struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+// parseFields calls fn with the fields of an encoded message. Fields of other wire types are skipped.
+func parseFields(data []byte, fn func(f field) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		f := field{num: num}
+		known := true
+
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			known = false
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if !known {
+			continue
+		}
+
+		err := fn(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	return appendVarint(b, num, protowire.EncodeBool(v))
+}
+
+func appendMessage(b []byte, num protowire.Number, m message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.marshal())
+}
+
+type topicRequest struct {
+	topicName string
+}
+
+func (m *topicRequest) marshal() []byte {
+	return appendString(nil, 1, m.topicName)
+}
+
+func (m *topicRequest) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		if f.num == 1 {
+			m.topicName = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// TopicInfo describes a topic, such as /event/Order_Placed__e or /data/AccountChangeEvent.
+type TopicInfo struct {
+	TopicName string
+	// TenantGUID is the ID of the org owning the topic.
+	TenantGUID   string
+	CanPublish   bool
+	CanSubscribe bool
+	// SchemaID is the ID of the latest schema of the topic's events.
+	SchemaID string
+	RPCID    string
+}
+
+func (m *TopicInfo) marshal() []byte {
+	b := appendString(nil, 1, m.TopicName)
+	b = appendString(b, 2, m.TenantGUID)
+	b = appendBool(b, 3, m.CanPublish)
+	b = appendBool(b, 4, m.CanSubscribe)
+	b = appendString(b, 5, m.SchemaID)
+	return appendString(b, 6, m.RPCID)
+}
+
+func (m *TopicInfo) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.TopicName = string(f.bytes)
+		case 2:
+			m.TenantGUID = string(f.bytes)
+		case 3:
+			m.CanPublish = protowire.DecodeBool(f.varint)
+		case 4:
+			m.CanSubscribe = protowire.DecodeBool(f.varint)
+		case 5:
+			m.SchemaID = string(f.bytes)
+		case 6:
+			m.RPCID = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type schemaRequest struct {
+	schemaID string
+}
+
+func (m *schemaRequest) marshal() []byte {
+	return appendString(nil, 1, m.schemaID)
+}
+
+func (m *schemaRequest) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		if f.num == 1 {
+			m.schemaID = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type schemaInfo struct {
+	schemaJSON string
+	schemaID   string
+	rpcID      string
+}
+
+func (m *schemaInfo) marshal() []byte {
+	b := appendString(nil, 1, m.schemaJSON)
+	b = appendString(b, 2, m.schemaID)
+	return appendString(b, 3, m.rpcID)
+}
+
+func (m *schemaInfo) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.schemaJSON = string(f.bytes)
+		case 2:
+			m.schemaID = string(f.bytes)
+		case 3:
+			m.rpcID = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// ReplayPreset is where a subscription starts.
+type ReplayPreset int32
+
+const (
+	// ReplayLatest receives only events published after subscribing.
+	ReplayLatest ReplayPreset = 0
+	// ReplayEarliest receives all events retained by salesforce, followed by new events.
+	ReplayEarliest ReplayPreset = 1
+	// ReplayCustom receives the events after a replay ID.
+	ReplayCustom ReplayPreset = 2
+)
+
+type fetchRequest struct {
+	topicName    string
+	replayPreset ReplayPreset
+	replayID     []byte
+	numRequested int32
+	authRefresh  string
+}
+
+func (m *fetchRequest) marshal() []byte {
+	b := appendString(nil, 1, m.topicName)
+	b = appendVarint(b, 2, uint64(m.replayPreset))
+	b = appendBytes(b, 3, m.replayID)
+	b = appendVarint(b, 4, uint64(m.numRequested))
+	return appendString(b, 5, m.authRefresh)
+}
+
+func (m *fetchRequest) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.topicName = string(f.bytes)
+		case 2:
+			m.replayPreset = ReplayPreset(f.varint)
+		case 3:
+			m.replayID = append([]byte(nil), f.bytes...)
+		case 4:
+			m.numRequested = int32(f.varint)
+		case 5:
+			m.authRefresh = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type fetchResponse struct {
+	events              []*consumerEvent
+	latestReplayID      []byte
+	rpcID               string
+	pendingNumRequested int32
+}
+
+func (m *fetchResponse) marshal() []byte {
+	var b []byte
+	for _, event := range m.events {
+		b = appendMessage(b, 1, event)
+	}
+	b = appendBytes(b, 2, m.latestReplayID)
+	b = appendString(b, 3, m.rpcID)
+	return appendVarint(b, 4, uint64(m.pendingNumRequested))
+}
+
+func (m *fetchResponse) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			event := &consumerEvent{}
+			err := event.unmarshal(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.events = append(m.events, event)
+		case 2:
+			m.latestReplayID = append([]byte(nil), f.bytes...)
+		case 3:
+			m.rpcID = string(f.bytes)
+		case 4:
+			m.pendingNumRequested = int32(f.varint)
+		}
+		return nil
+	})
+}
+
+type consumerEvent struct {
+	event    producerEvent
+	replayID []byte
+}
+
+func (m *consumerEvent) marshal() []byte {
+	b := appendMessage(nil, 1, &m.event)
+	return appendBytes(b, 2, m.replayID)
+}
+
+func (m *consumerEvent) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			return m.event.unmarshal(f.bytes)
+		case 2:
+			m.replayID = append([]byte(nil), f.bytes...)
+		}
+		return nil
+	})
+}
+
+type producerEvent struct {
+	id       string
+	schemaID string
+	payload  []byte
+	headers  map[string][]byte
+}
+
+func (m *producerEvent) marshal() []byte {
+	b := appendString(nil, 1, m.id)
+	b = appendString(b, 2, m.schemaID)
+	b = appendBytes(b, 3, m.payload)
+	for key, value := range m.headers {
+		header := appendString(nil, 1, key)
+		header = appendBytes(header, 2, value)
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, header)
+	}
+	return b
+}
+
+func (m *producerEvent) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.id = string(f.bytes)
+		case 2:
+			m.schemaID = string(f.bytes)
+		case 3:
+			m.payload = append([]byte(nil), f.bytes...)
+		case 4:
+			var key string
+			var value []byte
+			err := parseFields(f.bytes, func(f field) error {
+				switch f.num {
+				case 1:
+					key = string(f.bytes)
+				case 2:
+					value = append([]byte(nil), f.bytes...)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if m.headers == nil {
+				m.headers = make(map[string][]byte)
+			}
+			m.headers[key] = value
+		}
+		return nil
+	})
+}
+
+type publishRequest struct {
+	topicName   string
+	events      []*producerEvent
+	authRefresh string
+}
+
+func (m *publishRequest) marshal() []byte {
+	b := appendString(nil, 1, m.topicName)
+	for _, event := range m.events {
+		b = appendMessage(b, 2, event)
+	}
+	return appendString(b, 3, m.authRefresh)
+}
+
+func (m *publishRequest) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.topicName = string(f.bytes)
+		case 2:
+			event := &producerEvent{}
+			err := event.unmarshal(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.events = append(m.events, event)
+		case 3:
+			m.authRefresh = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type publishResponse struct {
+	results  []*PublishResult
+	schemaID string
+	rpcID    string
+}
+
+func (m *publishResponse) marshal() []byte {
+	var b []byte
+	for _, result := range m.results {
+		b = appendMessage(b, 1, result)
+	}
+	b = appendString(b, 2, m.schemaID)
+	return appendString(b, 3, m.rpcID)
+}
+
+func (m *publishResponse) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			result := &PublishResult{}
+			err := result.unmarshal(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.results = append(m.results, result)
+		case 2:
+			m.schemaID = string(f.bytes)
+		case 3:
+			m.rpcID = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// PublishResult holds the result of publishing an event.
+type PublishResult struct {
+	// ReplayID is the replay ID of the published event. It is empty if publishing failed.
+	ReplayID []byte
+	// Error is the reason publishing failed, or nil.
+	Error *PublishError
+	// CorrelationKey is the ID of the event, generated by the client.
+	CorrelationKey string
+}
+
+func (m *PublishResult) marshal() []byte {
+	b := appendBytes(nil, 1, m.ReplayID)
+	if m.Error != nil {
+		b = appendMessage(b, 2, m.Error)
+	}
+	return appendString(b, 3, m.CorrelationKey)
+}
+
+func (m *PublishResult) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.ReplayID = append([]byte(nil), f.bytes...)
+		case 2:
+			m.Error = &PublishError{}
+			return m.Error.unmarshal(f.bytes)
+		case 3:
+			m.CorrelationKey = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// PublishError is the reason an event wasn't published.
+type PublishError struct {
+	Code    int32
+	Message string
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("publish failed with code %d: %s", e.Code, e.Message)
+}
+
+func (m *PublishError) marshal() []byte {
+	b := appendVarint(nil, 1, uint64(m.Code))
+	return appendString(b, 2, m.Message)
+}
+
+func (m *PublishError) unmarshal(data []byte) error {
+	return parseFields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.Code = int32(f.varint)
+		case 2:
+			m.Message = string(f.bytes)
+		}
+		return nil
+	})
+}
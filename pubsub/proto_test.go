@@ -0,0 +1,110 @@
+package pubsub
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden messages are encoded by the official protocol buffers implementation, see testdata/gen.
+func TestMessages_golden(t *testing.T) {
+	payload, err := os.ReadFile(filepath.Join("testdata", "account_change_event.avro"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		file  string
+		msg   message
+		empty message
+	}{
+		{
+			file: "topic_info.pb",
+			msg: &TopicInfo{
+				TopicName:    "/data/AccountChangeEvent",
+				TenantGUID:   "00D000000000001AAA",
+				CanSubscribe: true,
+				SchemaID:     "schema1",
+				RPCID:        "rpc1",
+			},
+			empty: &TopicInfo{},
+		},
+		{
+			file: "schema_info.pb",
+			msg: &schemaInfo{
+				schemaJSON: `{"type":"record","name":"Event__e","fields":[]}`,
+				schemaID:   "schema1",
+				rpcID:      "rpc2",
+			},
+			empty: &schemaInfo{},
+		},
+		{
+			file: "fetch_request.pb",
+			msg: &fetchRequest{
+				topicName:    "/data/AccountChangeEvent",
+				replayPreset: ReplayCustom,
+				replayID:     []byte{0, 0, 0, 0, 0, 0, 0x30, 0x39},
+				numRequested: 100,
+				authRefresh:  "token",
+			},
+			empty: &fetchRequest{},
+		},
+		{
+			file: "fetch_response.pb",
+			msg: &fetchResponse{
+				events: []*consumerEvent{{
+					event: producerEvent{
+						id:       "6a3b2c1d-0000-4000-8000-000000000001",
+						schemaID: "schema1",
+						payload:  payload,
+						headers:  map[string][]byte{"origin": []byte("api")},
+					},
+					replayID: []byte{0, 0, 0, 0, 0, 0, 0x30, 0x3a},
+				}},
+				latestReplayID:      []byte{0, 0, 0, 0, 0, 0, 0x30, 0x3a},
+				rpcID:               "rpc3",
+				pendingNumRequested: 99,
+			},
+			empty: &fetchResponse{},
+		},
+		{
+			file: "publish_request.pb",
+			msg: &publishRequest{
+				topicName: "/event/Order_Placed__e",
+				events: []*producerEvent{{
+					id:       "6a3b2c1d-0000-4000-8000-000000000002",
+					schemaID: "schema2",
+					payload:  []byte{0x02, 0x08, 'A', 'c', 'm', 'e'},
+				}},
+			},
+			empty: &publishRequest{},
+		},
+		{
+			file: "publish_response.pb",
+			msg: &publishResponse{
+				results: []*PublishResult{
+					{ReplayID: []byte{0x01}, CorrelationKey: "6a3b2c1d-0000-4000-8000-000000000002"},
+					{Error: &PublishError{Code: 1, Message: "invalid payload"}, CorrelationKey: "6a3b2c1d-0000-4000-8000-000000000003"},
+				},
+				schemaID: "schema2",
+				rpcID:    "rpc4",
+			},
+			empty: &publishResponse{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert := assert.New(t)
+
+			golden, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			assert.NoError(err)
+
+			assert.Equal(golden, tt.msg.marshal())
+
+			err = tt.empty.unmarshal(golden)
+			assert.NoError(err)
+			assert.Equal(tt.msg, tt.empty)
+		})
+	}
+}
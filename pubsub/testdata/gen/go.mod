@@ -0,0 +1,10 @@
+module github.com/eleanorhealth/simpleforce/pubsub/testdata/gen
+
+go 1.24
+
+require (
+	github.com/linkedin/goavro/v2 v2.12.0
+	google.golang.org/protobuf v1.25.0
+)
+
+require github.com/golang/snappy v0.0.1 // indirect
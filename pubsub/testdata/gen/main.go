@@ -0,0 +1,278 @@
+// Command gen writes the golden payloads the pubsub tests compare the hand written encoders against. Avro payloads
+// are encoded with goavro and protocol buffers messages with the official Go implementation, from a descriptor
+// transcribed from pubsub_api.proto.
+// Ref: https://github.com/developerforce/pub-sub-api/blob/main/pubsub_api.proto
+//
+// Run it from this directory with "go run .".
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/linkedin/goavro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func main() {
+	payload := encodeChangeEvent()
+	write("account_change_event.avro", payload)
+
+	messages := loadMessages()
+
+	write("topic_info.pb", marshal(messages, "TopicInfo", map[string]interface{}{
+		"topic_name":    "/data/AccountChangeEvent",
+		"tenant_guid":   "00D000000000001AAA",
+		"can_publish":   false,
+		"can_subscribe": true,
+		"schema_id":     "schema1",
+		"rpc_id":        "rpc1",
+	}))
+
+	write("schema_info.pb", marshal(messages, "SchemaInfo", map[string]interface{}{
+		"schema_json": `{"type":"record","name":"Event__e","fields":[]}`,
+		"schema_id":   "schema1",
+		"rpc_id":      "rpc2",
+	}))
+
+	write("fetch_request.pb", marshal(messages, "FetchRequest", map[string]interface{}{
+		"topic_name":    "/data/AccountChangeEvent",
+		"replay_preset": protoreflect.EnumNumber(2),
+		"replay_id":     []byte{0, 0, 0, 0, 0, 0, 0x30, 0x39},
+		"num_requested": int32(100),
+		"auth_refresh":  "token",
+	}))
+
+	write("fetch_response.pb", marshal(messages, "FetchResponse", map[string]interface{}{
+		"events": []map[string]interface{}{{
+			"event": map[string]interface{}{
+				"id":        "6a3b2c1d-0000-4000-8000-000000000001",
+				"schema_id": "schema1",
+				"payload":   payload,
+				"headers":   []map[string]interface{}{{"key": "origin", "value": []byte("api")}},
+			},
+			"replay_id": []byte{0, 0, 0, 0, 0, 0, 0x30, 0x3a},
+		}},
+		"latest_replay_id":      []byte{0, 0, 0, 0, 0, 0, 0x30, 0x3a},
+		"rpc_id":                "rpc3",
+		"pending_num_requested": int32(99),
+	}))
+
+	write("publish_request.pb", marshal(messages, "PublishRequest", map[string]interface{}{
+		"topic_name": "/event/Order_Placed__e",
+		"events": []map[string]interface{}{{
+			"id":        "6a3b2c1d-0000-4000-8000-000000000002",
+			"schema_id": "schema2",
+			"payload":   []byte{0x02, 0x08, 'A', 'c', 'm', 'e'},
+		}},
+	}))
+
+	write("publish_response.pb", marshal(messages, "PublishResponse", map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"replay_id": []byte{0x01}, "correlation_key": "6a3b2c1d-0000-4000-8000-000000000002"},
+			{"error": map[string]interface{}{"code": protoreflect.EnumNumber(1), "msg": "invalid payload"}, "correlation_key": "6a3b2c1d-0000-4000-8000-000000000003"},
+		},
+		"schema_id": "schema2",
+		"rpc_id":    "rpc4",
+	}))
+}
+
+// encodeChangeEvent encodes an update of an account's name and billing city that clears its annual revenue.
+func encodeChangeEvent() []byte {
+	schema, err := os.ReadFile("../account_change_event.avsc")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	address := map[string]interface{}{
+		"Street":          nil,
+		"City":            goavro.Union("string", "Boston"),
+		"State":           nil,
+		"PostalCode":      nil,
+		"Country":         nil,
+		"StateCode":       nil,
+		"CountryCode":     nil,
+		"Latitude":        nil,
+		"Longitude":       nil,
+		"GeocodeAccuracy": nil,
+	}
+
+	payload, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"ChangeEventHeader": map[string]interface{}{
+			"entityName":      "Account",
+			"recordIds":       []interface{}{"001000000000001AAA"},
+			"changeType":      "UPDATE",
+			"changeOrigin":    "com/salesforce/api/soap/53.0;client=SfdcInternalAPI/",
+			"transactionKey":  "0004f1c5-3c4a-4a51-9e8c-9a3d2c2e5f10",
+			"sequenceNumber":  1,
+			"commitTimestamp": int64(1630497600000),
+			"commitNumber":    int64(10957366839372),
+			"commitUser":      "005000000000001AAA",
+			"nulledFields":    []interface{}{"0x40"},
+			"diffFields":      []interface{}{},
+			"changedFields":   []interface{}{"0x0842", "4-0x02"},
+		},
+		"Name":              goavro.Union("string", "Acme Corp"),
+		"Type":              nil,
+		"ParentId":          nil,
+		"BillingAddress":    goavro.Union("com.sforce.eventbus.Address", address),
+		"Phone":             nil,
+		"AnnualRevenue":     nil,
+		"NumberOfEmployees": nil,
+		"OwnerId":           nil,
+		"CreatedDate":       nil,
+		"CreatedById":       nil,
+		"LastModifiedDate":  goavro.Union("long", int64(1630497600000)),
+		"LastModifiedById":  goavro.Union("string", "005000000000001AAA"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return payload
+}
+
+func write(name string, data []byte) {
+	err := os.WriteFile("../"+name, data, 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadMessages builds the message descriptors of pubsub_api.proto.
+func loadMessages() protoreflect.FileDescriptor {
+	str := func(name string, num int32) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false)
+	}
+	byt := func(name string, num int32) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_BYTES, "", false)
+	}
+	boolean := func(name string, num int32) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_BOOL, "", false)
+	}
+	int32Field := func(name string, num int32) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_INT32, "", false)
+	}
+	enum := func(name string, num int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_ENUM, typeName, false)
+	}
+	msg := func(name string, num int32, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+		return field(name, num, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, typeName, repeated)
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("pubsub_api.proto"),
+		Package: proto.String("eventbus.v1"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			enumType("ErrorCode", "UNKNOWN", "PUBLISH", "COMMIT"),
+			enumType("ReplayPreset", "LATEST", "EARLIEST", "CUSTOM"),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("TopicInfo", str("topic_name", 1), str("tenant_guid", 2), boolean("can_publish", 3),
+				boolean("can_subscribe", 4), str("schema_id", 5), str("rpc_id", 6)),
+			message("TopicRequest", str("topic_name", 1)),
+			message("EventHeader", str("key", 1), byt("value", 2)),
+			message("ProducerEvent", str("id", 1), str("schema_id", 2), byt("payload", 3),
+				msg("headers", 4, ".eventbus.v1.EventHeader", true)),
+			message("ConsumerEvent", msg("event", 1, ".eventbus.v1.ProducerEvent", false), byt("replay_id", 2)),
+			message("PublishResult", byt("replay_id", 1), msg("error", 2, ".eventbus.v1.Error", false),
+				str("correlation_key", 3)),
+			message("Error", enum("code", 1, ".eventbus.v1.ErrorCode"), str("msg", 2)),
+			message("FetchRequest", str("topic_name", 1), enum("replay_preset", 2, ".eventbus.v1.ReplayPreset"),
+				byt("replay_id", 3), int32Field("num_requested", 4), str("auth_refresh", 5)),
+			message("FetchResponse", msg("events", 1, ".eventbus.v1.ConsumerEvent", true), byt("latest_replay_id", 2),
+				str("rpc_id", 3), int32Field("pending_num_requested", 4)),
+			message("SchemaRequest", str("schema_id", 1)),
+			message("SchemaInfo", str("schema_json", 1), str("schema_id", 2), str("rpc_id", 3)),
+			message("PublishRequest", str("topic_name", 1), msg("events", 2, ".eventbus.v1.ProducerEvent", true),
+				str("auth_refresh", 3)),
+			message("PublishResponse", msg("results", 1, ".eventbus.v1.PublishResult", true), str("schema_id", 2),
+				str("rpc_id", 3)),
+		},
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return fd
+}
+
+func field(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	if repeated {
+		label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	}
+
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(num),
+		Type:     typ.Enum(),
+		Label:    label.Enum(),
+		JsonName: proto.String(name),
+	}
+	if len(typeName) > 0 {
+		f.TypeName = proto.String(typeName)
+	}
+
+	return f
+}
+
+func message(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+}
+
+func enumType(name string, values ...string) *descriptorpb.EnumDescriptorProto {
+	e := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	for i, value := range values {
+		e.Value = append(e.Value, &descriptorpb.EnumValueDescriptorProto{Name: proto.String(value), Number: proto.Int32(int32(i))})
+	}
+
+	return e
+}
+
+// marshal encodes a message given as a map of field names to values, nested messages being maps too.
+func marshal(fd protoreflect.FileDescriptor, name string, values map[string]interface{}) []byte {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(build(fd.Messages().ByName(protoreflect.Name(name)), values))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return data
+}
+
+func build(md protoreflect.MessageDescriptor, values map[string]interface{}) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+
+	for name, value := range values {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			log.Fatalf("unknown field %s.%s", md.Name(), name)
+		}
+
+		switch {
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			for _, item := range value.([]map[string]interface{}) {
+				list.Append(protoreflect.ValueOfMessage(build(fd.Message(), item)))
+			}
+		case fd.Message() != nil:
+			m.Set(fd, protoreflect.ValueOfMessage(build(fd.Message(), value.(map[string]interface{}))))
+		default:
+			m.Set(fd, protoreflect.ValueOf(value))
+		}
+	}
+
+	return m
+}
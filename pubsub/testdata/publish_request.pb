@@ -0,0 +1,3 @@
+
+/event/Order_Placed__e7
+$6a3b2c1d-0000-4000-8000-000000000002schema2Acme
\ No newline at end of file
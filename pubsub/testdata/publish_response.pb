@@ -0,0 +1,4 @@
+
+)
+$6a3b2c1d-0000-4000-8000-000000000002
+;invalid payload$6a3b2c1d-0000-4000-8000-000000000003schema2rpc4
\ No newline at end of file
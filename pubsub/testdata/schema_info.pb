@@ -0,0 +1,2 @@
+
+/{"type":"record","name":"Event__e","fields":[]}schema1rpc2
\ No newline at end of file
@@ -0,0 +1,2 @@
+
+/data/AccountChangeEvent00D000000000001AAA *schema12rpc1
\ No newline at end of file
@@ -0,0 +1,115 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// ObjectField accesses a parent relationship, such as Account or Account.Owner, in the SObject. The path is a dotted
+// list of relationship names. The returned SObject shares its fields with the SObject it was accessed from. Nil is
+// returned if any relationship on the path doesn't exist.
+func (s *SObject) ObjectField(path string) *SObject {
+	obj := s
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := obj.InterfaceField(key).(type) {
+		case map[string]interface{}:
+			related := SObject(v)
+			obj = &related
+		case SObject:
+			obj = &v
+		case *SObject:
+			obj = v
+		default:
+			return nil
+		}
+
+		if obj == nil {
+			return nil
+		}
+	}
+
+	return obj
+}
+
+// PathField accesses a field through parent relationships, such as Account.Owner.Name, in the SObject as raw
+// interface. Nil is returned if the field or any relationship on the path doesn't exist.
+func (s *SObject) PathField(path string) interface{} {
+	obj := s
+
+	i := strings.LastIndex(path, ".")
+	if i >= 0 {
+		obj = s.ObjectField(path[:i])
+		if obj == nil {
+			return nil
+		}
+	}
+
+	return obj.InterfaceField(path[i+1:])
+}
+
+// StringPathField accesses a field through parent relationships, such as Account.Owner.Name, in the SObject as
+// string. Empty string is returned if the field or any relationship on the path doesn't exist.
+func (s *SObject) StringPathField(path string) string {
+	value, _ := s.PathField(path).(string)
+	return value
+}
+
+// ChildRecords accesses the results of a child relationship subquery, such as Contacts in
+// "SELECT Name, (SELECT Name FROM Contacts) FROM Account", in the SObject. The path may go through parent
+// relationships, such as Account.Contacts. Nil is returned if the relationship doesn't exist or has no records.
+// Use FetchChildRecords to retrieve all records of a relationship with more than one page of results.
+func (s *SObject) ChildRecords(path string) (*QueryResult, error) {
+	value := s.PathField(path)
+	if value == nil {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case *QueryResult:
+		return v, nil
+	case map[string]interface{}:
+	default:
+		return nil, fieldTypeError(path, v, "child relationship")
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryResult{}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return nil, fieldTypeError(path, value, "child relationship")
+	}
+
+	for _, record := range result.Records {
+		record.ClearChanges()
+	}
+
+	return result, nil
+}
+
+// FetchChildRecords returns all records of a child relationship subquery in the SObject, following nextRecordsUrl to
+// retrieve the pages salesforce didn't include with the parent record.
+func FetchChildRecords(ctx context.Context, client Client, sobj *SObject, path string) ([]*SObject, error) {
+	result, err := sobj.ChildRecords(path)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	records := result.Records
+
+	for !result.Done && len(result.NextRecordsURL) > 0 {
+		result, err = client.Query(ctx, "", result.NextRecordsURL)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, result.Records...)
+	}
+
+	return records, nil
+}
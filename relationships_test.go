@@ -0,0 +1,98 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const relationshipRecord = `{
+	"attributes": {"type": "Case"},
+	"Id": "500000000000001",
+	"Account": {
+		"attributes": {"type": "Account"},
+		"Name": "Acme",
+		"Owner": {
+			"attributes": {"type": "User"},
+			"Name": "Jane"
+		},
+		"Contacts": {
+			"totalSize": 3,
+			"done": false,
+			"nextRecordsUrl": "/services/data/v43.0/query/01gD0000002HU6KIAW-2000",
+			"records": [
+				{"attributes": {"type": "Contact"}, "Name": "A"},
+				{"attributes": {"type": "Contact"}, "Name": "B"}
+			]
+		}
+	},
+	"Contact": null
+}`
+
+func TestSObject_PathField(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := decodeSObject(t, relationshipRecord)
+
+	assert.Equal("Jane", obj.StringPathField("Account.Owner.Name"))
+	assert.Equal("Acme", obj.StringPathField("Account.Name"))
+	assert.Equal("500000000000001", obj.StringPathField("Id"))
+	assert.Nil(obj.PathField("Contact.Name"))
+	assert.Nil(obj.PathField("Account.Parent.Name"))
+
+	owner := obj.ObjectField("Account.Owner")
+	assert.Equal("User", owner.Type())
+
+	// Related SObjects share their fields.
+	owner.Set("Name", "John")
+	assert.Equal("John", obj.StringPathField("Account.Owner.Name"))
+}
+
+func TestSObject_ChildRecords(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := decodeSObject(t, relationshipRecord)
+
+	res, err := obj.ChildRecords("Account.Contacts")
+	assert.NoError(err)
+	assert.Equal(3, res.TotalSize)
+	assert.Len(res.Records, 2)
+	assert.Equal("Contact", res.Records[0].Type())
+	assert.Equal("B", res.Records[1].StringField("Name"))
+
+	res, err = obj.ChildRecords("Cases")
+	assert.NoError(err)
+	assert.Nil(res)
+
+	_, err = obj.ChildRecords("Account.Name")
+	assert.IsType(ErrInvalidField{}, err)
+}
+
+func TestFetchChildRecords(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(r.Method, http.MethodGet)
+		assert.Equal("/services/data/v43.0/query/01gD0000002HU6KIAW-2000", r.URL.Path)
+
+		err := json.NewEncoder(w).Encode(&QueryResult{
+			TotalSize: 3,
+			Done:      true,
+			Records:   []*SObject{NewSObject("Contact").Set("Name", "C")},
+		})
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	obj := decodeSObject(t, relationshipRecord)
+
+	records, err := FetchChildRecords(context.Background(), client, obj, "Account.Contacts")
+	assert.NoError(err)
+	assert.Len(records, 3)
+	assert.Equal("C", records[2].StringField("Name"))
+}
@@ -0,0 +1,455 @@
+// Package replicate mirrors SObjects of an org into a Sink, such as JSON Lines files or database tables. Each SObject
+// type is fully loaded with a Bulk API query, then kept in sync incrementally.
+package replicate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/eleanorhealth/simpleforce/soql"
+)
+
+// fetchBatchSize is the number of updated records retrieved by a single query, keeping the query URL well below the
+// salesforce limit.
+const fetchBatchSize = 200
+
+// Strategy is how changes are replicated after the initial load.
+type Strategy int
+
+const (
+	// ReplicationAPI lists updated and deleted records with GetUpdated and GetDeleted. Salesforce only retains
+	// changes for 30 days and deletions while the records are in the recycle bin; types not synchronized since are
+	// reloaded.
+	ReplicationAPI Strategy = iota
+	// Polling queries records by SystemModstamp, for types the replication API doesn't support. Deletions aren't
+	// detected until the type is reloaded.
+	Polling
+)
+
+// Object selects an SObject type to replicate.
+type Object struct {
+	Type string
+	// Fields are the replicated fields. If empty, all fields supported by the Bulk API are replicated, including
+	// fields created after the first load. Id and SystemModstamp are always replicated.
+	Fields   []string
+	Strategy Strategy
+}
+
+// ErrUnknownField is returned when a field selected for replication doesn't exist.
+type ErrUnknownField struct {
+	Type  string
+	Field string
+}
+
+func (e ErrUnknownField) Error() string {
+	return fmt.Sprintf("replicate: %s has no field %s", e.Type, e.Field)
+}
+
+// Client reads the replicated SObjects from salesforce. simpleforce.HTTPClient implements Client.
+type Client interface {
+	simpleforce.Client
+	simpleforce.BulkQuerier
+	simpleforce.ReplicationLister
+}
+
+// Replicator mirrors SObject types into a Sink.
+type Replicator struct {
+	client  Client
+	sink    Sink
+	states  StateStore
+	objects []Object
+	now     func() time.Time
+}
+
+// NewReplicator creates a new Replicator mirroring objects into sink and persisting the progress of each type to
+// states.
+func NewReplicator(client Client, sink Sink, states StateStore, objects []Object) *Replicator {
+	return &Replicator{
+		client:  client,
+		sink:    sink,
+		states:  states,
+		objects: objects,
+		now:     time.Now,
+	}
+}
+
+// Run synchronizes the objects every interval until ctx is done or a synchronization fails.
+func (r *Replicator) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := r.Sync(ctx)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync synchronizes each object once, in order. Types without a state, with new fields, whose last load didn't
+// complete or whose changes are no longer retained by salesforce are fully loaded; others replicate the changes since
+// their last synchronization.
+func (r *Replicator) Sync(ctx context.Context) error {
+	for _, obj := range r.objects {
+		err := r.sync(ctx, obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Replicator) sync(ctx context.Context, obj Object) error {
+	fields, err := r.fields(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	err = r.sink.EnsureSchema(ctx, obj.Type, fields)
+	if err != nil {
+		return err
+	}
+
+	state, ok, err := r.states.LoadState(ctx, obj.Type)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !ok, state.Loading, addedFields(state.Fields, fields):
+		return r.load(ctx, obj, fields)
+	case obj.Strategy == Polling:
+		return r.poll(ctx, obj, fields, state)
+	case r.now().Sub(state.SyncedAt) > simpleforce.MaxReplicationWindow:
+		return r.load(ctx, obj, fields)
+	default:
+		return r.replicate(ctx, obj, fields, state)
+	}
+}
+
+// fields returns the described fields of obj selected for replication.
+func (r *Replicator) fields(ctx context.Context, obj Object) ([]simpleforce.FieldMeta, error) {
+	meta, err := r.client.DescribeSObject(ctx, simpleforce.NewSObject(obj.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	described, err := meta.Fields()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]simpleforce.FieldMeta, len(described))
+	for _, field := range described {
+		byName[strings.ToLower(field.Name)] = field
+	}
+
+	names := obj.Fields
+	if len(names) == 0 {
+		for _, field := range described {
+			// Compound and binary fields can't be queried with the Bulk API.
+			switch field.Type {
+			case "address", "location", "base64":
+				continue
+			}
+			names = append(names, field.Name)
+		}
+	}
+
+	names = append([]string{"Id", "SystemModstamp"}, names...)
+
+	var fields []simpleforce.FieldMeta
+	seen := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		field, ok := byName[key]
+		if !ok {
+			return nil, ErrUnknownField{Type: obj.Type, Field: name}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// addedFields reports whether fields has fields missing from the replicated field names.
+func addedFields(replicated []string, fields []simpleforce.FieldMeta) bool {
+	known := make(map[string]bool, len(replicated))
+	for _, name := range replicated {
+		known[name] = true
+	}
+
+	for _, field := range fields {
+		if !known[field.Name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fieldNames(fields []simpleforce.FieldMeta) []string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+
+	return names
+}
+
+// load replaces the records of obj in the sink with the result of a bulk query.
+func (r *Replicator) load(ctx context.Context, obj Object, fields []simpleforce.FieldMeta) error {
+	// Changes made during the load are replicated by the next synchronization.
+	started := r.now().UTC()
+
+	query, err := soql.Select(fieldNames(fields)...).From(obj.Type).Build()
+	if err != nil {
+		return err
+	}
+
+	// The sink holds part of the records until the load completes, so a failed load must be retried rather than
+	// followed by incremental synchronizations, which would persist the missing records as deleted.
+	err = r.states.SaveState(ctx, obj.Type, &State{
+		Fields:  fieldNames(fields),
+		Loading: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.sink.Reset(ctx, obj.Type)
+	if err != nil {
+		return err
+	}
+
+	err = r.client.BulkQuery(ctx, query, func(records []*simpleforce.SObject) error {
+		for _, record := range records {
+			convertBulkValues(record, fields)
+			normalize(record, fields)
+		}
+
+		return r.sink.Upsert(ctx, obj.Type, records)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.sink.Flush(ctx, obj.Type)
+	if err != nil {
+		return err
+	}
+
+	return r.states.SaveState(ctx, obj.Type, &State{
+		Fields:   fieldNames(fields),
+		SyncedAt: started,
+	})
+}
+
+// convertBulkValues converts the string values of bulk query results to the types the REST API returns.
+func convertBulkValues(record *simpleforce.SObject, fields []simpleforce.FieldMeta) {
+	for _, field := range fields {
+		s, ok := (*record)[field.Name].(string)
+		if !ok {
+			continue
+		}
+
+		switch field.Type {
+		case "boolean":
+			if b, err := strconv.ParseBool(s); err == nil {
+				(*record)[field.Name] = b
+			}
+		case "int", "double", "currency", "percent":
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				(*record)[field.Name] = f
+			}
+		}
+	}
+}
+
+// normalize gives the records of bulk and REST queries the same representation: datetimes are formatted as the REST
+// API formats them, and related records, nested in REST results, are flattened to keys such as Account.Name as in
+// bulk results.
+func normalize(record *simpleforce.SObject, fields []simpleforce.FieldMeta) {
+	for key, value := range *record {
+		related, ok := value.(map[string]interface{})
+		if !ok || related["attributes"] == nil {
+			continue
+		}
+
+		delete(*record, key)
+		flattenRelated(*record, key+".", related)
+	}
+
+	for _, field := range fields {
+		if field.Type != "datetime" || (*record)[field.Name] == nil {
+			continue
+		}
+
+		if t, err := record.DateTimeFieldE(field.Name); err == nil {
+			record.SetDateTime(field.Name, t)
+		}
+	}
+}
+
+func flattenRelated(record map[string]interface{}, prefix string, related map[string]interface{}) {
+	for key, value := range related {
+		if key == "attributes" {
+			continue
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok && nested["attributes"] != nil {
+			flattenRelated(record, prefix+key+".", nested)
+			continue
+		}
+
+		record[prefix+key] = value
+	}
+}
+
+// replicate applies the changes listed by the replication API since the last synchronization.
+func (r *Replicator) replicate(ctx context.Context, obj Object, fields []simpleforce.FieldMeta, state *State) error {
+	end := r.now().UTC()
+
+	deleted, err := r.client.GetDeleted(ctx, obj.Type, state.SyncedAt, end)
+	if err != nil {
+		return err
+	}
+
+	// Deletions before the earliest date available are lost, so the sink may hold deleted records.
+	if deleted.EarliestDateAvailable.After(state.SyncedAt) {
+		return r.load(ctx, obj, fields)
+	}
+
+	updated, err := r.client.GetUpdated(ctx, obj.Type, state.SyncedAt, end)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(updated.IDs); start += fetchBatchSize {
+		stop := start + fetchBatchSize
+		if stop > len(updated.IDs) {
+			stop = len(updated.IDs)
+		}
+
+		query, err := soql.Select(fieldNames(fields)...).
+			From(obj.Type).
+			Where(soql.In("Id", updated.IDs[start:stop])).
+			Build()
+		if err != nil {
+			return err
+		}
+
+		var nextRecordsURL string
+		for {
+			result, err := r.client.Query(ctx, query, nextRecordsURL)
+			if err != nil {
+				return err
+			}
+
+			for _, record := range result.Records {
+				normalize(record, fields)
+			}
+
+			err = r.sink.Upsert(ctx, obj.Type, result.Records)
+			if err != nil {
+				return err
+			}
+
+			if result.Done || len(result.NextRecordsURL) == 0 {
+				break
+			}
+			nextRecordsURL = result.NextRecordsURL
+		}
+	}
+
+	if len(deleted.DeletedRecords) > 0 {
+		ids := make([]string, 0, len(deleted.DeletedRecords))
+		for _, record := range deleted.DeletedRecords {
+			ids = append(ids, record.ID)
+		}
+
+		err = r.sink.Delete(ctx, obj.Type, ids)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.sink.Flush(ctx, obj.Type)
+	if err != nil {
+		return err
+	}
+
+	// The next synchronization starts where both lists end.
+	state.Fields = fieldNames(fields)
+	state.SyncedAt = end
+	for _, covered := range []time.Time{updated.LatestDateCovered, deleted.LatestDateCovered} {
+		if !covered.IsZero() && covered.Before(state.SyncedAt) {
+			state.SyncedAt = covered
+		}
+	}
+
+	return r.states.SaveState(ctx, obj.Type, state)
+}
+
+// poll applies the records modified since the last synchronization, using a ChangeWatcher whose checkpoint is kept in
+// the state. The sink is flushed and the state saved once the poll ends, rather than for each page of records, so the
+// files of a JSONLSink are rewritten once per synchronization.
+func (r *Replicator) poll(ctx context.Context, obj Object, fields []simpleforce.FieldMeta, state *State) error {
+	state.Fields = fieldNames(fields)
+	watcher := simpleforce.NewChangeWatcher(r.client, &stateCheckpoints{state: state}, obj.Type, fieldNames(fields), 0)
+
+	pollErr := watcher.Poll(ctx, state.SyncedAt, func(ctx context.Context, record *simpleforce.SObject) error {
+		normalize(record, fields)
+		return r.sink.Upsert(ctx, obj.Type, []*simpleforce.SObject{record})
+	})
+
+	// The checkpoint covers the records handled before a failure, so they are kept.
+	err := r.sink.Flush(ctx, obj.Type)
+	if err != nil {
+		return err
+	}
+
+	err = r.states.SaveState(ctx, obj.Type, state)
+	if err != nil {
+		return err
+	}
+
+	return pollErr
+}
+
+// stateCheckpoints is the CheckpointStore of a polled type, keeping the checkpoint in the state of the type until poll
+// saves it.
+type stateCheckpoints struct {
+	state *State
+}
+
+func (c *stateCheckpoints) LoadCheckpoint(ctx context.Context, typeName string) (*simpleforce.WatchCheckpoint, bool, error) {
+	return c.state.Checkpoint, c.state.Checkpoint != nil, nil
+}
+
+func (c *stateCheckpoints) SaveCheckpoint(ctx context.Context, typeName string, checkpoint *simpleforce.WatchCheckpoint) error {
+	c.state.Checkpoint = checkpoint
+	if checkpoint.SystemModstamp.After(c.state.SyncedAt) {
+		c.state.SyncedAt = checkpoint.SystemModstamp
+	}
+
+	return nil
+}
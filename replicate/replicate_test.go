@@ -0,0 +1,332 @@
+package replicate
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/eleanorhealth/simpleforce/simpleforcetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func describeFields(fields ...map[string]interface{}) *simpleforce.SObjectMeta {
+	raw := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		raw = append(raw, field)
+	}
+
+	return &simpleforce.SObjectMeta{"fields": raw}
+}
+
+func field(name, typeName string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "type": typeName}
+}
+
+func countLines(t *testing.T, path string) int {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+	}
+
+	return lines
+}
+
+func TestReplicator_ReplicationAPI(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	client := simpleforcetest.NewClient()
+	client.SetClock(clock)
+	client.SetDescribe("Account", describeFields(
+		field("Id", "id"),
+		field("SystemModstamp", "datetime"),
+		field("Name", "string"),
+		field("NumberOfEmployees", "int"),
+		field("BillingAddress", "address"),
+	))
+
+	acme := simpleforce.NewSObject("Account").Set("Name", "Acme").Set("NumberOfEmployees", 10)
+	assert.NoError(client.CreateSObject(ctx, acme, nil, false, nil))
+	globex := simpleforce.NewSObject("Account").Set("Name", "Globex")
+	assert.NoError(client.CreateSObject(ctx, globex, nil, false, nil))
+
+	dir := t.TempDir()
+	sink := NewJSONLSink(dir)
+	states := NewMemoryStateStore()
+
+	replicator := NewReplicator(client, sink, states, []Object{{Type: "Account"}})
+	replicator.now = clock
+
+	// The first synchronization loads all records.
+	now = now.Add(time.Minute)
+	assert.NoError(replicator.Sync(ctx))
+
+	records, err := sink.Records("Account")
+	assert.NoError(err)
+	assert.Len(records, 2)
+	assert.Equal(acme.ID(), records[0]["Id"])
+	assert.Equal(10.0, records[0]["NumberOfEmployees"])
+	assert.NotContains(records[0], "BillingAddress")
+	assert.NotContains(records[0], "attributes")
+
+	state, ok, err := states.LoadState(ctx, "Account")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal([]string{"Id", "SystemModstamp", "Name", "NumberOfEmployees"}, state.Fields)
+	assert.True(now.Equal(state.SyncedAt))
+
+	// Later synchronizations apply updates and deletions.
+	now = now.Add(time.Hour)
+	assert.NoError(client.UpdateSObject(ctx, simpleforce.NewSObject("Account").SetID(acme.ID()).Set("Name", "Acme Corp"), nil, nil))
+	assert.NoError(client.DeleteSObject(ctx, globex))
+	initech := simpleforce.NewSObject("Account").Set("Name", "Initech")
+	assert.NoError(client.CreateSObject(ctx, initech, nil, false, nil))
+
+	now = now.Add(time.Minute)
+	assert.NoError(replicator.Sync(ctx))
+
+	records, err = sink.Records("Account")
+	assert.NoError(err)
+	assert.Len(records, 2)
+	assert.Equal("Acme Corp", records[0]["Name"])
+	assert.Equal(initech.ID(), records[1]["Id"])
+	assert.Equal(2, countLines(t, filepath.Join(dir, "Account.jsonl")))
+
+	// New fields are added to the schema and loaded.
+	client.SetDescribe("Account", describeFields(
+		field("Id", "id"),
+		field("SystemModstamp", "datetime"),
+		field("Name", "string"),
+		field("NumberOfEmployees", "int"),
+		field("Industry", "picklist"),
+	))
+	assert.NoError(client.UpdateSObject(ctx, simpleforce.NewSObject("Account").SetID(initech.ID()).Set("Industry", "Technology"), nil, nil))
+
+	now = now.Add(time.Minute)
+	assert.NoError(replicator.Sync(ctx))
+
+	reloaded := NewJSONLSink(dir)
+	records, err = reloaded.Records("Account")
+	assert.NoError(err)
+	assert.Len(records, 2)
+	assert.Equal("Technology", records[1]["Industry"])
+	assert.Contains(records[0], "Industry")
+
+	var names []string
+	assert.NoError(reloaded.EnsureSchema(ctx, "Account", nil))
+	for _, field := range reloaded.tables["Account"].fields {
+		names = append(names, field.Name)
+	}
+	assert.Equal([]string{"Id", "SystemModstamp", "Name", "NumberOfEmployees", "Industry"}, names)
+
+	// Types not synchronized within the replication window are reloaded.
+	now = now.Add(simpleforce.MaxReplicationWindow + time.Hour)
+	assert.NoError(replicator.Sync(ctx))
+
+	state, _, err = states.LoadState(ctx, "Account")
+	assert.NoError(err)
+	assert.True(now.Equal(state.SyncedAt))
+}
+
+func TestReplicator_failedLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	client := simpleforcetest.NewClient()
+	client.SetClock(clock)
+	client.SetDescribe("Account", describeFields(
+		field("Id", "id"),
+		field("SystemModstamp", "datetime"),
+		field("Name", "string"),
+	))
+
+	for _, name := range []string{"Acme", "Globex"} {
+		assert.NoError(client.CreateSObject(ctx, simpleforce.NewSObject("Account").Set("Name", name), nil, false, nil))
+	}
+
+	sink := NewJSONLSink(t.TempDir())
+	states := NewMemoryStateStore()
+
+	replicator := NewReplicator(client, sink, states, []Object{{Type: "Account"}})
+	replicator.now = clock
+
+	now = now.Add(time.Minute)
+	assert.NoError(replicator.Sync(ctx))
+
+	// A reload failing after the sink was reset is retried by the next synchronization, even if what triggered it,
+	// such as a field that is no longer accessible, went away. Replicating changes on top of the partial records
+	// would lose the others.
+	client.SetDescribe("Account", describeFields(
+		field("Id", "id"),
+		field("SystemModstamp", "datetime"),
+		field("Name", "string"),
+		field("Industry", "picklist"),
+	))
+
+	now = now.Add(time.Minute)
+	errBulk := simpleforcetest.APIError(500, "UNKNOWN_EXCEPTION", "bulk job failed")
+	client.InjectError("BulkQuery", errBulk)
+	assert.Equal(errBulk, replicator.Sync(ctx))
+
+	state, _, err := states.LoadState(ctx, "Account")
+	assert.NoError(err)
+	assert.True(state.Loading)
+
+	client.SetDescribe("Account", describeFields(
+		field("Id", "id"),
+		field("SystemModstamp", "datetime"),
+		field("Name", "string"),
+	))
+
+	now = now.Add(time.Minute)
+	assert.NoError(replicator.Sync(ctx))
+
+	records, err := sink.Records("Account")
+	assert.NoError(err)
+	assert.Len(records, 2)
+
+	state, _, err = states.LoadState(ctx, "Account")
+	assert.NoError(err)
+	assert.False(state.Loading)
+	assert.True(now.Equal(state.SyncedAt))
+}
+
+// flushCountingSink counts the flushes of the sink it wraps.
+type flushCountingSink struct {
+	Sink
+	flushes int
+}
+
+func (s *flushCountingSink) Flush(ctx context.Context, typeName string) error {
+	s.flushes++
+	return s.Sink.Flush(ctx, typeName)
+}
+
+func TestReplicator_Polling(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	client := simpleforcetest.NewClient()
+	client.SetClock(clock)
+	client.SetDescribe("Lead", describeFields(
+		field("Id", "id"),
+		field("SystemModstamp", "datetime"),
+		field("LastName", "string"),
+		field("Status", "picklist"),
+	))
+
+	lead := simpleforce.NewSObject("Lead").Set("LastName", "Smith").Set("Status", "Open")
+	assert.NoError(client.CreateSObject(ctx, lead, nil, false, nil))
+	assert.NoError(client.CreateSObject(ctx, simpleforce.NewSObject("Lead").Set("LastName", "Brown"), nil, false, nil))
+
+	sink := NewJSONLSink(t.TempDir())
+	states := NewFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+
+	counting := &flushCountingSink{Sink: sink}
+
+	replicator := NewReplicator(client, counting, states, []Object{{Type: "Lead", Fields: []string{"LastName"}, Strategy: Polling}})
+	replicator.now = clock
+
+	now = now.Add(time.Minute)
+	assert.NoError(replicator.Sync(ctx))
+
+	records, err := sink.Records("Lead")
+	assert.NoError(err)
+	assert.Len(records, 2)
+	assert.NotContains(records[0], "Status")
+
+	now = now.Add(time.Hour)
+	assert.NoError(client.UpdateSObject(ctx, simpleforce.NewSObject("Lead").SetID(lead.ID()).Set("LastName", "Jones"), nil, nil))
+	assert.NoError(client.UpdateSObject(ctx, simpleforce.NewSObject("Lead").SetID(records[1]["Id"].(string)).Set("LastName", "Green"), nil, nil))
+
+	// The sink is flushed once per synchronization, not for each page of changes.
+	client.SetPageSize(1)
+	flushes := counting.flushes
+
+	assert.NoError(replicator.Sync(ctx))
+	assert.Equal(flushes+1, counting.flushes)
+
+	records, err = sink.Records("Lead")
+	assert.NoError(err)
+	assert.Equal("Jones", records[0]["LastName"])
+	assert.Equal("Green", records[1]["LastName"])
+
+	state, ok, err := states.LoadState(ctx, "Lead")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Contains(state.Checkpoint.Handled, lead.ID())
+
+	replicator = NewReplicator(client, sink, states, []Object{{Type: "Lead", Fields: []string{"Rating"}}})
+	assert.Equal(ErrUnknownField{Type: "Lead", Field: "Rating"}, replicator.Sync(ctx))
+}
+
+func TestConvertBulkValues(t *testing.T) {
+	record := simpleforce.NewSObject("Account").
+		Set("IsDeleted", "false").
+		Set("AnnualRevenue", "1500.5").
+		Set("Name", "42").
+		Set("Rating", nil)
+
+	convertBulkValues(record, []simpleforce.FieldMeta{
+		{Name: "IsDeleted", Type: "boolean"},
+		{Name: "AnnualRevenue", Type: "currency"},
+		{Name: "Name", Type: "string"},
+		{Name: "Rating", Type: "double"},
+	})
+
+	assert.Equal(t, false, record.InterfaceField("IsDeleted"))
+	assert.Equal(t, 1500.5, record.InterfaceField("AnnualRevenue"))
+	assert.Equal(t, "42", record.InterfaceField("Name"))
+	assert.Nil(t, record.InterfaceField("Rating"))
+}
+
+func TestNormalize(t *testing.T) {
+	fields := []simpleforce.FieldMeta{
+		{Name: "SystemModstamp", Type: "datetime"},
+		{Name: "LastActivityDate", Type: "datetime"},
+	}
+
+	// Bulk results have flattened relationships and UTC datetimes.
+	bulk := simpleforce.NewSObject("Contact").
+		Set("SystemModstamp", "2021-09-01T12:00:00.000Z").
+		Set("LastActivityDate", nil).
+		Set("Account.Name", "Acme").
+		Set("Account.Owner.Name", "Jane")
+	// REST results have nested relationships and datetimes with an offset.
+	rest := simpleforce.NewSObject("Contact").
+		Set("SystemModstamp", "2021-09-01T14:00:00.000+0200").
+		Set("LastActivityDate", nil).
+		Set("Account", map[string]interface{}{
+			"attributes": map[string]interface{}{"type": "Account"},
+			"Name":       "Acme",
+			"Owner": map[string]interface{}{
+				"attributes": map[string]interface{}{"type": "User"},
+				"Name":       "Jane",
+			},
+		})
+
+	normalize(bulk, fields)
+	normalize(rest, fields)
+
+	assert.Equal(t, "2021-09-01T12:00:00.000+0000", bulk.InterfaceField("SystemModstamp"))
+	assert.Nil(t, bulk.InterfaceField("LastActivityDate"))
+	assert.Equal(t, map[string]interface{}(*bulk), map[string]interface{}(*rest))
+}
@@ -0,0 +1,265 @@
+package replicate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/eleanorhealth/simpleforce/internal/atomicfile"
+)
+
+// Sink stores the replicated records of SObject types, such as files or database tables. Calls for a type are never
+// concurrent.
+type Sink interface {
+	// EnsureSchema is called with the replicated fields of typeName before its records are written, and again when
+	// describe reports new fields. Sinks should add missing fields and keep the ones no longer replicated.
+	EnsureSchema(ctx context.Context, typeName string, fields []simpleforce.FieldMeta) error
+	// Reset removes all records of typeName before it is fully loaded.
+	Reset(ctx context.Context, typeName string) error
+	// Upsert inserts records, or replaces the stored records with the same ID.
+	Upsert(ctx context.Context, typeName string, records []*simpleforce.SObject) error
+	// Delete removes the records with the given IDs. IDs that aren't stored are ignored.
+	Delete(ctx context.Context, typeName string, ids []string) error
+	// Flush makes the changes written to typeName durable. The state of the type is only saved after Flush, so
+	// changes that weren't flushed are replicated again.
+	Flush(ctx context.Context, typeName string) error
+}
+
+// JSONLSink is a Sink writing the records of each SObject type to a JSON Lines file named after the type, such as
+// Account.jsonl, with one JSON object per record sorted by ID. The fields are described in a file such as
+// Account.schema.json. Records are held in memory and the files rewritten on Flush, which suits up to a few million
+// records per type.
+type JSONLSink struct {
+	dir string
+
+	mu     sync.Mutex
+	tables map[string]*jsonlTable
+}
+
+type jsonlTable struct {
+	fields  []simpleforce.FieldMeta
+	records map[string]map[string]interface{}
+	dirty   bool
+}
+
+// NewJSONLSink creates a JSONLSink writing files to dir, which must exist. Files left by a previous JSONLSink are
+// loaded on first use.
+func NewJSONLSink(dir string) *JSONLSink {
+	return &JSONLSink{
+		dir:    dir,
+		tables: make(map[string]*jsonlTable),
+	}
+}
+
+func (s *JSONLSink) EnsureSchema(ctx context.Context, typeName string, fields []simpleforce.FieldMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table, err := s.table(typeName)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(table.fields))
+	for _, field := range table.fields {
+		known[field.Name] = true
+	}
+
+	for _, field := range fields {
+		if !known[field.Name] {
+			table.fields = append(table.fields, field)
+			table.dirty = true
+		}
+	}
+
+	return nil
+}
+
+func (s *JSONLSink) Reset(ctx context.Context, typeName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table, err := s.table(typeName)
+	if err != nil {
+		return err
+	}
+
+	table.records = make(map[string]map[string]interface{})
+	table.dirty = true
+
+	return nil
+}
+
+func (s *JSONLSink) Upsert(ctx context.Context, typeName string, records []*simpleforce.SObject) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table, err := s.table(typeName)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		fields := record.Fields()
+		delete(fields, "attributes")
+		fields["Id"] = record.ID()
+		table.records[record.ID()] = fields
+	}
+	table.dirty = true
+
+	return nil
+}
+
+func (s *JSONLSink) Delete(ctx context.Context, typeName string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table, err := s.table(typeName)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		delete(table.records, simpleforce.ID(id).To18().String())
+	}
+	table.dirty = true
+
+	return nil
+}
+
+func (s *JSONLSink) Flush(ctx context.Context, typeName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table, err := s.table(typeName)
+	if err != nil {
+		return err
+	}
+
+	if !table.dirty {
+		return nil
+	}
+
+	ids := make([]string, 0, len(table.records))
+	for id := range table.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, id := range ids {
+		err = encoder.Encode(table.records[id])
+		if err != nil {
+			return err
+		}
+	}
+
+	schema, err := json.MarshalIndent(table.fields, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = atomicfile.Write(s.schemaPath(typeName), schema)
+	if err != nil {
+		return err
+	}
+
+	err = atomicfile.Write(s.recordsPath(typeName), buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	table.dirty = false
+
+	return nil
+}
+
+// Records returns the records of typeName held by the sink, including the ones not flushed yet.
+func (s *JSONLSink) Records(typeName string) ([]map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table, err := s.table(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]map[string]interface{}, 0, len(table.records))
+	for _, record := range table.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i]["Id"].(string) < records[j]["Id"].(string)
+	})
+
+	return records, nil
+}
+
+func (s *JSONLSink) recordsPath(typeName string) string {
+	return filepath.Join(s.dir, typeName+".jsonl")
+}
+
+func (s *JSONLSink) schemaPath(typeName string) string {
+	return filepath.Join(s.dir, typeName+".schema.json")
+}
+
+// table returns the table of typeName, loading its files on first use. The caller must hold s.mu.
+func (s *JSONLSink) table(typeName string) (*jsonlTable, error) {
+	if table, ok := s.tables[typeName]; ok {
+		return table, nil
+	}
+
+	table := &jsonlTable{records: make(map[string]map[string]interface{})}
+
+	data, err := os.ReadFile(s.schemaPath(typeName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &table.fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(s.recordsPath(typeName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 16*1024*1024)
+
+		for scanner.Scan() {
+			decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+			decoder.UseNumber()
+
+			var record map[string]interface{}
+			err = decoder.Decode(&record)
+			if err != nil {
+				return nil, err
+			}
+
+			id, _ := record["Id"].(string)
+			table.records[id] = record
+		}
+
+		err = scanner.Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.tables[typeName] = table
+
+	return table, nil
+}
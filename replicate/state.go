@@ -0,0 +1,140 @@
+package replicate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/eleanorhealth/simpleforce/internal/atomicfile"
+)
+
+// State is the replication state of an SObject type.
+type State struct {
+	// Fields are the fields replicated by the last load. Fields missing from it trigger a reload.
+	Fields []string `json:"fields"`
+	// SyncedAt is the time changes were replicated up to.
+	SyncedAt time.Time `json:"syncedAt"`
+	// Checkpoint is the position of the Polling strategy.
+	Checkpoint *simpleforce.WatchCheckpoint `json:"checkpoint,omitempty"`
+	// Loading is set while the type is fully loaded. A load that didn't complete leaves it set, so the type is loaded
+	// again.
+	Loading bool `json:"loading,omitempty"`
+}
+
+// StateStore persists the replication state of SObject types. The state is saved after the sink is flushed, so a
+// restarted Replicator never skips changes that didn't reach the sink.
+type StateStore interface {
+	// LoadState returns the stored state of an SObject type. ok is false if none was stored.
+	LoadState(ctx context.Context, typeName string) (state *State, ok bool, err error)
+	SaveState(ctx context.Context, typeName string, state *State) error
+}
+
+// MemoryStateStore is a StateStore keeping states in memory, for tests.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewMemoryStateStore creates a new empty MemoryStateStore.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[string][]byte)}
+}
+
+func (s *MemoryStateStore) LoadState(ctx context.Context, typeName string) (*State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.states[typeName]
+	if !ok {
+		return nil, false, nil
+	}
+
+	state := &State{}
+	err := json.Unmarshal(data, state)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return state, true, nil
+}
+
+func (s *MemoryStateStore) SaveState(ctx context.Context, typeName string, state *State) error {
+	// States are stored encoded, so callers can't modify them after saving.
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[typeName] = data
+	return nil
+}
+
+// FileStateStore is a StateStore keeping the states of all SObject types in a JSON file, replaced atomically on
+// writes.
+type FileStateStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileStateStore creates a FileStateStore persisting states to path.
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+func (s *FileStateStore) LoadState(ctx context.Context, typeName string) (*State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states, err := s.read()
+	if err != nil {
+		return nil, false, err
+	}
+
+	state, ok := states[typeName]
+	return state, ok, nil
+}
+
+func (s *FileStateStore) SaveState(ctx context.Context, typeName string, state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	states[typeName] = state
+
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(s.path, data)
+}
+
+func (s *FileStateStore) read() (map[string]*State, error) {
+	states := make(map[string]*State)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &states)
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
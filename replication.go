@@ -0,0 +1,222 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// MaxReplicationWindow is the longest time window the updated and deleted resources accept. Longer windows are split
+// by GetUpdated and GetDeleted.
+const MaxReplicationWindow = 30 * 24 * time.Hour
+
+// replicationTimeLayout is the format of the start and end parameters of the updated and deleted resources.
+const replicationTimeLayout = "2006-01-02T15:04:05-07:00"
+
+// UpdatedResult holds the IDs of the records created or updated in a time window.
+type UpdatedResult struct {
+	IDs []string
+	// LatestDateCovered is the end of the window covered, which may be earlier than the requested end. Pass it as the
+	// start of the next call to avoid missing records.
+	LatestDateCovered time.Time
+}
+
+// DeletedRecord is a record deleted in a time window.
+type DeletedRecord struct {
+	ID          string
+	DeletedDate time.Time
+}
+
+// DeletedResult holds the records deleted in a time window.
+type DeletedResult struct {
+	DeletedRecords []DeletedRecord
+	// EarliestDateAvailable is the earliest time salesforce still has deletions for. Records deleted before it are no
+	// longer reported, so a replica last synchronized before it must be reloaded.
+	EarliestDateAvailable time.Time
+	LatestDateCovered     time.Time
+}
+
+// ReplicationLister lists the records created, updated or deleted in a time window. HTTPClient implements
+// ReplicationLister.
+type ReplicationLister interface {
+	GetUpdated(ctx context.Context, typeName string, start, end time.Time) (*UpdatedResult, error)
+	GetDeleted(ctx context.Context, typeName string, start, end time.Time) (*DeletedResult, error)
+}
+
+// GetUpdated returns the IDs of the records of typeName created or updated between start and end. Windows longer than
+// MaxReplicationWindow are retrieved in consecutive requests, each starting at the latest date covered by the
+// previous one. Salesforce only retains changes for 30 days, so ErrReplicationWindow is returned if start is earlier
+// than that or isn't before end.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_getupdated.htm
+func (h *HTTPClient) GetUpdated(ctx context.Context, typeName string, start, end time.Time) (*UpdatedResult, error) {
+	result := &UpdatedResult{}
+	seen := make(map[string]bool)
+
+	err := h.replicationWindows(ctx, typeName, "updated", start, end, func(data []byte) error {
+		var page struct {
+			IDs               []string `json:"ids"`
+			LatestDateCovered string   `json:"latestDateCovered"`
+		}
+
+		err := json.Unmarshal(data, &page)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range page.IDs {
+			if !seen[id] {
+				seen[id] = true
+				result.IDs = append(result.IDs, id)
+			}
+		}
+
+		result.LatestDateCovered, err = parseDateTime(page.LatestDateCovered)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetDeleted returns the records of typeName deleted between start and end, with the time of their deletion. Windows
+// longer than MaxReplicationWindow are retrieved in consecutive requests as with GetUpdated, and ErrReplicationWindow
+// is returned if start is more than 30 days ago or isn't before end.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_getdeleted.htm
+func (h *HTTPClient) GetDeleted(ctx context.Context, typeName string, start, end time.Time) (*DeletedResult, error) {
+	result := &DeletedResult{}
+	seen := make(map[string]bool)
+	first := true
+
+	err := h.replicationWindows(ctx, typeName, "deleted", start, end, func(data []byte) error {
+		var page struct {
+			DeletedRecords []struct {
+				ID          string `json:"id"`
+				DeletedDate string `json:"deletedDate"`
+			} `json:"deletedRecords"`
+			EarliestDateAvailable string `json:"earliestDateAvailable"`
+			LatestDateCovered     string `json:"latestDateCovered"`
+		}
+
+		err := json.Unmarshal(data, &page)
+		if err != nil {
+			return err
+		}
+
+		for _, deleted := range page.DeletedRecords {
+			if seen[deleted.ID] {
+				continue
+			}
+			seen[deleted.ID] = true
+
+			deletedDate, err := parseDateTime(deleted.DeletedDate)
+			if err != nil {
+				return err
+			}
+
+			result.DeletedRecords = append(result.DeletedRecords, DeletedRecord{ID: deleted.ID, DeletedDate: deletedDate})
+		}
+
+		if first {
+			first = false
+
+			result.EarliestDateAvailable, err = parseDateTime(page.EarliestDateAvailable)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.LatestDateCovered, err = parseDateTime(page.LatestDateCovered)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// replicationWindows requests the updated or deleted resource of typeName for each window of at most
+// MaxReplicationWindow between start and end, passing the responses to decode in order. Each window starts at the
+// latest date covered by the previous one, so records changed after the end of a partly covered window aren't missed.
+func (h *HTTPClient) replicationWindows(ctx context.Context, typeName, resource string, start, end time.Time, decode func(data []byte) error) error {
+	if len(typeName) == 0 {
+		return ErrInvalidSObject{"Type is empty"}
+	}
+	if !start.Before(end) {
+		return ErrReplicationWindow{start, end, "start must be before end"}
+	}
+	if start.Before(h.now().Add(-MaxReplicationWindow)) {
+		return ErrReplicationWindow{start, end, "start must be within the 30 days salesforce retains changes"}
+	}
+
+	windowStart := start
+	for {
+		windowEnd := windowStart.Add(MaxReplicationWindow)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+
+		params := url.Values{}
+		params.Set("start", windowStart.UTC().Format(replicationTimeLayout))
+		params.Set("end", windowEnd.UTC().Format(replicationTimeLayout))
+
+		reqURL := h.makeURL("sobjects/" + typeName + "/" + resource + "/?" + params.Encode())
+
+		res, err := h.request(ctx, http.MethodGet, reqURL, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		var data json.RawMessage
+		err = json.NewDecoder(res.Body).Decode(&data)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		err = decode(data)
+		if err != nil {
+			return err
+		}
+
+		var page struct {
+			LatestDateCovered string `json:"latestDateCovered"`
+		}
+
+		err = json.Unmarshal(data, &page)
+		if err != nil {
+			return err
+		}
+
+		covered, err := parseDateTime(page.LatestDateCovered)
+		if err != nil {
+			return err
+		}
+
+		// Stop after the last window or once end is covered, and when a window covers none of its time, as there is
+		// nothing to continue from.
+		if windowEnd.Equal(end) || !covered.Before(end) || !covered.After(windowStart) {
+			return nil
+		}
+
+		windowStart = covered
+	}
+}
+
+// parseDateTime parses a datetime returned by salesforce. Empty strings are parsed as the zero time.
+func parseDateTime(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(dateTimeLayout, value)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+
+	return t, nil
+}
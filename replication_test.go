@@ -0,0 +1,106 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClient_GetUpdated(t *testing.T) {
+	assert := assert.New(t)
+
+	var windows [][2]string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/sobjects/Account/updated/", r.URL.Path)
+
+		start, end := r.URL.Query().Get("start"), r.URL.Query().Get("end")
+		windows = append(windows, [2]string{start, end})
+
+		// The first window is only covered up to noon of its last day, where the second window continues. The
+		// boundary record is reported by both windows.
+		ids, covered := `"001000000000001AAA"`, "2021-08-30T12:00:00.000+0000"
+		if len(windows) > 1 {
+			ids = `"001000000000001AAA", "001000000000002AAA"`
+			windowEnd, err := time.Parse(replicationTimeLayout, end)
+			assert.NoError(err)
+			covered = windowEnd.UTC().Format(dateTimeLayout)
+		}
+
+		fmt.Fprintf(w, `{"ids": [%s], "latestDateCovered": "%s"}`, ids, covered)
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	client.now = func() time.Time { return time.Date(2021, 8, 15, 0, 0, 0, 0, time.UTC) }
+
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2021, 9, 30, 0, 0, 0, 0, time.UTC)
+
+	result, err := client.GetUpdated(context.Background(), "Account", start, end)
+	assert.NoError(err)
+	assert.Equal([]string{"001000000000001AAA", "001000000000002AAA"}, result.IDs)
+	assert.True(end.Equal(result.LatestDateCovered))
+
+	assert.Equal([][2]string{
+		{"2021-08-01T00:00:00+00:00", "2021-08-31T00:00:00+00:00"},
+		{"2021-08-30T12:00:00+00:00", "2021-09-29T12:00:00+00:00"},
+		{"2021-09-29T12:00:00+00:00", "2021-09-30T00:00:00+00:00"},
+	}, windows)
+
+	_, err = client.GetUpdated(context.Background(), "", start, end)
+	assert.Equal(ErrInvalidSObject{"Type is empty"}, err)
+
+	// Invalid windows are rejected without a request.
+	_, err = client.GetUpdated(context.Background(), "Account", end, start)
+	assert.EqualError(err, "invalid replication window 2021-09-30T00:00:00Z to 2021-08-01T00:00:00Z: start must be before end")
+
+	_, err = client.GetUpdated(context.Background(), "Account", start.Add(-MaxReplicationWindow), start)
+	assert.Equal(ErrReplicationWindow{start.Add(-MaxReplicationWindow), start, "start must be within the 30 days salesforce retains changes"}, err)
+
+	assert.Len(windows, 3)
+}
+
+func TestHTTPClient_GetDeleted(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/sobjects/Account/deleted/", r.URL.Path)
+		requests++
+
+		_, err := w.Write([]byte(`{
+			"deletedRecords": [{"id": "001000000000003AAA", "deletedDate": "2021-09-02T10:15:00.000+0000"}],
+			"earliestDateAvailable": "2021-08-20T00:00:00.000+0000",
+			"latestDateCovered": "2021-09-03T00:00:00.000+0000"
+		}`))
+		assert.NoError(err)
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	client.now = func() time.Time { return time.Date(2021, 9, 3, 0, 0, 0, 0, time.UTC) }
+
+	result, err := client.GetDeleted(context.Background(), "Account", time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 9, 3, 0, 0, 0, 0, time.UTC))
+	assert.NoError(err)
+	assert.Equal(1, requests)
+	assert.Len(result.DeletedRecords, 1)
+	assert.Equal("001000000000003AAA", result.DeletedRecords[0].ID)
+	assert.True(time.Date(2021, 9, 2, 10, 15, 0, 0, time.UTC).Equal(result.DeletedRecords[0].DeletedDate))
+	assert.True(time.Date(2021, 8, 20, 0, 0, 0, 0, time.UTC).Equal(result.EarliestDateAvailable))
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(`[{"errorCode": "INVALID_REPLICATION_DATE", "message": "Max date range is 30 days"}]`))
+		assert.NoError(err)
+	})
+
+	_, err = client.GetDeleted(context.Background(), "Account", time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 9, 3, 0, 0, 0, 0, time.UTC))
+	assert.Error(err)
+}
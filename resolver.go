@@ -0,0 +1,224 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce/soql"
+	"github.com/pkg/errors"
+)
+
+// referenceBatchSize is the number of IDs fetched by a single query in FetchReferences, keeping the query URL well
+// below the salesforce limit.
+const referenceBatchSize = 200
+
+// DefaultTypeReloadInterval is the minimum time between reloads of the key prefixes triggered by unknown prefixes.
+const DefaultTypeReloadInterval = 5 * time.Minute
+
+// GlobalDescriber lists all available SObjects. Client and DescribeCache implement GlobalDescriber.
+type GlobalDescriber interface {
+	DescribeGlobal(ctx context.Context) (*SObjectMeta, error)
+}
+
+// TypeResolver maps IDs to the SObject type of the record they identify, using the key prefixes reported by
+// DescribeGlobal. It is useful for polymorphic references such as WhatId, WhoId and ParentId.
+type TypeResolver struct {
+	describer      GlobalDescriber
+	reloadInterval time.Duration
+	now            func() time.Time
+
+	mu       sync.RWMutex
+	prefixes map[string]string
+	loadedAt time.Time
+}
+
+// NewTypeResolver creates a new TypeResolver.
+func NewTypeResolver(describer GlobalDescriber) *TypeResolver {
+	return &TypeResolver{
+		describer:      describer,
+		reloadInterval: DefaultTypeReloadInterval,
+		now:            time.Now,
+	}
+}
+
+// SetReloadInterval sets the minimum time between reloads of the key prefixes triggered by unknown prefixes.
+func (r *TypeResolver) SetReloadInterval(interval time.Duration) {
+	r.reloadInterval = interval
+}
+
+// ResolveType returns the SObject type of the record identified by id. The key prefixes are loaded on first use and
+// reloaded if the prefix of id is unknown, to pick up SObjects created since. Unknown prefixes don't reload the key
+// prefixes again until the reload interval has passed. ErrUnknownKeyPrefix is returned if no SObject has the prefix
+// of id.
+func (r *TypeResolver) ResolveType(ctx context.Context, id ID) (string, error) {
+	if !id.Valid() {
+		return "", ErrInvalidID{string(id)}
+	}
+
+	prefix := id.KeyPrefix()
+
+	r.mu.RLock()
+	typeName, ok := r.prefixes[prefix]
+	loaded := r.prefixes != nil && r.now().Sub(r.loadedAt) < r.reloadInterval
+	r.mu.RUnlock()
+
+	if ok {
+		return typeName, nil
+	}
+	if loaded {
+		return "", ErrUnknownKeyPrefix{prefix}
+	}
+
+	err := r.load(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.RLock()
+	typeName, ok = r.prefixes[prefix]
+	r.mu.RUnlock()
+
+	if !ok {
+		return "", ErrUnknownKeyPrefix{prefix}
+	}
+
+	return typeName, nil
+}
+
+// NewSObject creates an SObject of the type of the record identified by id, with its ID set.
+func (r *TypeResolver) NewSObject(ctx context.Context, id ID) (*SObject, error) {
+	typeName, err := r.ResolveType(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSObject(typeName).SetID(id.String()), nil
+}
+
+// load replaces the key prefixes with the ones reported by DescribeGlobal.
+func (r *TypeResolver) load(ctx context.Context) error {
+	meta, err := r.describer.DescribeGlobal(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal((*meta)["sobjects"])
+	if err != nil {
+		return err
+	}
+
+	var sobjects []struct {
+		Name      string `json:"name"`
+		KeyPrefix string `json:"keyPrefix"`
+	}
+
+	err = json.Unmarshal(data, &sobjects)
+	if err != nil {
+		return err
+	}
+
+	prefixes := make(map[string]string, len(sobjects))
+	for _, sobj := range sobjects {
+		if len(sobj.KeyPrefix) > 0 {
+			prefixes[sobj.KeyPrefix] = sobj.Name
+		}
+	}
+
+	r.mu.Lock()
+	r.prefixes = prefixes
+	r.loadedAt = r.now()
+	r.mu.Unlock()
+
+	return nil
+}
+
+// FetchReferences retrieves the records identified by ids, which may be of any SObject type, with one query per type
+// and batch of IDs. The given fields must exist on every referenced type; Id is always retrieved. The records are
+// returned by their 18 character ID. IDs of deleted or inaccessible records are absent from the result. IDs that are
+// invalid or have an unknown key prefix are reported by ErrUnresolvedReferences, which is returned together with the
+// records of the other IDs.
+func FetchReferences(ctx context.Context, client Client, resolver *TypeResolver, ids []ID, fields []string) (map[ID]*SObject, error) {
+	byType := make(map[string][]ID)
+	seen := make(map[ID]bool)
+	unresolved := ErrUnresolvedReferences{}
+
+	for _, id := range ids {
+		id = id.To18()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		typeName, err := resolver.ResolveType(ctx, id)
+		if errors.As(err, &ErrInvalidID{}) || errors.As(err, &ErrUnknownKeyPrefix{}) {
+			unresolved.add(id, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		byType[typeName] = append(byType[typeName], id)
+	}
+
+	selectFields := []string{sobjectIDKey}
+	for _, field := range fields {
+		if !strings.EqualFold(field, sobjectIDKey) {
+			selectFields = append(selectFields, field)
+		}
+	}
+
+	types := make([]string, 0, len(byType))
+	for typeName := range byType {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+
+	records := make(map[ID]*SObject, len(seen))
+
+	for _, typeName := range types {
+		typeIDs := byType[typeName]
+
+		for start := 0; start < len(typeIDs); start += referenceBatchSize {
+			end := start + referenceBatchSize
+			if end > len(typeIDs) {
+				end = len(typeIDs)
+			}
+
+			query, err := soql.Select(selectFields...).
+				From(typeName).
+				Where(soql.In(sobjectIDKey, typeIDs[start:end])).
+				Build()
+			if err != nil {
+				return nil, err
+			}
+
+			var nextRecordsURL string
+			for {
+				result, err := client.Query(ctx, query, nextRecordsURL)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, record := range result.Records {
+					records[ID(record.ID())] = record
+				}
+
+				if result.Done || len(result.NextRecordsURL) == 0 {
+					break
+				}
+				nextRecordsURL = result.NextRecordsURL
+			}
+		}
+	}
+
+	if len(unresolved.Errors) > 0 {
+		return records, unresolved
+	}
+
+	return records, nil
+}
@@ -0,0 +1,118 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const globalDescribe = `{
+	"sobjects": [
+		{"name": "Account", "keyPrefix": "001"},
+		{"name": "Contact", "keyPrefix": "003"},
+		{"name": "AccountFeed", "keyPrefix": null}
+	]
+}`
+
+func TestTypeResolver_ResolveType(t *testing.T) {
+	assert := assert.New(t)
+
+	describes := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(r.URL.Path, "/sobjects")
+		describes++
+
+		_, err := w.Write([]byte(globalDescribe))
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	resolver := NewTypeResolver(client)
+
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	resolver.now = func() time.Time { return now }
+
+	typeName, err := resolver.ResolveType(context.Background(), "0015000000Gv7qJ")
+	assert.NoError(err)
+	assert.Equal("Account", typeName)
+
+	sobj, err := resolver.NewSObject(context.Background(), "0035000000Gv7qJ")
+	assert.NoError(err)
+	assert.Equal("Contact", sobj.Type())
+	assert.Equal("0035000000Gv7qJAAR", sobj.ID())
+
+	assert.Equal(1, describes)
+
+	// Unknown prefixes don't reload the key prefixes within the reload interval.
+	_, err = resolver.ResolveType(context.Background(), "5005000000Gv7qJ")
+	assert.Equal(ErrUnknownKeyPrefix{"500"}, err)
+	assert.Equal(1, describes)
+
+	// Unknown prefixes reload the key prefixes once the reload interval has passed.
+	now = now.Add(DefaultTypeReloadInterval)
+	_, err = resolver.ResolveType(context.Background(), "5005000000Gv7qJ")
+	assert.Equal(ErrUnknownKeyPrefix{"500"}, err)
+	assert.Equal(2, describes)
+
+	_, err = resolver.ResolveType(context.Background(), "5005000000Gv7qJ")
+	assert.Equal(ErrUnknownKeyPrefix{"500"}, err)
+	assert.Equal(2, describes)
+
+	_, err = resolver.ResolveType(context.Background(), "object1")
+	assert.IsType(ErrInvalidID{}, err)
+}
+
+func TestFetchReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	var queries []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/sobjects") {
+			_, err := w.Write([]byte(globalDescribe))
+			assert.NoError(err)
+			return
+		}
+
+		query := r.URL.Query().Get("q")
+		queries = append(queries, query)
+
+		res := &QueryResult{Done: true}
+		if strings.Contains(query, "FROM Account") {
+			res.Records = []*SObject{NewSObject("Account").SetID("0015000000Gv7qJAAR").Set("Name", "Acme")}
+		} else {
+			res.Records = []*SObject{NewSObject("Contact").SetID("0035000000Gv7qJAAR").Set("Name", "Jane")}
+		}
+
+		err := json.NewEncoder(w).Encode(res)
+		assert.NoError(err)
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	resolver := NewTypeResolver(client)
+
+	records, err := FetchReferences(context.Background(), client, resolver,
+		[]ID{"0015000000Gv7qJ", "0035000000Gv7qJAAR", "0015000000Gv7qJAAR", "5005000000Gv7qJ", "object1"}, []string{"Id", "Name"})
+
+	// IDs that can't be resolved are reported without failing the others.
+	assert.Equal(ErrUnresolvedReferences{Errors: map[ID]error{
+		"5005000000Gv7qJAAR": ErrUnknownKeyPrefix{"500"},
+		"object1":            ErrInvalidID{"object1"},
+	}}, err)
+
+	assert.Equal([]string{
+		"SELECT Id, Name FROM Account WHERE Id IN ('0015000000Gv7qJAAR')",
+		"SELECT Id, Name FROM Contact WHERE Id IN ('0035000000Gv7qJAAR')",
+	}, queries)
+
+	assert.Len(records, 2)
+	assert.Equal("Acme", records["0015000000Gv7qJAAR"].StringField("Name"))
+	assert.Equal("Jane", records["0035000000Gv7qJAAR"].StringField("Name"))
+}
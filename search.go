@@ -0,0 +1,109 @@
+package simpleforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// SearchResult holds the response data from a SOSL search.
+type SearchResult struct {
+	Records []*SObject `json:"searchRecords"`
+}
+
+// ByType groups the records of the search result by SObject type.
+func (r *SearchResult) ByType() map[string][]*SObject {
+	groups := make(map[string][]*SObject)
+	for _, record := range r.Records {
+		groups[record.Type()] = append(groups[record.Type()], record)
+	}
+
+	return groups
+}
+
+// SearchSObject configures the records of one SObject type returned by a parameterized search.
+type SearchSObject struct {
+	Name string `json:"name"`
+	// Fields are the fields returned for the SObject. Only IDs are returned if empty.
+	Fields []string `json:"fields,omitempty"`
+	// Where is a SOQL condition the records must match. Build it with the soql package to escape values.
+	Where string `json:"where,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// SearchRequest holds the parameters of a parameterized search.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_search_parameterized.htm
+type SearchRequest struct {
+	// Query is the search term. Escape user input with soql.EscapeSOSL.
+	Query string `json:"q"`
+	// In is the scope of fields searched: ALL, NAME, EMAIL, PHONE or SIDEBAR. All fields are searched if empty.
+	In string `json:"in,omitempty"`
+	// Fields are the fields returned for SObjects without fields of their own.
+	Fields   []string        `json:"fields,omitempty"`
+	SObjects []SearchSObject `json:"sobjects,omitempty"`
+	// OverallLimit is the maximum number of records returned across all SObjects.
+	OverallLimit int `json:"overallLimit,omitempty"`
+	// DefaultLimit is the maximum number of records returned for SObjects without a limit of their own.
+	DefaultLimit int `json:"defaultLimit,omitempty"`
+}
+
+// Searcher runs SOSL searches. HTTPClient implements Searcher.
+type Searcher interface {
+	Search(ctx context.Context, search string) (*SearchResult, error)
+	ParameterizedSearch(ctx context.Context, req *SearchRequest) (*SearchResult, error)
+}
+
+// Search runs a SOSL search, such as one built with soql.Find.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_search.htm
+func (h *HTTPClient) Search(ctx context.Context, search string) (*SearchResult, error) {
+	url := h.makeURL("search/?q=" + url.QueryEscape(search))
+
+	res, err := h.request(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return decodeSearchResult(res)
+}
+
+// ParameterizedSearch runs a search described by req, without writing SOSL.
+func (h *HTTPClient) ParameterizedSearch(ctx context.Context, req *SearchRequest) (*SearchResult, error) {
+	if req == nil || len(req.Query) == 0 {
+		return nil, errors.New("search term is empty")
+	}
+
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := h.makeURL("parameterizedSearch/")
+
+	res, err := h.request(ctx, http.MethodPost, url, bytes.NewReader(reqData), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return decodeSearchResult(res)
+}
+
+func decodeSearchResult(res *http.Response) (*SearchResult, error) {
+	result := &SearchResult{}
+
+	err := json.NewDecoder(res.Body).Decode(result)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range result.Records {
+		record.ClearChanges()
+	}
+
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const searchResponse = `{"searchRecords": [
+	{"attributes": {"type": "Account"}, "Id": "001000000000001AAA", "Name": "Acme"},
+	{"attributes": {"type": "Contact"}, "Id": "003000000000001AAA", "Name": "Wile E. Coyote"},
+	{"attributes": {"type": "Account"}, "Id": "001000000000002AAA", "Name": "Acme Rockets"}
+]}`
+
+func TestHTTPClient_Search(t *testing.T) {
+	assert := assert.New(t)
+
+	search := "FIND {Acme \\& Co} RETURNING Account(Name), Contact(Name)"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodGet, r.Method)
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/search/", r.URL.Path)
+		assert.Equal(search, r.URL.Query().Get("q"))
+
+		w.Write([]byte(searchResponse))
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	result, err := client.Search(context.Background(), search)
+	assert.NoError(err)
+	assert.Len(result.Records, 3)
+	assert.Empty(result.Records[0].ChangedFields())
+
+	groups := result.ByType()
+	assert.Len(groups["Account"], 2)
+	assert.Len(groups["Contact"], 1)
+	assert.Equal("Acme Rockets", groups["Account"][1].StringField("Name"))
+}
+
+func TestHTTPClient_ParameterizedSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("/services/data/"+DefaultAPIVersion+"/parameterizedSearch/", r.URL.Path)
+
+		var body map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		assert.NoError(err)
+		assert.Equal(map[string]interface{}{
+			"q":  "Acme",
+			"in": "NAME",
+			"sobjects": []interface{}{
+				map[string]interface{}{"name": "Account", "fields": []interface{}{"Name"}, "limit": float64(5)},
+			},
+			"overallLimit": float64(10),
+		}, body)
+
+		w.Write([]byte(searchResponse))
+	}))
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	result, err := client.ParameterizedSearch(context.Background(), &SearchRequest{
+		Query:        "Acme",
+		In:           "NAME",
+		SObjects:     []SearchSObject{{Name: "Account", Fields: []string{"Name"}, Limit: 5}},
+		OverallLimit: 10,
+	})
+	assert.NoError(err)
+	assert.Len(result.Records, 3)
+
+	_, err = client.ParameterizedSearch(context.Background(), &SearchRequest{})
+	assert.Error(err)
+}
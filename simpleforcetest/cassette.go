@@ -0,0 +1,446 @@
+package simpleforcetest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/eleanorhealth/simpleforce/soql"
+)
+
+// CassetteMode selects whether a Cassette records or replays interactions.
+type CassetteMode int
+
+const (
+	// Replay serves responses from the cassette file without sending requests.
+	Replay CassetteMode = iota
+	// Record sends requests through the underlying transport and records them.
+	Record
+)
+
+// redacted replaces secrets and redacted field values in cassettes.
+const redacted = "REDACTED"
+
+// digestPrefix starts the recorded form of bodies that can't be redacted, followed by their SHA-256 digest.
+const digestPrefix = "sha256:"
+
+// secretHeaders are headers never written to cassettes.
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Sfdc-Session"}
+
+// secretFields are the body fields of OAuth requests and responses never written to cassettes.
+var secretFields = []string{"access_token", "refresh_token", "id_token", "signature", "client_secret", "client_assertion", "assertion", "password"}
+
+// sobjectResources are the resources of an SObject type that share the path form of external ID requests,
+// sobjects/{type}/{idField}/{idValue}.
+var sobjectResources = map[string]bool{
+	"describe":     true,
+	"updated":      true,
+	"deleted":      true,
+	"listviews":    true,
+	"quickActions": true,
+}
+
+var (
+	soslTermPattern    = regexp.MustCompile(`\{(?:[^\\}]|\\.)*\}`)
+	soslLiteralPattern = regexp.MustCompile(`'(?:[^\\']|\\.)*'`)
+)
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest is a recorded request. The host isn't recorded, so cassettes replay against any instance.
+type CassetteRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response.
+type CassetteResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type cassetteFile struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Cassette is an http.RoundTripper recording salesforce interactions to a file, to be replayed in tests without
+// access to an org. Tokens and the values of redacted fields are replaced in recorded headers and JSON and form
+// bodies, so cassettes of sandboxes holding PHI can be committed. The literals of SOQL queries are replaced with ?,
+// SOSL search terms are redacted, as are the external ID values in paths such as
+// sobjects/Contact/Patient_ID__c/12345. Other bodies, such as bulk results and files, may hold anything: they are
+// recorded as their SHA-256 digest.
+//
+// In Replay mode, requests are matched to recorded interactions by method, path, query parameters and body, after
+// the same redaction. Each interaction is replayed once, in the order recorded. Replaying a response whose body was
+// recorded as a digest fails, rather than returning an empty body code under test would take for an empty result.
+//
+//	cassette, err := simpleforcetest.NewCassette("testdata/contacts.json", simpleforcetest.Replay, nil, []string{"Birthdate"})
+//	client := simpleforce.NewHTTPClient(&http.Client{Transport: cassette}, instanceURL, simpleforce.DefaultAPIVersion)
+type Cassette struct {
+	path         string
+	mode         CassetteMode
+	transport    http.RoundTripper
+	redactFields map[string]bool
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	replayed     []bool
+}
+
+// NewCassette creates a Cassette stored at path. In Record mode, requests are sent through transport, or
+// http.DefaultTransport if nil, and the cassette is written by Save. In Replay mode, the interactions are loaded from
+// path. redactFields are the names of record fields whose values are redacted, matched case-insensitively.
+func NewCassette(path string, mode CassetteMode, transport http.RoundTripper, redactFields []string) (*Cassette, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	c := &Cassette{
+		path:         path,
+		mode:         mode,
+		transport:    transport,
+		redactFields: make(map[string]bool),
+	}
+
+	for _, field := range append(redactFields, secretFields...) {
+		c.redactFields[strings.ToLower(field)] = true
+	}
+
+	if mode == Replay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		file := &cassetteFile{}
+		err = json.Unmarshal(data, file)
+		if err != nil {
+			return nil, err
+		}
+
+		c.interactions = file.Interactions
+		c.replayed = make([]bool, len(file.Interactions))
+	}
+
+	return c, nil
+}
+
+// Interactions returns the recorded or loaded interactions.
+func (c *Cassette) Interactions() []*Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Interaction(nil), c.interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(&cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+// RoundTrip records or replays a request.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recorded := c.request(req, body)
+
+	if c.mode == Replay {
+		return c.replay(req, recorded)
+	}
+
+	// The transport must see the original request, with its body restored.
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+
+	res, err := c.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, &Interaction{
+		Request: recorded,
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Header:     c.header(res.Header),
+			Body:       c.recordedBody(res.Header.Get("Content-Type"), resBody),
+		},
+	})
+	c.mu.Unlock()
+
+	return res, nil
+}
+
+func (c *Cassette) replay(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.interactions {
+		if c.replayed[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		c.replayed[i] = true
+
+		if strings.HasPrefix(interaction.Response.Body, digestPrefix) {
+			return nil, fmt.Errorf("simpleforcetest: the response body of %s %s wasn't recorded, as it isn't JSON or a form", req.Method, req.URL.RequestURI())
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("simpleforcetest: no recorded interaction matches %s %s", req.Method, req.URL.RequestURI())
+}
+
+// matches reports whether a request matches a recorded one.
+func matches(recorded, req CassetteRequest) bool {
+	return recorded.Method == req.Method &&
+		recorded.Path == req.Path &&
+		recorded.Query == req.Query &&
+		recorded.Body == req.Body
+}
+
+// request returns the redacted and normalized form of a request.
+func (c *Cassette) request(req *http.Request, body []byte) CassetteRequest {
+	recorded := CassetteRequest{
+		Method: req.Method,
+		Path:   redactPath(req.URL.Path),
+		Query:  c.query(req.URL.Path, req.URL.Query()),
+		Header: c.header(req.Header),
+	}
+
+	recorded.Body = c.recordedBody(req.Header.Get("Content-Type"), body)
+
+	return recorded
+}
+
+// recordedBody returns the redacted form of a body. Bodies that can't be redacted are recorded as their digest.
+func (c *Cassette) recordedBody(contentType string, body []byte) string {
+	redactedBody, ok := c.body(contentType, body)
+	if !ok {
+		return fmt.Sprintf("%s%x", digestPrefix, sha256.Sum256(body))
+	}
+
+	return redactedBody
+}
+
+// redactPath redacts the external ID value of sobjects/{type}/{idField}/{idValue} paths and anything following it.
+func redactPath(path string) string {
+	segments := strings.Split(path, "/")
+
+	for i := 0; i+3 < len(segments); i++ {
+		if segments[i] != "sobjects" {
+			continue
+		}
+
+		idField := segments[i+2]
+		if sobjectResources[idField] || simpleforce.ID(idField).Valid() {
+			break
+		}
+
+		for j := i + 3; j < len(segments); j++ {
+			if len(segments[j]) > 0 {
+				segments[j] = redacted
+			}
+		}
+		break
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// query normalizes query parameters by sorting them by name, redacting secrets and the literals of SOQL queries and
+// SOSL searches.
+func (c *Cassette) query(path string, values url.Values) string {
+	for key, vals := range values {
+		switch {
+		case c.redactFields[strings.ToLower(key)]:
+			values[key] = []string{redacted}
+
+		case key == "q" && strings.HasSuffix(strings.TrimSuffix(path, "/"), "/search"):
+			for i, val := range vals {
+				vals[i] = redactSOSL(val)
+			}
+
+		case key == "q" || key == "explain":
+			for i, val := range vals {
+				vals[i] = redactSOQL(val)
+			}
+		}
+	}
+
+	return values.Encode()
+}
+
+// redactSOQL replaces the literals of a SOQL query with ?. Queries that can't be parsed are redacted entirely.
+func redactSOQL(query string) string {
+	stmt, err := soql.Parse(query)
+	if err != nil {
+		return redacted
+	}
+
+	redactStatement(stmt)
+
+	return stmt.String()
+}
+
+func redactStatement(stmt *soql.Statement) {
+	for _, item := range stmt.Select {
+		if item.Subquery != nil {
+			redactStatement(item.Subquery)
+		}
+	}
+
+	redactExpr(stmt.Where)
+	redactExpr(stmt.Having)
+}
+
+func redactExpr(expr soql.Expr) {
+	switch expr := expr.(type) {
+	case *soql.LogicalExpr:
+		for _, operand := range expr.Operands {
+			redactExpr(operand)
+		}
+	case *soql.NotExpr:
+		redactExpr(expr.Operand)
+	case *soql.Comparison:
+		redactValue(&expr.Value)
+	}
+}
+
+func redactValue(v *soql.Value) {
+	switch v.Kind {
+	case soql.ListValue:
+		for i := range v.List {
+			redactValue(&v.List[i])
+		}
+	case soql.SubqueryValue:
+		redactStatement(v.Subquery)
+	default:
+		v.Text = "?"
+	}
+}
+
+// redactSOSL redacts the search term and the string literals of a SOSL search.
+func redactSOSL(search string) string {
+	search = soslTermPattern.ReplaceAllLiteralString(search, "{"+redacted+"}")
+	return soslLiteralPattern.ReplaceAllLiteralString(search, "'?'")
+}
+
+// header returns a copy of header with secrets redacted.
+func (c *Cassette) header(header http.Header) http.Header {
+	header = header.Clone()
+
+	for _, key := range secretHeaders {
+		if len(header.Values(key)) > 0 {
+			header.Set(key, redacted)
+		}
+	}
+
+	return header
+}
+
+// body redacts the values of redacted fields in JSON and form bodies. JSON bodies are normalized, so bodies
+// differing only in formatting or key order are equal. false is returned for other bodies, which can't be redacted.
+func (c *Cassette) body(contentType string, body []byte) (string, bool) {
+	if len(body) == 0 {
+		return "", true
+	}
+
+	var v interface{}
+	if json.Unmarshal(body, &v) == nil {
+		normalized, err := json.Marshal(c.redact(v))
+		if err == nil {
+			return string(normalized), true
+		}
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err == nil {
+			return c.query("", values), true
+		}
+	}
+
+	return "", false
+}
+
+// redact replaces the values of redacted fields in a decoded JSON value, as well as the SOQL query of bulk query jobs
+// and the search term of parameterized searches.
+func (c *Cassette) redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if c.redactFields[strings.ToLower(key)] && value != nil {
+				v[key] = redacted
+				continue
+			}
+
+			if str, ok := value.(string); ok && key == "query" {
+				v[key] = redactSOQL(str)
+				continue
+			}
+			if _, ok := value.(string); ok && key == "q" {
+				v[key] = redacted
+				continue
+			}
+
+			v[key] = c.redact(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = c.redact(value)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,187 @@
+package simpleforcetest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/stretchr/testify/assert"
+)
+
+// authTransport adds a bearer token to requests, as an oauth2.Transport using the cassette as its base does.
+type authTransport struct {
+	base http.RoundTripper
+}
+
+func (t authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer secret-token")
+	return t.base.RoundTrip(req)
+}
+
+func TestCassette(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	server := NewServer(nil)
+
+	recorder, err := NewCassette(path, Record, nil, []string{"Birthdate"})
+	assert.NoError(err)
+
+	client := simpleforce.NewHTTPClient(&http.Client{Transport: authTransport{recorder}}, server.URL, simpleforce.DefaultAPIVersion)
+
+	contact := simpleforce.NewSObject("Contact").Set("LastName", "Smith").Set("Birthdate", "1980-01-02")
+	assert.NoError(client.CreateSObject(ctx, contact, nil, false, nil))
+
+	result, err := client.Query(ctx, "SELECT LastName, Birthdate FROM Contact WHERE LastName = 'Smith'", "")
+	assert.NoError(err)
+	assert.Equal("1980-01-02", result.Records[0].StringField("Birthdate"))
+
+	assert.NoError(recorder.Save())
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.NotContains(string(data), "1980-01-02")
+	assert.NotContains(string(data), "secret-token")
+
+	player, err := NewCassette(path, Replay, nil, []string{"Birthdate"})
+	assert.NoError(err)
+	assert.Len(player.Interactions(), 2)
+
+	client = simpleforce.NewHTTPClient(&http.Client{Transport: authTransport{player}}, "https://example.my.salesforce.com", simpleforce.DefaultAPIVersion)
+
+	// Redacted values don't affect matching.
+	replayed := simpleforce.NewSObject("Contact").Set("Birthdate", "1990-03-04").Set("LastName", "Smith")
+	assert.NoError(client.CreateSObject(ctx, replayed, nil, false, nil))
+	assert.Equal(contact.ID(), replayed.ID())
+
+	result, err = client.Query(ctx, "SELECT LastName, Birthdate FROM Contact WHERE LastName = 'Smith'", "")
+	assert.NoError(err)
+	assert.Equal(1, result.TotalSize)
+	assert.Equal(redacted, result.Records[0].StringField("Birthdate"))
+
+	// Each interaction is replayed once.
+	_, err = client.Query(ctx, "SELECT LastName, Birthdate FROM Contact WHERE LastName = 'Smith'", "")
+	assert.Error(err)
+}
+
+func TestCassette_redaction(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	server := NewServer(nil)
+	defer server.Close()
+	server.Store().SetExternalIDFields("Contact", "Patient_ID__c")
+
+	recorder, err := NewCassette(path, Record, nil, []string{"Birthdate", "LastName", "Patient_ID__c"})
+	assert.NoError(err)
+
+	client := simpleforce.NewHTTPClient(&http.Client{Transport: recorder}, server.URL, simpleforce.DefaultAPIVersion)
+
+	contact := simpleforce.NewSObject("Contact").Set("LastName", "Zebulon").Set("Patient_ID__c", "P-98765")
+	assert.NoError(client.CreateSObject(ctx, contact, nil, false, nil))
+
+	result, err := client.Query(ctx, "SELECT Id FROM Contact WHERE LastName = 'Zebulon' AND CreatedDate > 2020-01-02T00:00:00Z", "")
+	assert.NoError(err)
+	assert.Len(result.Records, 1)
+
+	fetched := simpleforce.NewSObject("Contact")
+	assert.NoError(client.GetSObjectByExternalID(ctx, fetched, "Patient_ID__c", "P-98765"))
+
+	assert.NoError(recorder.Save())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.NotContains(string(data), "Zebulon")
+	assert.NotContains(string(data), "2020-01-02")
+	assert.NotContains(string(data), "P-98765")
+
+	interactions := recorder.Interactions()
+	assert.Equal("q=SELECT+Id+FROM+Contact+WHERE+LastName+%3D+%27%3F%27+AND+CreatedDate+%3E+%3F", interactions[1].Request.Query)
+	assert.Equal("/services/data/"+simpleforce.DefaultAPIVersion+"/sobjects/Contact/Patient_ID__c/REDACTED", interactions[2].Request.Path)
+
+	player, err := NewCassette(path, Replay, nil, []string{"Birthdate", "LastName", "Patient_ID__c"})
+	assert.NoError(err)
+
+	client = simpleforce.NewHTTPClient(&http.Client{Transport: player}, "https://example.my.salesforce.com", simpleforce.DefaultAPIVersion)
+
+	assert.NoError(client.CreateSObject(ctx, simpleforce.NewSObject("Contact").Set("LastName", "Other").Set("Patient_ID__c", "P-1"), nil, false, nil))
+
+	// Literals don't affect matching.
+	result, err = client.Query(ctx, "SELECT Id FROM Contact WHERE LastName = 'Other' AND CreatedDate > 2021-03-04T00:00:00Z", "")
+	assert.NoError(err)
+	assert.Equal(contact.ID(), result.Records[0].ID())
+
+	fetched = simpleforce.NewSObject("Contact")
+	assert.NoError(client.GetSObjectByExternalID(ctx, fetched, "Patient_ID__c", "P-1"))
+	assert.Equal(contact.ID(), fetched.ID())
+}
+
+func TestRedactPath(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("/services/data/v43.0/sobjects/Contact/Email/REDACTED", redactPath("/services/data/v43.0/sobjects/Contact/Email/jane@example.com"))
+	assert.Equal("/services/data/v43.0/sobjects/Contact/0035000000Gv7qJAAR/Account", redactPath("/services/data/v43.0/sobjects/Contact/0035000000Gv7qJAAR/Account"))
+	assert.Equal("/services/data/v43.0/sobjects/Contact/describe/layouts", redactPath("/services/data/v43.0/sobjects/Contact/describe/layouts"))
+	assert.Equal("/services/data/v43.0/sobjects/Contact/", redactPath("/services/data/v43.0/sobjects/Contact/"))
+}
+
+func TestCassette_bodies(t *testing.T) {
+	assert := assert.New(t)
+
+	cassette, err := NewCassette(filepath.Join(t.TempDir(), "cassette.json"), Record, nil, nil)
+	assert.NoError(err)
+
+	assert.Equal("FIND {REDACTED} IN ALL FIELDS RETURNING Contact(Id WHERE Name = '?')",
+		redactSOSL(`FIND {Zebulon\}} IN ALL FIELDS RETURNING Contact(Id WHERE Name = 'O\'Zeb')`))
+
+	// Bodies that can't be redacted are recorded as their digest.
+	csv := []byte("Id,LastName\n0035000000Gv7qJAAR,Zebulon\n")
+	assert.Regexp(`^sha256:[0-9a-f]{64}$`, cassette.recordedBody("text/csv", csv))
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.my.salesforce.com/services/data/v43.0/jobs/ingest/1/batches", nil)
+	assert.NoError(err)
+	req.Header.Set("Content-Type", "text/csv")
+	assert.Regexp(`^sha256:[0-9a-f]{64}$`, cassette.request(req, csv).Body)
+}
+
+func TestCassette_unrecordedBody(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	server := NewServer(nil)
+	defer server.Close()
+
+	version := simpleforce.NewSObject("ContentVersion").Set("VersionData", "Zebulon")
+	assert.NoError(server.Store().CreateSObject(ctx, version, nil, false, nil))
+
+	recorder, err := NewCassette(path, Record, nil, nil)
+	assert.NoError(err)
+
+	client := simpleforce.NewHTTPClient(&http.Client{Transport: recorder}, server.URL, simpleforce.DefaultAPIVersion)
+	assert.NoError(client.DownloadFile(ctx, version.ID(), filepath.Join(t.TempDir(), "file")))
+	assert.NoError(recorder.Save())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.NotContains(string(data), "Zebulon")
+
+	player, err := NewCassette(path, Replay, nil, nil)
+	assert.NoError(err)
+
+	// The file wasn't recorded, so replaying it fails rather than downloading an empty file.
+	client = simpleforce.NewHTTPClient(&http.Client{Transport: player}, "https://example.my.salesforce.com", simpleforce.DefaultAPIVersion)
+	err = client.DownloadFile(ctx, version.ID(), filepath.Join(t.TempDir(), "file"))
+	assert.Error(err)
+	assert.Contains(err.Error(), "wasn't recorded")
+}
@@ -0,0 +1,591 @@
+// Package simpleforcetest provides an in-memory implementation of simpleforce.Client for unit tests.
+//
+//	client := simpleforcetest.NewClient()
+//	client.SetExternalIDFields("Account", "External_ID__c")
+//
+//	err := client.CreateSObject(ctx, simpleforce.NewSObject("Account").Set("Name", "Acme"), nil, false, nil)
+//
+//	result, err := client.Query(ctx, "SELECT Id, Name FROM Account WHERE Name LIKE 'Ac%' ORDER BY Name", "")
+//
+// Records are stored by type and ID, with system fields such as CreatedDate and LastModifiedDate maintained by the
+// Client. Queries support a subset of SOQL, see Client.Query.
+package simpleforcetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+)
+
+const (
+	// DefaultPageSize is the number of records returned per page of query results, as with salesforce.
+	DefaultPageSize = 2000
+
+	apiVersion = simpleforce.DefaultAPIVersion
+)
+
+// Client is an in-memory simpleforce.Client. The zero value is not usable; create Clients with NewClient.
+type Client struct {
+	mu sync.Mutex
+
+	records     map[string]map[string]*record
+	prefixes    map[string]string
+	counters    map[string]int
+	externalIDs map[string]map[string]bool
+	describes   map[string]*simpleforce.SObjectMeta
+	errors      map[string][]error
+	cursors     map[string]*cursor
+	locators    int
+	deleted     map[string]map[string]time.Time
+	pageSize    int
+	userID      string
+	now         func() time.Time
+}
+
+var (
+	_ simpleforce.Client               = (*Client)(nil)
+	_ simpleforce.BulkQuerier          = (*Client)(nil)
+	_ simpleforce.ReplicationLister    = (*Client)(nil)
+	_ simpleforce.ConditionalDescriber = (*Client)(nil)
+	_ simpleforce.ExternalIDClient     = (*Client)(nil)
+	_ simpleforce.ConditionalGetter    = (*Client)(nil)
+	_ simpleforce.ConditionalUpdater   = (*Client)(nil)
+	_ simpleforce.Searcher             = (*Client)(nil)
+	_ simpleforce.Explainer            = (*Client)(nil)
+	_ simpleforce.EventPublisher       = (*Client)(nil)
+)
+
+// NewClient creates a new empty Client.
+func NewClient() *Client {
+	c := &Client{
+		records:     make(map[string]map[string]*record),
+		prefixes:    make(map[string]string),
+		counters:    make(map[string]int),
+		externalIDs: make(map[string]map[string]bool),
+		describes:   make(map[string]*simpleforce.SObjectMeta),
+		errors:      make(map[string][]error),
+		cursors:     make(map[string]*cursor),
+		deleted:     make(map[string]map[string]time.Time),
+		pageSize:    DefaultPageSize,
+		now:         time.Now,
+	}
+
+	c.userID = c.newID("User")
+
+	return c
+}
+
+// SetPageSize sets the number of records returned per page of query results.
+func (c *Client) SetPageSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pageSize = size
+}
+
+// SetClock replaces the clock used for system fields such as LastModifiedDate.
+func (c *Client) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// SetExternalIDFields declares fields of an SObject type as external ID fields, which can be used to get, upsert and
+// delete records. As with salesforce, other fields are rejected.
+func (c *Client) SetExternalIDFields(typeName string, fields ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.externalIDs[typeName] == nil {
+		c.externalIDs[typeName] = make(map[string]bool)
+	}
+
+	for _, field := range fields {
+		c.externalIDs[typeName][strings.ToLower(field)] = true
+	}
+}
+
+// SetDescribe sets the metadata returned by DescribeSObject for an SObject type. SObject types without metadata
+// can't be described.
+func (c *Client) SetDescribe(typeName string, meta *simpleforce.SObjectMeta) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.describes[typeName] = meta
+}
+
+// InjectError makes the next call of the named Client method, such as "CreateSObject", fail with err. Errors
+// injected for the same method are returned by consecutive calls. Use APIError to create the errors salesforce
+// returns.
+func (c *Client) InjectError(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errors[method] = append(c.errors[method], err)
+}
+
+// Records returns copies of the stored records of an SObject type, sorted by ID.
+func (c *Client) Records(typeName string) []*simpleforce.SObject {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var sobjs []*simpleforce.SObject
+	for _, rec := range c.sorted(typeName) {
+		sobjs = append(sobjs, rec.sobject())
+	}
+
+	return sobjs
+}
+
+// injected pops the next error injected for method.
+func (c *Client) injected(method string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := c.errors[method]
+	if len(errs) == 0 {
+		return nil
+	}
+
+	c.errors[method] = errs[1:]
+
+	return errs[0]
+}
+
+// writableFields returns the fields of sobj written by a create or update. If the SObject is tracking changes, only
+// the changed fields of an update are written.
+func writableFields(sobj *simpleforce.SObject, blacklistedFields []string, update bool) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	if changed := sobj.ChangedFields(); update && changed != nil {
+		for _, key := range changed {
+			fields[key] = (*sobj)[key]
+		}
+	} else {
+		data, _ := json.Marshal(sobj)
+		_ = json.Unmarshal(data, &fields)
+		delete(fields, "attributes")
+	}
+
+	for _, key := range blacklistedFields {
+		delete(fields, key)
+	}
+
+	return fields
+}
+
+// replace replaces the fields of sobj with the fields of fetched and starts tracking changes.
+func replace(sobj, fetched *simpleforce.SObject) {
+	for key := range *sobj {
+		delete(*sobj, key)
+	}
+
+	for key, value := range *fetched {
+		(*sobj)[key] = value
+	}
+
+	sobj.ClearChanges()
+}
+
+// Query runs a query against the stored records. The supported subset of SOQL is: SELECT of fields, parent
+// relationship fields such as Account.Name and COUNT(); FROM; WHERE with AND, OR, NOT and the comparison operators
+// on literal values, except semi-joins and relative date literals; ORDER BY; LIMIT and OFFSET. Parent relationships
+// are resolved by naming convention: Account through AccountId and Parent__r through Parent__c. Results are paginated
+// by the page size.
+func (c *Client) Query(ctx context.Context, query, nextRecordsURL string) (*simpleforce.QueryResult, error) {
+	if err := c.injected("Query"); err != nil {
+		return nil, err
+	}
+
+	result, err := c.queryPage(query, nextRecordsURL)
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	return result, nil
+}
+
+// BulkQuery runs a query against the stored records and passes each page of results to handle. Unlike salesforce,
+// field values keep their types rather than being converted to strings.
+func (c *Client) BulkQuery(ctx context.Context, query string, handle func(records []*simpleforce.SObject) error) error {
+	if err := c.injected("BulkQuery"); err != nil {
+		return err
+	}
+
+	var nextRecordsURL string
+	for {
+		result, err := c.queryPage(query, nextRecordsURL)
+		if err != nil {
+			return clientError(err)
+		}
+
+		err = handle(result.Records)
+		if err != nil {
+			return err
+		}
+
+		if result.Done {
+			return nil
+		}
+		nextRecordsURL = result.NextRecordsURL
+	}
+}
+
+// Explain isn't supported by the Client, unless an error is injected.
+func (c *Client) Explain(ctx context.Context, query string) (*simpleforce.ExplainResult, error) {
+	return nil, c.unsupported("Explain")
+}
+
+// Search isn't supported by the Client, unless an error is injected. Use ParameterizedSearch instead.
+func (c *Client) Search(ctx context.Context, search string) (*simpleforce.SearchResult, error) {
+	return nil, c.unsupported("Search")
+}
+
+func (c *Client) unsupported(method string) error {
+	if err := c.injected(method); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("simpleforcetest: %s is not supported", method)
+}
+
+// ParameterizedSearch returns the records of the requested SObject types with a string field containing the search
+// term, ignoring case. Search operators and wildcards aren't supported.
+func (c *Client) ParameterizedSearch(ctx context.Context, req *simpleforce.SearchRequest) (*simpleforce.SearchResult, error) {
+	if err := c.injected("ParameterizedSearch"); err != nil {
+		return nil, err
+	}
+
+	result, err := c.search(req)
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	return result, nil
+}
+
+// DescribeSObject returns the metadata set with SetDescribe.
+func (c *Client) DescribeSObject(ctx context.Context, sobj *simpleforce.SObject) (*simpleforce.SObjectMeta, error) {
+	if err := c.injected("DescribeSObject"); err != nil {
+		return nil, err
+	}
+
+	meta, err := c.describe(sobj.Type())
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	return meta, nil
+}
+
+// DescribeSObjectIfModified returns the metadata set with SetDescribe. The metadata never changes.
+func (c *Client) DescribeSObjectIfModified(ctx context.Context, sobj *simpleforce.SObject, since time.Time) (*simpleforce.DescribeResult, error) {
+	if err := c.injected("DescribeSObjectIfModified"); err != nil {
+		return nil, err
+	}
+
+	meta, err := c.describe(sobj.Type())
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	if !since.IsZero() {
+		return &simpleforce.DescribeResult{NotModified: true}, nil
+	}
+
+	return &simpleforce.DescribeResult{Meta: meta}, nil
+}
+
+// DescribeGlobal lists the SObject types with records or metadata, with their key prefixes.
+func (c *Client) DescribeGlobal(ctx context.Context) (*simpleforce.SObjectMeta, error) {
+	if err := c.injected("DescribeGlobal"); err != nil {
+		return nil, err
+	}
+
+	return c.describeGlobal(), nil
+}
+
+// DescribeGlobalIfModified lists the SObject types with records or metadata, with their key prefixes. The list is
+// always returned.
+func (c *Client) DescribeGlobalIfModified(ctx context.Context, since time.Time) (*simpleforce.DescribeResult, error) {
+	if err := c.injected("DescribeGlobalIfModified"); err != nil {
+		return nil, err
+	}
+
+	return &simpleforce.DescribeResult{Meta: c.describeGlobal()}, nil
+}
+
+// CreateSObject stores a new record and sets the ID of sobj.
+func (c *Client) CreateSObject(ctx context.Context, sobj *simpleforce.SObject, blacklistedFields []string, allowDuplicates bool, autoAssign *bool) error {
+	if err := c.injected("CreateSObject"); err != nil {
+		return err
+	}
+
+	id, err := c.create(sobj.Type(), writableFields(sobj, blacklistedFields, false))
+	if err != nil {
+		return clientError(err)
+	}
+
+	sobj.SetID(id)
+
+	return nil
+}
+
+// GetSObject replaces the fields of sobj with the stored record.
+func (c *Client) GetSObject(ctx context.Context, sobj *simpleforce.SObject) error {
+	if err := c.injected("GetSObject"); err != nil {
+		return err
+	}
+
+	fetched, _, err := c.get(sobj.Type(), sobj.ID(), nil)
+	if err != nil {
+		return clientError(err)
+	}
+
+	replace(sobj, fetched)
+
+	return nil
+}
+
+// GetSObjectWithOptions replaces the fields of sobj with the stored record, as configured by opts.
+func (c *Client) GetSObjectWithOptions(ctx context.Context, sobj *simpleforce.SObject, opts *simpleforce.GetOptions) (*simpleforce.GetResult, error) {
+	if err := c.injected("GetSObjectWithOptions"); err != nil {
+		return nil, err
+	}
+
+	fetched, result, err := c.get(sobj.Type(), sobj.ID(), opts)
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	if fetched != nil {
+		replace(sobj, fetched)
+	}
+
+	return result, nil
+}
+
+// GetSObjectByExternalID replaces the fields of sobj with the stored record matching the external ID.
+func (c *Client) GetSObjectByExternalID(ctx context.Context, sobj *simpleforce.SObject, idField, idValue string) error {
+	if err := c.injected("GetSObjectByExternalID"); err != nil {
+		return err
+	}
+
+	if len(idValue) == 0 {
+		idValue = sobj.StringField(idField)
+	}
+
+	fetched, err := c.getByExternalID(sobj.Type(), idField, idValue)
+	if err != nil {
+		return clientError(err)
+	}
+
+	replace(sobj, fetched)
+
+	return nil
+}
+
+// UpdateSObject updates the stored record with the fields of sobj. If the SObject is tracking changes, only the
+// changed fields are written.
+func (c *Client) UpdateSObject(ctx context.Context, sobj *simpleforce.SObject, blacklistedFields []string, autoAssign *bool) error {
+	if err := c.injected("UpdateSObject"); err != nil {
+		return err
+	}
+
+	return c.updateSObject(sobj, blacklistedFields, nil)
+}
+
+// UpdateSObjectIfUnmodified updates the stored record only if it satisfies precondition. If precondition is nil,
+// the LastModifiedDate field of sobj is used.
+func (c *Client) UpdateSObjectIfUnmodified(ctx context.Context, sobj *simpleforce.SObject, blacklistedFields []string, autoAssign *bool, precondition *simpleforce.Precondition) error {
+	if err := c.injected("UpdateSObjectIfUnmodified"); err != nil {
+		return err
+	}
+
+	if precondition == nil {
+		lastModified, err := sobj.DateTimeFieldE("LastModifiedDate")
+		if err != nil || lastModified.IsZero() {
+			return APIError(400, "INVALID_FIELD", "LastModifiedDate is empty or invalid")
+		}
+
+		precondition = &simpleforce.Precondition{IfUnmodifiedSince: lastModified}
+	}
+
+	return c.updateSObject(sobj, blacklistedFields, precondition)
+}
+
+func (c *Client) updateSObject(sobj *simpleforce.SObject, blacklistedFields []string, precondition *simpleforce.Precondition) error {
+	err := c.update(sobj.Type(), sobj.ID(), writableFields(sobj, blacklistedFields, true), precondition)
+	if err != nil {
+		return clientError(err)
+	}
+
+	if sobj.ChangedFields() != nil {
+		sobj.ClearChanges()
+	}
+
+	return nil
+}
+
+// UpsertSObject updates the record matching the external ID, or creates one if there is none. ErrMultipleChoices is
+// returned if several records match. idField must be Id or declared with SetExternalIDFields.
+func (c *Client) UpsertSObject(ctx context.Context, sobj *simpleforce.SObject, idField, idValue string, blacklistedFields []string, allowDuplicates bool, autoAssign *bool) (*simpleforce.UpsertResult, error) {
+	if err := c.injected("UpsertSObject"); err != nil {
+		return nil, err
+	}
+
+	if len(idValue) == 0 {
+		idValue = sobj.StringField(idField)
+	}
+
+	if len(idValue) == 0 {
+		return nil, APIError(400, "MISSING_ARGUMENT", "External ID value is empty")
+	}
+
+	fields := writableFields(sobj, append([]string{idField}, blacklistedFields...), false)
+
+	result, err := c.upsert(sobj.Type(), idField, idValue, fields)
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	sobj.SetID(result.ID)
+
+	return result, nil
+}
+
+// DeleteSObject deletes the stored record.
+func (c *Client) DeleteSObject(ctx context.Context, sobj *simpleforce.SObject) error {
+	if err := c.injected("DeleteSObject"); err != nil {
+		return err
+	}
+
+	return clientError(c.remove(sobj.Type(), sobj.ID()))
+}
+
+// DeleteSObjectByExternalID deletes the stored record matching the external ID.
+func (c *Client) DeleteSObjectByExternalID(ctx context.Context, sobj *simpleforce.SObject, idField, idValue string) error {
+	if err := c.injected("DeleteSObjectByExternalID"); err != nil {
+		return err
+	}
+
+	if len(idValue) == 0 {
+		idValue = sobj.StringField(idField)
+	}
+
+	return clientError(c.removeByExternalID(sobj.Type(), idField, idValue))
+}
+
+// PublishEvent stores a platform event as a record of its type, so tests can inspect it with Records.
+func (c *Client) PublishEvent(ctx context.Context, event *simpleforce.SObject) (*simpleforce.PublishResult, error) {
+	if err := c.injected("PublishEvent"); err != nil {
+		return nil, err
+	}
+
+	result := c.publish(event)
+	if !result.Success {
+		return &result, simpleforce.ErrFailure
+	}
+
+	return &result, nil
+}
+
+// PublishEvents stores platform events as records of their types, so tests can inspect them with Records.
+func (c *Client) PublishEvents(ctx context.Context, events []*simpleforce.SObject) ([]simpleforce.PublishResult, error) {
+	if err := c.injected("PublishEvents"); err != nil {
+		return nil, err
+	}
+
+	results := make([]simpleforce.PublishResult, 0, len(events))
+	for _, event := range events {
+		results = append(results, c.publish(event))
+	}
+
+	return results, nil
+}
+
+func (c *Client) publish(event *simpleforce.SObject) simpleforce.PublishResult {
+	if !strings.HasSuffix(event.Type(), "__e") {
+		return simpleforce.PublishResult{Errors: []simpleforce.SaveError{{
+			StatusCode: "INVALID_TYPE",
+			Message:    "sObject type '" + event.Type() + "' is not a platform event",
+			Fields:     []string{},
+		}}}
+	}
+
+	id, err := c.create(event.Type(), writableFields(event, nil, false))
+	if err != nil {
+		return simpleforce.PublishResult{Errors: []simpleforce.SaveError{saveError(err)}}
+	}
+
+	return simpleforce.PublishResult{ID: id, Success: true, Errors: []simpleforce.SaveError{}}
+}
+
+// GetUpdated returns the IDs of the stored records of typeName last modified between start and end. As with
+// simpleforce.HTTPClient, simpleforce.ErrReplicationWindow is returned if start isn't before end or is more than 30
+// days ago, and as with salesforce, windows longer than simpleforce.MaxReplicationWindow are rejected.
+func (c *Client) GetUpdated(ctx context.Context, typeName string, start, end time.Time) (*simpleforce.UpdatedResult, error) {
+	if err := c.injected("GetUpdated"); err != nil {
+		return nil, err
+	}
+	if err := c.validateReplicationWindow(start, end); err != nil {
+		return nil, err
+	}
+
+	result, err := c.updated(typeName, start, end)
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	return result, nil
+}
+
+// GetDeleted returns the records of typeName deleted between start and end, with the same validation as GetUpdated.
+func (c *Client) GetDeleted(ctx context.Context, typeName string, start, end time.Time) (*simpleforce.DeletedResult, error) {
+	if err := c.injected("GetDeleted"); err != nil {
+		return nil, err
+	}
+	if err := c.validateReplicationWindow(start, end); err != nil {
+		return nil, err
+	}
+
+	result, err := c.deletedRecords(typeName, start, end)
+	if err != nil {
+		return nil, clientError(err)
+	}
+
+	return result, nil
+}
+
+// validateReplicationWindow returns the error simpleforce.HTTPClient returns without a request for start and end.
+func (c *Client) validateReplicationWindow(start, end time.Time) error {
+	if !start.Before(end) {
+		return simpleforce.ErrReplicationWindow{Start: start, End: end, Message: "start must be before end"}
+	}
+	if start.Before(c.now().Add(-simpleforce.MaxReplicationWindow)) {
+		return simpleforce.ErrReplicationWindow{Start: start, End: end, Message: "start must be within the 30 days salesforce retains changes"}
+	}
+
+	return nil
+}
+
+// DownloadFile writes the VersionData field of the stored ContentVersion record to filepath.
+func (c *Client) DownloadFile(ctx context.Context, contentVersionID string, filepath string) error {
+	if err := c.injected("DownloadFile"); err != nil {
+		return err
+	}
+
+	content, err := c.versionData(contentVersionID)
+	if err != nil {
+		return clientError(err)
+	}
+
+	return os.WriteFile(filepath, content, 0o644)
+}
@@ -0,0 +1,258 @@
+package simpleforcetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_CRUD(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client := NewClient()
+
+	account := simpleforce.NewSObject("Account").Set("Name", "Acme").Set("NumberOfEmployees", 10)
+	err := client.CreateSObject(ctx, account, nil, false, nil)
+	assert.NoError(err)
+	assert.True(simpleforce.ID(account.ID()).Valid())
+	assert.Equal("001", simpleforce.ID(account.ID()).KeyPrefix())
+
+	fetched := simpleforce.NewSObject("Account").SetID(account.ID())
+	err = client.GetSObject(ctx, fetched)
+	assert.NoError(err)
+	assert.Equal("Acme", fetched.StringField("Name"))
+	assert.Equal(10, fetched.IntField("NumberOfEmployees"))
+	assert.NotEmpty(fetched.StringField("CreatedDate"))
+	assert.Empty(fetched.ChangedFields())
+
+	fetched.Set("Name", "Acme Corp")
+	err = client.UpdateSObject(ctx, fetched, nil, nil)
+	assert.NoError(err)
+	assert.Equal("Acme Corp", client.Records("Account")[0].StringField("Name"))
+
+	err = client.DeleteSObject(ctx, fetched)
+	assert.NoError(err)
+	assert.Empty(client.Records("Account"))
+
+	err = client.GetSObject(ctx, fetched)
+	assert.Error(err)
+	assert.Contains(err.Error(), "NOT_FOUND")
+}
+
+func TestClient_UpsertSObject(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client := NewClient()
+	client.SetExternalIDFields("Contact", "External_ID__c")
+
+	res, err := client.UpsertSObject(ctx, simpleforce.NewSObject("Contact").Set("LastName", "Smith"), "External_ID__c", "c-1", nil, false, nil)
+	assert.NoError(err)
+	assert.True(res.Created)
+
+	res2, err := client.UpsertSObject(ctx, simpleforce.NewSObject("Contact").Set("LastName", "Jones"), "External_ID__c", "c-1", nil, false, nil)
+	assert.NoError(err)
+	assert.False(res2.Created)
+	assert.Equal(res.ID, res2.ID)
+
+	contact := simpleforce.NewSObject("Contact")
+	err = client.GetSObjectByExternalID(ctx, contact, "External_ID__c", "c-1")
+	assert.NoError(err)
+	assert.Equal("Jones", contact.StringField("LastName"))
+
+	_, err = client.UpsertSObject(ctx, simpleforce.NewSObject("Contact"), "Email", "a@example.com", nil, false, nil)
+	assert.Error(err)
+
+	err = client.CreateSObject(ctx, simpleforce.NewSObject("Contact").Set("External_ID__c", "c-1"), nil, false, nil)
+	assert.NoError(err)
+
+	_, err = client.UpsertSObject(ctx, simpleforce.NewSObject("Contact"), "External_ID__c", "c-1", nil, false, nil)
+	assert.True(errors.As(err, &simpleforce.ErrMultipleChoices{}))
+}
+
+func TestClient_Query(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client := NewClient()
+
+	account := simpleforce.NewSObject("Account").Set("Name", "Acme")
+	assert.NoError(client.CreateSObject(ctx, account, nil, false, nil))
+
+	for _, name := range []string{"Smith", "jones", "Brown", "O'Brien"} {
+		contact := simpleforce.NewSObject("Contact").Set("LastName", name).Set("AccountId", account.ID())
+		assert.NoError(client.CreateSObject(ctx, contact, nil, false, nil))
+	}
+	assert.NoError(client.CreateSObject(ctx, simpleforce.NewSObject("Contact").Set("LastName", "Orphan"), nil, false, nil))
+
+	result, err := client.Query(ctx, "SELECT LastName, Account.Name FROM Contact WHERE Account.Name = 'acme' "+
+		"AND (LastName LIKE 'o%' OR LastName IN ('Smith', 'JONES')) ORDER BY LastName DESC", "")
+	assert.NoError(err)
+	assert.True(result.Done)
+	assert.Equal(3, result.TotalSize)
+
+	var names []string
+	for _, record := range result.Records {
+		names = append(names, record.StringField("LastName"))
+		assert.Equal("Acme", record.StringPathField("Account.Name"))
+		assert.Nil(record.InterfaceField("Id"))
+	}
+	assert.Equal([]string{"Smith", "O'Brien", "jones"}, names)
+
+	result, err = client.Query(ctx, "SELECT COUNT() FROM Contact WHERE AccountId = null", "")
+	assert.NoError(err)
+	assert.Equal(1, result.TotalSize)
+	assert.Empty(result.Records)
+
+	_, err = client.Query(ctx, "SELECT Id FROM Contact WHERE CreatedDate = TODAY", "")
+	assert.Error(err)
+
+	_, err = client.Query(ctx, "SELECT Id FROM Contact WHERE", "")
+	assert.Error(err)
+}
+
+func TestClient_Query_pagination(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client := NewClient()
+	client.SetPageSize(2)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(client.CreateSObject(ctx, simpleforce.NewSObject("Lead").Set("Rank__c", i), nil, false, nil))
+	}
+
+	records, err := simpleforce.FetchReferences(ctx, client, simpleforce.NewTypeResolver(client), []simpleforce.ID{
+		simpleforce.ID(client.Records("Lead")[0].ID()),
+	}, []string{"Rank__c"})
+	assert.NoError(err)
+	assert.Len(records, 1)
+
+	var ranks []int
+	var nextRecordsURL string
+	for {
+		result, err := client.Query(ctx, "SELECT Rank__c FROM Lead ORDER BY Rank__c DESC LIMIT 4", nextRecordsURL)
+		assert.NoError(err)
+
+		for _, record := range result.Records {
+			ranks = append(ranks, record.IntField("Rank__c"))
+		}
+
+		if result.Done {
+			break
+		}
+		nextRecordsURL = result.NextRecordsURL
+	}
+
+	assert.Equal([]int{4, 3, 2, 1}, ranks)
+}
+
+func TestClient_InjectError(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client := NewClient()
+
+	client.InjectError("CreateSObject", APIError(400, "REQUIRED_FIELD_MISSING", "Required fields are missing: [Name]"))
+
+	err := client.CreateSObject(ctx, simpleforce.NewSObject("Account"), nil, false, nil)
+	assert.Equal(simpleforce.ErrAPI{StatusCode: 400, ErrorCode: "REQUIRED_FIELD_MISSING", Message: "Required fields are missing: [Name]"}, err)
+
+	err = client.CreateSObject(ctx, simpleforce.NewSObject("Account"), nil, false, nil)
+	assert.NoError(err)
+}
+
+func TestClient_errors(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	server := NewServer(nil)
+	defer server.Close()
+
+	fake := server.Store()
+	client := server.NewHTTPClient()
+
+	fake.SetExternalIDFields("Contact", "External_ID__c")
+	for i := 0; i < 2; i++ {
+		assert.NoError(fake.CreateSObject(ctx, simpleforce.NewSObject("Contact").Set("External_ID__c", "dup"), nil, false, nil))
+	}
+	contact := fake.Records("Contact")[0]
+	now := time.Now()
+
+	calls := map[string]func(c simpleforce.Client) error{
+		"not found": func(c simpleforce.Client) error {
+			return c.GetSObject(ctx, simpleforce.NewSObject("Contact").SetID("0035000000Gv7qJAAR"))
+		},
+		"multiple choices": func(c simpleforce.Client) error {
+			return c.(simpleforce.ExternalIDClient).GetSObjectByExternalID(ctx, simpleforce.NewSObject("Contact"), "External_ID__c", "dup")
+		},
+		"precondition failed": func(c simpleforce.Client) error {
+			sobj := simpleforce.NewSObject("Contact").SetID(contact.ID()).Set("LastName", "Smith")
+			return c.(simpleforce.ConditionalUpdater).UpdateSObjectIfUnmodified(ctx, sobj, nil, nil, &simpleforce.Precondition{IfMatch: `"stale"`})
+		},
+		"reversed replication window": func(c simpleforce.Client) error {
+			_, err := c.(simpleforce.ReplicationLister).GetUpdated(ctx, "Contact", now, now.Add(-time.Hour))
+			return err
+		},
+		"expired replication window": func(c simpleforce.Client) error {
+			_, err := c.(simpleforce.ReplicationLister).GetDeleted(ctx, "Contact", now.Add(-31*24*time.Hour), now)
+			return err
+		},
+	}
+
+	// The fake returns the errors HTTPClient returns for the responses of the Server.
+	for name, call := range calls {
+		expected, actual := call(client), call(fake)
+		assert.Error(expected, name)
+		assert.IsType(expected, actual, name)
+		assert.EqualError(actual, expected.Error(), name)
+	}
+}
+
+func TestClient_UpdateSObjectIfUnmodified(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	client := NewClient()
+
+	account := simpleforce.NewSObject("Account").Set("NumberOfEmployees", 1)
+	assert.NoError(client.CreateSObject(ctx, account, nil, false, nil))
+
+	stale := simpleforce.NewSObject("Account").SetID(account.ID())
+	res, err := client.GetSObjectWithOptions(ctx, stale, nil)
+	assert.NoError(err)
+
+	// A concurrent writer updates the record between the read and the write.
+	client.InjectError("UpdateSObjectIfUnmodified", APIError(412, "PRECONDITION_FAILED", "modified"))
+
+	attempts := 0
+	err = simpleforce.UpdateSObjectWithRetry(ctx, client, account, nil, nil, 0, func(sobj *simpleforce.SObject) error {
+		attempts++
+		sobj.Set("NumberOfEmployees", sobj.IntField("NumberOfEmployees")+1)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(2, attempts)
+	assert.Equal(2, client.Records("Account")[0].IntField("NumberOfEmployees"))
+
+	stale.Set("NumberOfEmployees", 100)
+	err = client.UpdateSObjectIfUnmodified(ctx, stale, nil, nil, &simpleforce.Precondition{IfMatch: res.ETag})
+	assert.True(errors.As(err, &simpleforce.ErrPreconditionFailed{}))
+
+	res2, err := client.GetSObjectWithOptions(ctx, simpleforce.NewSObject("Account").SetID(account.ID()), &simpleforce.GetOptions{
+		IfModifiedSince: res.LastModified,
+	})
+	assert.NoError(err)
+	assert.False(res2.NotModified)
+
+	res3, err := client.GetSObjectWithOptions(ctx, simpleforce.NewSObject("Account").SetID(account.ID()), &simpleforce.GetOptions{
+		IfNoneMatch: res2.ETag,
+	})
+	assert.NoError(err)
+	assert.True(res3.NotModified)
+}
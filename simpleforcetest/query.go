@@ -0,0 +1,530 @@
+package simpleforcetest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/eleanorhealth/simpleforce/soql"
+)
+
+// query runs a SOQL query, returning the matching records and their total count. The caller must hold c.mu.
+func (c *Client) query(query string) ([]*simpleforce.SObject, int, error) {
+	stmt, err := soql.Parse(query)
+	if err != nil {
+		return nil, 0, newAPIError(400, "MALFORMED_QUERY", err.Error())
+	}
+
+	err = checkSupported(stmt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matches []*record
+	for _, rec := range c.sorted(stmt.From) {
+		ok, err := c.match(rec, stmt.Alias, stmt.Where)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if ok {
+			matches = append(matches, rec)
+		}
+	}
+
+	if len(stmt.OrderBy) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return c.less(matches[i], matches[j], stmt.Alias, stmt.OrderBy)
+		})
+	}
+
+	if stmt.Offset != nil {
+		if *stmt.Offset >= len(matches) {
+			matches = nil
+		} else {
+			matches = matches[*stmt.Offset:]
+		}
+	}
+
+	if stmt.Limit != nil && *stmt.Limit < len(matches) {
+		matches = matches[:*stmt.Limit]
+	}
+
+	if stmt.Select[0].Field.Function != "" {
+		// COUNT() returns only the number of matching records.
+		return nil, len(matches), nil
+	}
+
+	records := make([]*simpleforce.SObject, 0, len(matches))
+	for _, rec := range matches {
+		records = append(records, c.project(rec, stmt))
+	}
+
+	return records, len(records), nil
+}
+
+// checkSupported rejects the parts of SOQL the Client doesn't support.
+func checkSupported(stmt *soql.Statement) error {
+	if len(stmt.GroupBy) > 0 || stmt.Having != nil {
+		return unsupportedQuery("GROUP BY")
+	}
+
+	for _, item := range stmt.Select {
+		switch {
+		case item.Subquery != nil:
+			return unsupportedQuery("subqueries")
+		case item.TypeOf != nil:
+			return unsupportedQuery("TYPEOF")
+		case len(item.Field.Function) > 0:
+			if !strings.EqualFold(item.Field.Function, "COUNT") || len(item.Field.Name) > 0 || len(stmt.Select) > 1 {
+				return unsupportedQuery(item.Field.Function + "()")
+			}
+		}
+	}
+
+	for _, item := range stmt.OrderBy {
+		if len(item.Field.Function) > 0 {
+			return unsupportedQuery(item.Field.Function + "()")
+		}
+	}
+
+	return checkSupportedExpr(stmt.Where)
+}
+
+func checkSupportedExpr(expr soql.Expr) error {
+	switch e := expr.(type) {
+	case *soql.LogicalExpr:
+		for _, operand := range e.Operands {
+			err := checkSupportedExpr(operand)
+			if err != nil {
+				return err
+			}
+		}
+	case *soql.NotExpr:
+		return checkSupportedExpr(e.Operand)
+	case *soql.Comparison:
+		if len(e.Field.Function) > 0 {
+			return unsupportedQuery(e.Field.Function + "()")
+		}
+
+		values := []soql.Value{e.Value}
+		if e.Value.Kind == soql.ListValue {
+			values = e.Value.List
+		}
+
+		for _, v := range values {
+			switch v.Kind {
+			case soql.SubqueryValue:
+				return unsupportedQuery("semi-joins")
+			case soql.DateLiteralValue:
+				return unsupportedQuery("date literal " + v.Text)
+			}
+		}
+	}
+
+	return nil
+}
+
+func unsupportedQuery(construct string) error {
+	return newAPIError(400, "MALFORMED_QUERY", fmt.Sprintf("simpleforcetest: %s not supported in queries", construct))
+}
+
+// resolve returns the value of a field or parent relationship path of rec. Parent relationships resolve to a copy of
+// the related record with its attributes. The caller must hold c.mu.
+func (c *Client) resolve(rec *record, path string) (interface{}, bool) {
+	names := strings.Split(path, ".")
+
+	for i, name := range names {
+		if i == len(names)-1 {
+			value, ok := lookup(rec.fields, name)
+			return copyValue(value), ok
+		}
+
+		parent := c.parent(rec, name)
+		if parent == nil {
+			return nil, false
+		}
+
+		rec = parent
+	}
+
+	return nil, false
+}
+
+// parent returns the record referenced by a parent relationship of rec, or nil if it is empty. The caller must hold
+// c.mu.
+func (c *Client) parent(rec *record, relationship string) *record {
+	field := relationship + "Id"
+	if strings.HasSuffix(strings.ToLower(relationship), "__r") {
+		field = relationship[:len(relationship)-3] + "__c"
+	}
+
+	value, _ := lookup(rec.fields, field)
+	id, _ := value.(string)
+	if len(id) == 0 {
+		return nil
+	}
+
+	prefix := simpleforce.ID(id).KeyPrefix()
+	for typeName, p := range c.prefixes {
+		if p == prefix {
+			return c.find(typeName, id)
+		}
+	}
+
+	return nil
+}
+
+// project builds the query result for rec, with the selected fields nested under their relationships as salesforce
+// returns them. The caller must hold c.mu.
+func (c *Client) project(rec *record, stmt *soql.Statement) *simpleforce.SObject {
+	sobj := &simpleforce.SObject{"attributes": rec.attributes()}
+
+	for _, item := range stmt.Select {
+		names := strings.Split(trimAlias(item.Field.Name, stmt.Alias), ".")
+
+		target := map[string]interface{}(*sobj)
+		current := rec
+
+		for i, name := range names {
+			if i == len(names)-1 {
+				value, ok := lookup(current.fields, name)
+				if ok {
+					name, _ = findKey(current.fields, name)
+				}
+				target[name] = copyValue(value)
+				break
+			}
+
+			parent := c.parent(current, name)
+			if parent == nil {
+				if _, ok := target[name]; !ok {
+					target[name] = nil
+				}
+				break
+			}
+
+			nested, ok := target[name].(map[string]interface{})
+			if !ok {
+				nested = map[string]interface{}{"attributes": parent.attributes()}
+				target[name] = nested
+			}
+
+			target = nested
+			current = parent
+		}
+	}
+
+	sobj.ClearChanges()
+
+	return sobj
+}
+
+// trimAlias removes the alias of the queried SObject from the start of a field path.
+func trimAlias(path, alias string) string {
+	if len(alias) > 0 && len(path) > len(alias) && strings.EqualFold(path[:len(alias)+1], alias+".") {
+		return path[len(alias)+1:]
+	}
+
+	return path
+}
+
+// match evaluates a WHERE condition against rec. The caller must hold c.mu.
+func (c *Client) match(rec *record, alias string, expr soql.Expr) (bool, error) {
+	switch e := expr.(type) {
+	case nil:
+		return true, nil
+
+	case *soql.LogicalExpr:
+		and := e.Operator == "AND"
+
+		for _, operand := range e.Operands {
+			ok, err := c.match(rec, alias, operand)
+			if err != nil {
+				return false, err
+			}
+
+			if ok != and {
+				return ok, nil
+			}
+		}
+
+		return and, nil
+
+	case *soql.NotExpr:
+		ok, err := c.match(rec, alias, e.Operand)
+		return !ok, err
+
+	case *soql.Comparison:
+		value, _ := c.resolve(rec, trimAlias(e.Field.Name, alias))
+		return compare(value, e.Operator, e.Value)
+	}
+
+	return false, fmt.Errorf("simpleforcetest: unexpected condition %T", expr)
+}
+
+// compare evaluates a comparison of a field value with a literal.
+func compare(value interface{}, operator string, literal soql.Value) (bool, error) {
+	switch operator {
+	case "IN", "NOT IN":
+		in := false
+		for _, item := range literal.List {
+			ok, err := compare(value, "=", item)
+			if err != nil {
+				return false, err
+			}
+			in = in || ok
+		}
+
+		return in == (operator == "IN"), nil
+
+	case "INCLUDES", "EXCLUDES":
+		s, _ := value.(string)
+		selected := make(map[string]bool)
+		for _, v := range strings.Split(s, ";") {
+			selected[strings.ToLower(strings.TrimSpace(v))] = true
+		}
+
+		includes := false
+		for _, item := range literal.List {
+			all := true
+			for _, v := range strings.Split(item.Text, ";") {
+				all = all && selected[strings.ToLower(strings.TrimSpace(v))]
+			}
+			includes = includes || all
+		}
+
+		return includes == (operator == "INCLUDES"), nil
+
+	case "LIKE":
+		s, ok := value.(string)
+		if !ok {
+			return false, nil
+		}
+
+		return likePattern(literal.Text).MatchString(s), nil
+	}
+
+	if literal.Kind == soql.NullValue || value == nil {
+		isNull := literal.Kind == soql.NullValue && value == nil
+
+		switch operator {
+		case "=":
+			return isNull, nil
+		case "!=":
+			return !isNull, nil
+		default:
+			return false, nil
+		}
+	}
+
+	cmp, ok := compareValues(value, literal)
+	if !ok {
+		return operator == "!=", nil
+	}
+
+	switch operator {
+	case "=":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+
+	return false, fmt.Errorf("simpleforcetest: unexpected operator %s", operator)
+}
+
+// compareValues compares a field value with a literal, returning false if they can't be compared.
+func compareValues(value interface{}, literal soql.Value) (int, bool) {
+	switch literal.Kind {
+	case soql.BoolValue:
+		b, ok := value.(bool)
+		if !ok {
+			return 0, false
+		}
+		return compareBools(b, strings.EqualFold(literal.Text, "true")), true
+
+	case soql.NumberValue, soql.CurrencyValue:
+		text := literal.Text
+		if literal.Kind == soql.CurrencyValue {
+			text = text[3:]
+		}
+
+		want, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		got, ok := value.(float64)
+		if !ok {
+			return 0, false
+		}
+		return compareFloats(got, want), true
+
+	case soql.DateValue:
+		s, ok := value.(string)
+		if !ok || len(s) < len(literal.Text) {
+			return 0, false
+		}
+		return strings.Compare(s[:len(literal.Text)], literal.Text), true
+
+	case soql.DateTimeValue:
+		s, _ := value.(string)
+		got, err := parseDateTime(s)
+		if err != nil {
+			return 0, false
+		}
+
+		want, err := parseDateTime(literal.Text)
+		if err != nil {
+			return 0, false
+		}
+		return compareTimes(got, want), true
+
+	case soql.StringValue:
+		s, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(strings.ToLower(s), strings.ToLower(literal.Text)), true
+	}
+
+	return 0, false
+}
+
+// less orders two records by the ORDER BY items. Nulls sort first in ascending order and last in descending order
+// unless specified otherwise. The caller must hold c.mu.
+func (c *Client) less(a, b *record, alias string, orderBy []soql.OrderItem) bool {
+	for _, item := range orderBy {
+		path := trimAlias(item.Field.Name, alias)
+
+		va, _ := c.resolve(a, path)
+		vb, _ := c.resolve(b, path)
+
+		nullsFirst := !item.Desc
+		if len(item.Nulls) > 0 {
+			nullsFirst = item.Nulls == "FIRST"
+		}
+
+		switch {
+		case va == nil && vb == nil:
+			continue
+		case va == nil:
+			return nullsFirst
+		case vb == nil:
+			return !nullsFirst
+		}
+
+		cmp := compareAny(va, vb)
+		if cmp == 0 {
+			continue
+		}
+
+		return (cmp < 0) != item.Desc
+	}
+
+	return false
+}
+
+// compareAny compares two non-null field values of the same type.
+func compareAny(a, b interface{}) int {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			return compareFloats(a, b)
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			return compareBools(a, b)
+		}
+	}
+
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// dateTimeLayouts are the formats of datetime values in records and queries.
+var dateTimeLayouts = []string{dateTimeLayout, time.RFC3339Nano, "2006-01-02T15:04:05-0700"}
+
+func parseDateTime(s string) (time.Time, error) {
+	var err error
+
+	for _, layout := range dateTimeLayouts {
+		var t time.Time
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// likePattern converts a LIKE pattern to a case-insensitive regular expression. The wildcards % and _ can be escaped
+// with a backslash.
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+
+	b.WriteString("(?is)^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
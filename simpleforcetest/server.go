@@ -0,0 +1,920 @@
+package simpleforcetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+)
+
+// DefaultAPILimit is the number of API requests a Server accepts before responding with REQUEST_LIMIT_EXCEEDED.
+const DefaultAPILimit = 15000
+
+// Server is a local HTTP server speaking the salesforce REST API, backed by the records of a Client. It serves
+// queries, parameterized searches, describes, record CRUD by ID and external ID, VersionData of ContentVersions,
+// composite requests and sObject collections, with the response shapes and headers of salesforce. Faults such as
+// expired sessions, unavailability and row locks can be injected with AddFault.
+//
+//	server := simpleforcetest.NewServer(nil)
+//	defer server.Close()
+//
+//	client := server.NewHTTPClient()
+type Server struct {
+	*httptest.Server
+
+	store *Client
+
+	mu     sync.Mutex
+	faults []*Fault
+	usage  int
+	limit  int
+}
+
+// NewServer starts a Server backed by store. If store is nil, a new empty Client is used. The Server must be closed
+// by the caller.
+func NewServer(store *Client) *Server {
+	if store == nil {
+		store = NewClient()
+	}
+
+	s := &Server{
+		store: store,
+		limit: DefaultAPILimit,
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Store returns the Client holding the records of the Server. Records written through either are visible to both.
+func (s *Server) Store() *Client {
+	return s.store
+}
+
+// NewHTTPClient creates an HTTPClient sending requests to the Server.
+func (s *Server) NewHTTPClient() *simpleforce.HTTPClient {
+	return simpleforce.NewHTTPClient(s.Client(), s.URL, simpleforce.DefaultAPIVersion)
+}
+
+// SetAPILimit sets the number of API requests accepted by the Server, reported in the Sforce-Limit-Info header.
+func (s *Server) SetAPILimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+}
+
+// APIUsage returns the number of API requests received by the Server. A composite request counts as one.
+func (s *Server) APIUsage() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.usage
+}
+
+// Fault makes the Server delay or fail the requests it matches.
+type Fault struct {
+	// Method matches requests by HTTP method. All methods are matched if empty.
+	Method string
+	// Path matches requests by the prefix of their path after the API version, such as "sobjects/Account". All
+	// paths are matched if empty.
+	Path string
+	// Latency delays the response.
+	Latency time.Duration
+	// StatusCode, ErrorCode and Message describe the error response. No error is returned if StatusCode is zero.
+	StatusCode int
+	ErrorCode  string
+	Message    string
+	// Count is the number of requests the fault applies to. If zero, it applies until the faults are cleared.
+	Count int
+}
+
+// SessionExpired returns a Fault responding as salesforce does when the access token expired.
+func SessionExpired() Fault {
+	return Fault{
+		StatusCode: http.StatusUnauthorized,
+		ErrorCode:  "INVALID_SESSION_ID",
+		Message:    "Session expired or invalid",
+	}
+}
+
+// ServiceUnavailable returns a Fault responding as salesforce does during maintenance or overload.
+func ServiceUnavailable() Fault {
+	return Fault{
+		StatusCode: http.StatusServiceUnavailable,
+		ErrorCode:  "SERVER_UNAVAILABLE",
+		Message:    "Server temporarily unavailable, please try again later",
+	}
+}
+
+// RowLock returns a Fault responding as salesforce does when a record is locked by another transaction.
+func RowLock() Fault {
+	return Fault{
+		StatusCode: http.StatusBadRequest,
+		ErrorCode:  "UNABLE_TO_LOCK_ROW",
+		Message:    "unable to obtain exclusive access to this record or 1 records",
+	}
+}
+
+// AddFault adds a fault to the Server. Faults are checked in the order they were added; the latencies of all
+// matching faults are applied, and the first matching error is returned.
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all faults of the Server.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// fault returns the latency and error injected for a request, using up the matching faults.
+func (s *Server) fault(method, path string) (time.Duration, *apiError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latency time.Duration
+	var err *apiError
+
+	faults := s.faults[:0]
+	for _, f := range s.faults {
+		matched := (len(f.Method) == 0 || strings.EqualFold(f.Method, method)) && strings.HasPrefix(path, f.Path)
+		if matched && (f.StatusCode == 0 || err == nil) {
+			latency += f.Latency
+			if f.StatusCode != 0 {
+				err = newAPIError(f.StatusCode, f.ErrorCode, f.Message)
+			}
+
+			if f.Count > 0 {
+				f.Count--
+				if f.Count == 0 {
+					continue
+				}
+			}
+		}
+
+		faults = append(faults, f)
+	}
+	s.faults = faults
+
+	return latency, err
+}
+
+// response is the response to a REST API request.
+type response struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+func jsonResponse(statusCode int, v interface{}) *response {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	return &response{
+		statusCode: statusCode,
+		header:     http.Header{"Content-Type": {"application/json;charset=UTF-8"}},
+		body:       body,
+	}
+}
+
+// errorResponse returns the response of an error. Errors other than API errors are internal server errors.
+func errorResponse(err error) *response {
+	e, ok := err.(*apiError)
+	if !ok {
+		e = newAPIError(http.StatusInternalServerError, "UNKNOWN_EXCEPTION", err.Error())
+	}
+
+	return &response{
+		statusCode: e.statusCode,
+		header:     http.Header{"Content-Type": {"application/json;charset=UTF-8"}},
+		body:       e.body(),
+	}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.usage++
+	usage, limit := s.usage, s.limit
+	s.mu.Unlock()
+
+	w.Header().Set("Sforce-Limit-Info", fmt.Sprintf("api-usage=%d/%d", usage, limit))
+
+	res := s.serve(r, usage > limit)
+
+	for key, values := range res.header {
+		w.Header()[key] = values
+	}
+
+	w.WriteHeader(res.statusCode)
+	_, _ = w.Write(res.body)
+}
+
+func (s *Server) serve(r *http.Request, limitExceeded bool) *response {
+	path, ok := resourcePath(r.URL.EscapedPath())
+	if !ok {
+		return errorResponse(notFound())
+	}
+
+	if limitExceeded {
+		return errorResponse(newAPIError(http.StatusForbidden, "REQUEST_LIMIT_EXCEEDED", "TotalRequests Limit exceeded."))
+	}
+
+	latency, err := s.fault(r.Method, unescapePath(path))
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return errorResponse(r.Context().Err())
+		}
+	}
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	body, readErr := io.ReadAll(r.Body)
+	if readErr != nil {
+		return errorResponse(readErr)
+	}
+
+	if path == "composite" && r.Method == http.MethodPost {
+		return s.composite(body)
+	}
+
+	return s.route(r.Method, path, r.URL.Query(), r.Header, body)
+}
+
+// resourcePath returns the escaped path of a REST API resource relative to the API version, such as
+// "sobjects/Account/001000000000001AAA".
+func resourcePath(escapedPath string) (string, bool) {
+	rest := strings.TrimPrefix(escapedPath, "/services/data/")
+	if rest == escapedPath || !strings.HasPrefix(rest, "v") {
+		return "", false
+	}
+
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return "", false
+	}
+
+	return strings.Trim(rest[i+1:], "/"), true
+}
+
+func unescapePath(path string) string {
+	unescaped, err := url.PathUnescape(path)
+	if err != nil {
+		return path
+	}
+
+	return unescaped
+}
+
+// route serves a request to a REST API resource.
+func (s *Server) route(method, path string, query url.Values, header http.Header, body []byte) *response {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = unescapePath(segment)
+	}
+
+	switch {
+	case segments[0] == "query" && len(segments) == 1:
+		if len(query.Get("explain")) > 0 {
+			return errorResponse(newAPIError(http.StatusBadRequest, "MALFORMED_QUERY", "simpleforcetest: explain is not supported"))
+		}
+		return s.query(method, query.Get("q"), "")
+	case segments[0] == "query" && len(segments) == 2:
+		return s.query(method, "", fmt.Sprintf("/services/data/%s/query/%s", apiVersion, segments[1]))
+	case segments[0] == "search" && len(segments) == 1:
+		return errorResponse(newAPIError(http.StatusBadRequest, "INVALID_SEARCH", "simpleforcetest: SOSL is not supported"))
+	case segments[0] == "parameterizedSearch" && len(segments) == 1:
+		return s.search(method, body)
+	case segments[0] == "sobjects":
+		return s.sobjects(method, segments[1:], query, header, body)
+	case segments[0] == "composite" && len(segments) >= 2 && segments[1] == "sobjects":
+		return s.collection(method, segments[2:], query, body)
+	}
+
+	return errorResponse(notFound())
+}
+
+func methodNotAllowed(method string) *response {
+	return errorResponse(newAPIError(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+method+"' not allowed"))
+}
+
+func (s *Server) query(method, query, nextRecordsURL string) *response {
+	if method != http.MethodGet {
+		return methodNotAllowed(method)
+	}
+
+	result, err := s.store.queryPage(query, nextRecordsURL)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	return jsonResponse(http.StatusOK, result)
+}
+
+func (s *Server) search(method string, body []byte) *response {
+	if method != http.MethodPost {
+		return methodNotAllowed(method)
+	}
+
+	req := &simpleforce.SearchRequest{}
+	err := json.Unmarshal(body, req)
+	if err != nil {
+		return errorResponse(newAPIError(http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error()))
+	}
+
+	result, err := s.store.search(req)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	return jsonResponse(http.StatusOK, result)
+}
+
+// sobjects serves the resources under sobjects/.
+func (s *Server) sobjects(method string, segments []string, query url.Values, header http.Header, body []byte) *response {
+	switch {
+	case len(segments) == 0 || len(segments[0]) == 0:
+		if method != http.MethodGet {
+			return methodNotAllowed(method)
+		}
+		return jsonResponse(http.StatusOK, s.store.describeGlobal())
+	case len(segments) == 1:
+		if method != http.MethodPost {
+			return methodNotAllowed(method)
+		}
+		return s.create(segments[0], body)
+	case len(segments) == 2 && segments[1] == "describe":
+		return s.describe(method, segments[0], header)
+	case len(segments) == 2 && (segments[1] == "updated" || segments[1] == "deleted"):
+		return s.replication(method, segments[0], segments[1], query)
+	case len(segments) == 2:
+		return s.record(method, segments[0], segments[1], query, header, body)
+	case len(segments) == 3 && segments[0] == "ContentVersion" && segments[2] == "VersionData":
+		return s.versionData(method, segments[1])
+	case len(segments) == 3:
+		return s.externalIDRecord(method, segments[0], segments[1], segments[2], body)
+	}
+
+	return errorResponse(notFound())
+}
+
+func (s *Server) describe(method, typeName string, header http.Header) *response {
+	if method != http.MethodGet {
+		return methodNotAllowed(method)
+	}
+
+	meta, err := s.store.describe(typeName)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// The metadata never changes.
+	if len(header.Get("If-Modified-Since")) > 0 {
+		return &response{statusCode: http.StatusNotModified}
+	}
+
+	return jsonResponse(http.StatusOK, meta)
+}
+
+// saveResult is the result of a record write.
+type saveResult struct {
+	ID      string                  `json:"id,omitempty"`
+	Success bool                    `json:"success"`
+	Errors  []simpleforce.SaveError `json:"errors"`
+}
+
+// replication serves sobjects/{type}/updated and sobjects/{type}/deleted.
+func (s *Server) replication(method, typeName, resource string, query url.Values) *response {
+	if method != http.MethodGet {
+		return methodNotAllowed(method)
+	}
+
+	start, err := time.Parse(time.RFC3339, query.Get("start"))
+	if err != nil {
+		return errorResponse(newAPIError(http.StatusBadRequest, "INVALID_REPLICATION_DATE", "invalid start: "+query.Get("start")))
+	}
+
+	end, err := time.Parse(time.RFC3339, query.Get("end"))
+	if err != nil {
+		return errorResponse(newAPIError(http.StatusBadRequest, "INVALID_REPLICATION_DATE", "invalid end: "+query.Get("end")))
+	}
+
+	if resource == "updated" {
+		result, err := s.store.updated(typeName, start, end)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		return jsonResponse(http.StatusOK, map[string]interface{}{
+			"ids":               result.IDs,
+			"latestDateCovered": result.LatestDateCovered.Format(dateTimeLayout),
+		})
+	}
+
+	result, err := s.store.deletedRecords(typeName, start, end)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	deletedRecords := make([]map[string]string, 0, len(result.DeletedRecords))
+	for _, deleted := range result.DeletedRecords {
+		deletedRecords = append(deletedRecords, map[string]string{
+			"id":          deleted.ID,
+			"deletedDate": deleted.DeletedDate.Format(dateTimeLayout),
+		})
+	}
+
+	return jsonResponse(http.StatusOK, map[string]interface{}{
+		"deletedRecords":        deletedRecords,
+		"earliestDateAvailable": result.EarliestDateAvailable.Format(dateTimeLayout),
+		"latestDateCovered":     result.LatestDateCovered.Format(dateTimeLayout),
+	})
+}
+
+func decodeFields(body []byte) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+
+	err := json.Unmarshal(body, &fields)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+	}
+
+	return fields, nil
+}
+
+func (s *Server) create(typeName string, body []byte) *response {
+	fields, err := decodeFields(body)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	id, err := s.store.create(typeName, fields)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	res := jsonResponse(http.StatusCreated, saveResult{ID: id, Success: true, Errors: []simpleforce.SaveError{}})
+	res.header.Set("Location", fmt.Sprintf("/services/data/%s/sobjects/%s/%s", apiVersion, typeName, id))
+
+	return res
+}
+
+// record serves sobjects/{type}/{id}.
+func (s *Server) record(method, typeName, id string, query url.Values, header http.Header, body []byte) *response {
+	switch method {
+	case http.MethodGet:
+		opts := &simpleforce.GetOptions{IfNoneMatch: header.Get("If-None-Match")}
+		if fields := query.Get("fields"); len(fields) > 0 {
+			opts.Fields = strings.Split(fields, ",")
+		}
+		if since, err := http.ParseTime(header.Get("If-Modified-Since")); err == nil {
+			opts.IfModifiedSince = since
+		}
+
+		sobj, result, err := s.store.get(typeName, id, opts)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		res := &response{statusCode: http.StatusNotModified, header: http.Header{}}
+		if sobj != nil {
+			res = jsonResponse(http.StatusOK, sobj)
+		}
+		res.header.Set("ETag", result.ETag)
+		res.header.Set("Last-Modified", result.LastModified.UTC().Format(http.TimeFormat))
+
+		return res
+	case http.MethodPatch:
+		fields, err := decodeFields(body)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		var precondition *simpleforce.Precondition
+		if len(header.Get("If-Match")) > 0 || len(header.Get("If-Unmodified-Since")) > 0 {
+			precondition = &simpleforce.Precondition{IfMatch: header.Get("If-Match")}
+			if since, err := http.ParseTime(header.Get("If-Unmodified-Since")); err == nil {
+				precondition.IfUnmodifiedSince = since
+			}
+		}
+
+		err = s.store.update(typeName, id, fields, precondition)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		return &response{statusCode: http.StatusNoContent}
+	case http.MethodDelete:
+		err := s.store.remove(typeName, id)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		return &response{statusCode: http.StatusNoContent}
+	}
+
+	return methodNotAllowed(method)
+}
+
+// externalIDRecord serves sobjects/{type}/{field}/{value}.
+func (s *Server) externalIDRecord(method, typeName, idField, idValue string, body []byte) *response {
+	switch method {
+	case http.MethodGet:
+		sobj, err := s.store.getByExternalID(typeName, idField, idValue)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		return jsonResponse(http.StatusOK, sobj)
+	case http.MethodPatch:
+		fields, err := decodeFields(body)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		result, err := s.store.upsert(typeName, idField, idValue, fields)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		if result.Created {
+			return jsonResponse(http.StatusCreated, result)
+		}
+
+		return jsonResponse(http.StatusOK, result)
+	case http.MethodDelete:
+		err := s.store.removeByExternalID(typeName, idField, idValue)
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		return &response{statusCode: http.StatusNoContent}
+	}
+
+	return methodNotAllowed(method)
+}
+
+func (s *Server) versionData(method, id string) *response {
+	if method != http.MethodGet {
+		return methodNotAllowed(method)
+	}
+
+	content, err := s.store.versionData(id)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	return &response{
+		statusCode: http.StatusOK,
+		header:     http.Header{"Content-Type": {"application/octetstream"}},
+		body:       content,
+	}
+}
+
+// collectionRequest is the body of sObject collection create and update requests.
+type collectionRequest struct {
+	AllOrNone bool                     `json:"allOrNone"`
+	Records   []map[string]interface{} `json:"records"`
+}
+
+// collection serves the sObject collections resources under composite/sobjects.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections.htm
+func (s *Server) collection(method string, segments []string, query url.Values, body []byte) *response {
+	if method == http.MethodGet {
+		if len(segments) != 1 {
+			return errorResponse(notFound())
+		}
+		return s.retrieveCollection(segments[0], query)
+	}
+
+	if len(segments) != 0 {
+		return errorResponse(notFound())
+	}
+
+	req := &collectionRequest{}
+
+	var write func(index int) (string, error)
+
+	switch method {
+	case http.MethodPost:
+		err := json.Unmarshal(body, req)
+		if err != nil {
+			return errorResponse(newAPIError(http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error()))
+		}
+
+		write = func(index int) (string, error) {
+			fields := req.Records[index]
+			if _, ok := fields["Id"]; ok {
+				return "", newAPIError(http.StatusBadRequest, "INVALID_FIELD", "cannot specify Id in an insert call")
+			}
+			return s.store.create(recordType(fields), fields)
+		}
+	case http.MethodPatch:
+		err := json.Unmarshal(body, req)
+		if err != nil {
+			return errorResponse(newAPIError(http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error()))
+		}
+
+		write = func(index int) (string, error) {
+			fields := req.Records[index]
+			id, _ := fields["Id"].(string)
+			if len(id) == 0 {
+				return "", newAPIError(http.StatusBadRequest, "MISSING_ARGUMENT", "Id not specified in an update call")
+			}
+			return id, s.store.update(recordType(fields), id, fields, nil)
+		}
+	case http.MethodDelete:
+		req.AllOrNone, _ = strconv.ParseBool(query.Get("allOrNone"))
+
+		var ids []string
+		if len(query.Get("ids")) > 0 {
+			ids = strings.Split(query.Get("ids"), ",")
+		}
+		for range ids {
+			req.Records = append(req.Records, nil)
+		}
+
+		write = func(index int) (string, error) {
+			id := ids[index]
+			return id, s.store.remove(s.store.typeOf(id), id)
+		}
+	default:
+		return methodNotAllowed(method)
+	}
+
+	var snapshot map[string]map[string]*record
+	if req.AllOrNone {
+		snapshot = s.store.snapshot()
+	}
+
+	results := make([]saveResult, len(req.Records))
+	failed := false
+
+	for i := range req.Records {
+		id, err := write(i)
+		if err != nil {
+			failed = true
+			results[i] = saveResult{Errors: []simpleforce.SaveError{saveError(err)}}
+			if method != http.MethodPost {
+				results[i].ID = id
+			}
+			continue
+		}
+
+		results[i] = saveResult{ID: id, Success: true, Errors: []simpleforce.SaveError{}}
+	}
+
+	if failed && req.AllOrNone {
+		s.store.restore(snapshot)
+
+		for i := range results {
+			if results[i].Success {
+				results[i] = saveResult{Errors: []simpleforce.SaveError{{
+					StatusCode: "ALL_OR_NONE_OPERATION_ROLLED_BACK",
+					Message:    "Record rolled back because not all records were valid and the request was using AllOrNone header",
+					Fields:     []string{},
+				}}}
+			}
+		}
+	}
+
+	return jsonResponse(http.StatusOK, results)
+}
+
+// retrieveCollection serves GET composite/sobjects/{type}, returning null for records that don't exist.
+func (s *Server) retrieveCollection(typeName string, query url.Values) *response {
+	if len(query.Get("ids")) == 0 || len(query.Get("fields")) == 0 {
+		return errorResponse(newAPIError(http.StatusBadRequest, "MISSING_ARGUMENT", "ids and fields are required"))
+	}
+
+	opts := &simpleforce.GetOptions{Fields: strings.Split(query.Get("fields"), ",")}
+
+	var records []*simpleforce.SObject
+	for _, id := range strings.Split(query.Get("ids"), ",") {
+		sobj, _, err := s.store.get(typeName, id, opts)
+		if err != nil {
+			sobj = nil
+		}
+
+		records = append(records, sobj)
+	}
+
+	return jsonResponse(http.StatusOK, records)
+}
+
+// recordType returns the type in the attributes of a record of a request body.
+func recordType(fields map[string]interface{}) string {
+	attributes, _ := fields["attributes"].(map[string]interface{})
+	typeName, _ := attributes["type"].(string)
+
+	return typeName
+}
+
+// compositeRequest is the body of a composite request.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_composite.htm
+type compositeRequest struct {
+	AllOrNone        bool           `json:"allOrNone"`
+	CompositeRequest []compositeSub `json:"compositeRequest"`
+}
+
+type compositeSub struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	ReferenceID string            `json:"referenceId"`
+	Body        json.RawMessage   `json:"body"`
+	HTTPHeaders map[string]string `json:"httpHeaders"`
+}
+
+type compositeSubResponse struct {
+	Body           json.RawMessage   `json:"body"`
+	HTTPHeaders    map[string]string `json:"httpHeaders"`
+	HTTPStatusCode int               `json:"httpStatusCode"`
+	ReferenceID    string            `json:"referenceId"`
+}
+
+// referencePattern matches references to the results of earlier subrequests, such as @{newAccount.id}.
+var referencePattern = regexp.MustCompile(`@\{([^}]+)\}`)
+
+// composite serves composite requests. Subrequests are run in order and may reference the results of earlier ones.
+// If allOrNone is set and a subrequest fails, all writes are rolled back.
+func (s *Server) composite(body []byte) *response {
+	req := &compositeRequest{}
+	err := json.Unmarshal(body, req)
+	if err != nil {
+		return errorResponse(newAPIError(http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error()))
+	}
+
+	var snapshot map[string]map[string]*record
+	if req.AllOrNone {
+		snapshot = s.store.snapshot()
+	}
+
+	results := make(map[string]interface{})
+	responses := make([]compositeSubResponse, 0, len(req.CompositeRequest))
+	failed := false
+
+	for _, sub := range req.CompositeRequest {
+		var res *response
+		if failed && req.AllOrNone {
+			res = errorResponse(processingHalted("The transaction was rolled back since another operation in the same transaction failed."))
+		} else {
+			res = s.compositeSub(sub, results)
+		}
+
+		if res.statusCode >= http.StatusBadRequest {
+			failed = true
+		} else if len(res.body) > 0 {
+			var result interface{}
+			if json.Unmarshal(res.body, &result) == nil {
+				results[sub.ReferenceID] = result
+			}
+		}
+
+		responses = append(responses, compositeResponse(sub.ReferenceID, res))
+	}
+
+	if failed && req.AllOrNone {
+		s.store.restore(snapshot)
+
+		for i, sub := range req.CompositeRequest {
+			if responses[i].HTTPStatusCode < http.StatusBadRequest {
+				res := errorResponse(processingHalted("The transaction was rolled back since another operation in the same transaction failed."))
+				responses[i] = compositeResponse(sub.ReferenceID, res)
+			}
+		}
+	}
+
+	return jsonResponse(http.StatusOK, map[string]interface{}{"compositeResponse": responses})
+}
+
+func processingHalted(message string) *apiError {
+	return newAPIError(http.StatusBadRequest, "PROCESSING_HALTED", message)
+}
+
+func compositeResponse(referenceID string, res *response) compositeSubResponse {
+	sub := compositeSubResponse{
+		Body:           json.RawMessage("null"),
+		HTTPHeaders:    map[string]string{},
+		HTTPStatusCode: res.statusCode,
+		ReferenceID:    referenceID,
+	}
+
+	if len(res.body) > 0 {
+		sub.Body = res.body
+		if !json.Valid(res.body) {
+			sub.Body, _ = json.Marshal(string(res.body))
+		}
+	}
+
+	for key := range res.header {
+		if key != "Content-Type" {
+			sub.HTTPHeaders[key] = res.header.Get(key)
+		}
+	}
+
+	return sub
+}
+
+// compositeSub runs a subrequest of a composite request, after replacing its references with the results of
+// earlier subrequests.
+func (s *Server) compositeSub(sub compositeSub, results map[string]interface{}) *response {
+	var refErr error
+
+	resolve := func(escape func(string) string) func(string) string {
+		return func(ref string) string {
+			value, err := resolveReference(referencePattern.FindStringSubmatch(ref)[1], results)
+			if err != nil {
+				refErr = err
+				return ref
+			}
+			return escape(fmt.Sprint(value))
+		}
+	}
+
+	rawURL := referencePattern.ReplaceAllStringFunc(sub.URL, resolve(url.PathEscape))
+	body := referencePattern.ReplaceAllStringFunc(string(sub.Body), resolve(jsonEscape))
+	if refErr != nil {
+		return errorResponse(processingHalted(refErr.Error()))
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errorResponse(newAPIError(http.StatusBadRequest, "INVALID_URL", err.Error()))
+	}
+
+	path, ok := resourcePath(u.EscapedPath())
+	if !ok || path == "composite" {
+		return errorResponse(notFound())
+	}
+
+	header := http.Header{}
+	for key, value := range sub.HTTPHeaders {
+		header.Set(key, value)
+	}
+
+	return s.route(strings.ToUpper(sub.Method), path, u.Query(), header, []byte(body))
+}
+
+// jsonEscape escapes s for use inside a JSON string.
+func jsonEscape(s string) string {
+	escaped, _ := json.Marshal(s)
+	return string(escaped[1 : len(escaped)-1])
+}
+
+// resolveReference returns the value a reference such as newAccount.id or contacts.records[0].Id points to.
+func resolveReference(ref string, results map[string]interface{}) (interface{}, error) {
+	parts := strings.Split(ref, ".")
+
+	value, ok := results[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("Invalid reference specified. No value for %s found in %s", ref, parts[0])
+	}
+
+	for _, part := range parts[1:] {
+		name, index := part, -1
+		if i := strings.Index(part, "["); i >= 0 && strings.HasSuffix(part, "]") {
+			n, err := strconv.Atoi(part[i+1 : len(part)-1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid reference specified: %s", ref)
+			}
+			name, index = part[:i], n
+		}
+
+		fields, _ := value.(map[string]interface{})
+		value, ok = fields[name]
+
+		if ok && index >= 0 {
+			list, _ := value.([]interface{})
+			ok = index < len(list)
+			if ok {
+				value = list[index]
+			}
+		}
+
+		if !ok || value == nil {
+			return nil, fmt.Errorf("Invalid reference specified. No value for %s found in %s", ref, parts[0])
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,358 @@
+package simpleforcetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_CRUD(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	server := NewServer(nil)
+	defer server.Close()
+
+	server.Store().SetExternalIDFields("Contact", "External_ID__c")
+	client := server.NewHTTPClient()
+
+	contact := simpleforce.NewSObject("Contact").Set("LastName", "Smith").Set("External_ID__c", "c-1")
+	err := client.CreateSObject(ctx, contact, nil, false, nil)
+	assert.NoError(err)
+	assert.Equal("003", simpleforce.ID(contact.ID()).KeyPrefix())
+
+	fetched := simpleforce.NewSObject("Contact").SetID(contact.ID())
+	res, err := client.GetSObjectWithOptions(ctx, fetched, nil)
+	assert.NoError(err)
+	assert.Equal("Smith", fetched.StringField("LastName"))
+	assert.Contains(fetched.AttributesField().URL, contact.ID())
+	assert.NotEmpty(res.ETag)
+	assert.False(res.LastModified.IsZero())
+
+	res2, err := client.GetSObjectWithOptions(ctx, simpleforce.NewSObject("Contact").SetID(contact.ID()), &simpleforce.GetOptions{
+		IfNoneMatch: res.ETag,
+	})
+	assert.NoError(err)
+	assert.True(res2.NotModified)
+
+	fetched.Set("LastName", "Jones")
+	err = client.UpdateSObjectIfUnmodified(ctx, fetched, nil, nil, &simpleforce.Precondition{IfMatch: res.ETag})
+	assert.NoError(err)
+
+	err = client.UpdateSObjectIfUnmodified(ctx, fetched, nil, nil, &simpleforce.Precondition{IfMatch: res.ETag})
+	assert.True(errors.As(err, &simpleforce.ErrPreconditionFailed{}))
+
+	upsert, err := client.UpsertSObject(ctx, simpleforce.NewSObject("Contact").Set("FirstName", "Ann"), "External_ID__c", "c-1", nil, false, nil)
+	assert.NoError(err)
+	assert.False(upsert.Created)
+	assert.Equal(contact.ID(), upsert.ID)
+
+	upsert, err = client.UpsertSObject(ctx, simpleforce.NewSObject("Contact").Set("LastName", "Brown"), "External_ID__c", "c/2", nil, false, nil)
+	assert.NoError(err)
+	assert.True(upsert.Created)
+
+	byExternalID := simpleforce.NewSObject("Contact")
+	err = client.GetSObjectByExternalID(ctx, byExternalID, "External_ID__c", "c-1")
+	assert.NoError(err)
+	assert.Equal("Ann", byExternalID.StringField("FirstName"))
+	assert.Equal("Jones", byExternalID.StringField("LastName"))
+
+	err = client.DeleteSObjectByExternalID(ctx, simpleforce.NewSObject("Contact"), "External_ID__c", "c/2")
+	assert.NoError(err)
+
+	err = client.DeleteSObject(ctx, fetched)
+	assert.NoError(err)
+	assert.Empty(server.Store().Records("Contact"))
+
+	err = client.GetSObject(ctx, fetched)
+	assert.Equal(simpleforce.ErrAPI{StatusCode: 404, ErrorCode: "NOT_FOUND", Message: "The requested resource does not exist"}, err)
+}
+
+func TestServer_Query(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	server := NewServer(nil)
+	defer server.Close()
+
+	server.Store().SetPageSize(2)
+	client := server.NewHTTPClient()
+
+	for _, name := range []string{"Acme", "Globex", "Initech"} {
+		assert.NoError(client.CreateSObject(ctx, simpleforce.NewSObject("Account").Set("Name", name), nil, false, nil))
+	}
+
+	var names []string
+	var nextRecordsURL string
+	for {
+		result, err := client.Query(ctx, "SELECT Name FROM Account ORDER BY Name", nextRecordsURL)
+		assert.NoError(err)
+		assert.Equal(3, result.TotalSize)
+
+		for _, record := range result.Records {
+			names = append(names, record.StringField("Name"))
+		}
+
+		if result.Done {
+			break
+		}
+		nextRecordsURL = result.NextRecordsURL
+	}
+	assert.Equal([]string{"Acme", "Globex", "Initech"}, names)
+
+	_, err := client.Query(ctx, "SELECT FROM Account", "")
+	assert.Error(err)
+	assert.Contains(err.Error(), "MALFORMED_QUERY")
+}
+
+func TestServer_DownloadFile(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	server := NewServer(nil)
+	defer server.Close()
+
+	client := server.NewHTTPClient()
+
+	version := simpleforce.NewSObject("ContentVersion").Set("VersionData", "hello")
+	assert.NoError(client.CreateSObject(ctx, version, nil, false, nil))
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	assert.NoError(client.DownloadFile(ctx, version.ID(), path))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal("hello", string(content))
+}
+
+func TestServer_faults(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	server := NewServer(nil)
+	defer server.Close()
+
+	client := server.NewHTTPClient()
+
+	expired := SessionExpired()
+	expired.Count = 1
+	server.AddFault(expired)
+
+	err := client.CreateSObject(ctx, simpleforce.NewSObject("Account"), nil, false, nil)
+	assert.Error(err)
+	assert.Contains(err.Error(), "INVALID_SESSION_ID")
+
+	err = client.CreateSObject(ctx, simpleforce.NewSObject("Account"), nil, false, nil)
+	assert.NoError(err)
+
+	lock := RowLock()
+	lock.Method = http.MethodPatch
+	lock.Path = "sobjects/Account"
+	server.AddFault(lock)
+
+	account := server.Store().Records("Account")[0].Set("Name", "Acme")
+	err = client.UpdateSObject(ctx, account, nil, nil)
+	assert.Error(err)
+	assert.Contains(err.Error(), "UNABLE_TO_LOCK_ROW")
+
+	_, err = client.Query(ctx, "SELECT Id FROM Account", "")
+	assert.NoError(err)
+
+	server.ClearFaults()
+	server.AddFault(Fault{Latency: time.Second})
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	_, err = client.Query(timeoutCtx, "SELECT Id FROM Account", "")
+	assert.Error(err)
+
+	server.ClearFaults()
+	server.SetAPILimit(server.APIUsage())
+
+	res, err := http.Get(server.URL + "/services/data/v43.0/sobjects")
+	assert.NoError(err)
+	res.Body.Close()
+	assert.Equal(http.StatusForbidden, res.StatusCode)
+	assert.Regexp(`^api-usage=\d+/\d+$`, res.Header.Get("Sforce-Limit-Info"))
+}
+
+func doJSON(t *testing.T, server *Server, method, path string, body interface{}, v interface{}) int {
+	data, err := json.Marshal(body)
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(method, server.URL+"/services/data/v43.0/"+path, bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	res, err := server.Client().Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(v))
+
+	return res.StatusCode
+}
+
+func TestServer_composite(t *testing.T) {
+	assert := assert.New(t)
+
+	server := NewServer(nil)
+	defer server.Close()
+
+	var result struct {
+		CompositeResponse []struct {
+			Body           json.RawMessage
+			HTTPStatusCode int
+			ReferenceID    string
+		}
+	}
+
+	status := doJSON(t, server, http.MethodPost, "composite", map[string]interface{}{
+		"allOrNone": true,
+		"compositeRequest": []map[string]interface{}{{
+			"method":      "POST",
+			"url":         "/services/data/v43.0/sobjects/Account",
+			"referenceId": "newAccount",
+			"body":        map[string]interface{}{"Name": "Acme"},
+		}, {
+			"method":      "POST",
+			"url":         "/services/data/v43.0/sobjects/Contact",
+			"referenceId": "newContact",
+			"body":        map[string]interface{}{"LastName": "Smith", "AccountId": "@{newAccount.id}"},
+		}, {
+			"method":      "GET",
+			"url":         "/services/data/v43.0/sobjects/Account/@{newAccount.id}?fields=Name",
+			"referenceId": "account",
+		}},
+	}, &result)
+	assert.Equal(http.StatusOK, status)
+	assert.Len(result.CompositeResponse, 3)
+	assert.Equal(http.StatusCreated, result.CompositeResponse[0].HTTPStatusCode)
+	assert.Equal(http.StatusOK, result.CompositeResponse[2].HTTPStatusCode)
+	assert.Contains(string(result.CompositeResponse[2].Body), "Acme")
+
+	account := server.Store().Records("Account")[0]
+	assert.Equal(account.ID(), server.Store().Records("Contact")[0].StringField("AccountId"))
+
+	status = doJSON(t, server, http.MethodPost, "composite", map[string]interface{}{
+		"allOrNone": true,
+		"compositeRequest": []map[string]interface{}{{
+			"method":      "PATCH",
+			"url":         "/services/data/v43.0/sobjects/Account/" + account.ID(),
+			"referenceId": "update",
+			"body":        map[string]interface{}{"Name": "Acme Corp"},
+		}, {
+			"method":      "DELETE",
+			"url":         "/services/data/v43.0/sobjects/Account/001000000000099AAA",
+			"referenceId": "delete",
+		}},
+	}, &result)
+	assert.Equal(http.StatusOK, status)
+	assert.Equal(http.StatusBadRequest, result.CompositeResponse[0].HTTPStatusCode)
+	assert.Contains(string(result.CompositeResponse[0].Body), "PROCESSING_HALTED")
+	assert.Equal(http.StatusNotFound, result.CompositeResponse[1].HTTPStatusCode)
+	assert.Equal("Acme", server.Store().Records("Account")[0].StringField("Name"))
+}
+
+func TestServer_collections(t *testing.T) {
+	assert := assert.New(t)
+
+	server := NewServer(nil)
+	defer server.Close()
+
+	var results []struct {
+		ID      string
+		Success bool
+		Errors  []simpleforce.SaveError
+	}
+
+	doJSON(t, server, http.MethodPost, "composite/sobjects", map[string]interface{}{
+		"records": []map[string]interface{}{
+			{"attributes": map[string]string{"type": "Account"}, "Name": "Acme"},
+			{"attributes": map[string]string{"type": "Account"}, "Name": "Globex"},
+			{"attributes": map[string]string{"type": "Account"}, "Id": "001000000000001AAA"},
+		},
+	}, &results)
+	assert.Len(results, 3)
+	assert.True(results[0].Success)
+	assert.True(results[1].Success)
+	assert.False(results[2].Success)
+	assert.Equal("INVALID_FIELD", results[2].Errors[0].StatusCode)
+	assert.Len(server.Store().Records("Account"), 2)
+
+	ids := []string{results[0].ID, results[1].ID}
+
+	doJSON(t, server, http.MethodPatch, "composite/sobjects", map[string]interface{}{
+		"allOrNone": true,
+		"records": []map[string]interface{}{
+			{"attributes": map[string]string{"type": "Account"}, "Id": ids[0], "Name": "Acme Corp"},
+			{"attributes": map[string]string{"type": "Account"}, "Name": "No ID"},
+		},
+	}, &results)
+	assert.Equal("ALL_OR_NONE_OPERATION_ROLLED_BACK", results[0].Errors[0].StatusCode)
+	assert.Equal("MISSING_ARGUMENT", results[1].Errors[0].StatusCode)
+	assert.Equal("Acme", server.Store().Records("Account")[0].StringField("Name"))
+
+	var records []*simpleforce.SObject
+	doJSON(t, server, http.MethodGet, "composite/sobjects/Account?fields=Name&ids="+ids[1]+",001000000000099AAA", nil, &records)
+	assert.Len(records, 2)
+	assert.Equal("Globex", records[0].StringField("Name"))
+	assert.Nil(records[1])
+
+	doJSON(t, server, http.MethodDelete, "composite/sobjects?ids="+ids[0]+","+ids[1], nil, &results)
+	assert.True(results[0].Success)
+	assert.True(results[1].Success)
+	assert.Empty(server.Store().Records("Account"))
+}
+
+func TestServer_replication(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	// The client checks that windows start within the 30 days salesforce retains changes on its own clock, so the
+	// changes are made in the past hours.
+	now := time.Now().UTC().Truncate(time.Second).Add(-2 * time.Hour)
+
+	server := NewServer(nil)
+	defer server.Close()
+
+	server.Store().SetClock(func() time.Time { return now })
+	client := server.NewHTTPClient()
+
+	kept := simpleforce.NewSObject("Lead").Set("LastName", "Smith")
+	assert.NoError(client.CreateSObject(ctx, kept, nil, false, nil))
+	removed := simpleforce.NewSObject("Lead").Set("LastName", "Jones")
+	assert.NoError(client.CreateSObject(ctx, removed, nil, false, nil))
+
+	now = now.Add(time.Hour)
+	assert.NoError(client.DeleteSObject(ctx, removed))
+
+	start := now.Add(-15 * 24 * time.Hour)
+	end := now.Add(time.Hour)
+
+	updated, err := client.GetUpdated(ctx, "Lead", start, end)
+	assert.NoError(err)
+	assert.Equal([]string{kept.ID()}, updated.IDs)
+	assert.True(end.Equal(updated.LatestDateCovered))
+
+	deleted, err := client.GetDeleted(ctx, "Lead", start, end)
+	assert.NoError(err)
+	assert.Len(deleted.DeletedRecords, 1)
+	assert.Equal(removed.ID(), deleted.DeletedRecords[0].ID)
+	assert.True(now.Equal(deleted.DeletedRecords[0].DeletedDate))
+	assert.True(start.Equal(deleted.EarliestDateAvailable))
+
+	var v []map[string]interface{}
+	status := doJSON(t, server, http.MethodGet, "sobjects/Lead/updated/?start=2021-01-01T00:00:00Z&end=2021-03-01T00:00:00Z", nil, &v)
+	assert.Equal(http.StatusBadRequest, status)
+	assert.Equal("INVALID_REPLICATION_DATE", v[0]["errorCode"])
+}
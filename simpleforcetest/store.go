@@ -0,0 +1,875 @@
+package simpleforcetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce"
+)
+
+// dateTimeLayout is the format salesforce uses for datetime fields.
+const dateTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// standardKeyPrefixes are the key prefixes of common standard SObjects. Other SObjects get a generated prefix.
+var standardKeyPrefixes = map[string]string{
+	"Account":        "001",
+	"Contact":        "003",
+	"User":           "005",
+	"Opportunity":    "006",
+	"Note":           "002",
+	"Attachment":     "00P",
+	"Lead":           "00Q",
+	"Task":           "00T",
+	"Event":          "00U",
+	"Case":           "500",
+	"Campaign":       "701",
+	"ContentVersion": "068",
+}
+
+// systemFields are maintained by the Client and ignored when writing records.
+var systemFields = map[string]bool{
+	"id":               true,
+	"createddate":      true,
+	"createdbyid":      true,
+	"lastmodifieddate": true,
+	"lastmodifiedbyid": true,
+	"systemmodstamp":   true,
+	"isdeleted":        true,
+}
+
+// apiError is an error response of the salesforce REST API. The Client converts it to the error HTTPClient returns
+// for the response, and the Server writes it as the response.
+type apiError struct {
+	statusCode int
+	errorCode  string
+	message    string
+	// urls are the records matching an external ID, for 300 Multiple Choices responses.
+	urls []string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.statusCode, e.errorCode, e.message)
+}
+
+// body returns the response body of the error.
+func (e *apiError) body() []byte {
+	if e.statusCode == 300 {
+		body, _ := json.Marshal(e.urls)
+		return body
+	}
+
+	body, _ := json.Marshal([]map[string]string{{"errorCode": e.errorCode, "message": e.message}})
+	return body
+}
+
+func newAPIError(statusCode int, errorCode, message string) *apiError {
+	return &apiError{
+		statusCode: statusCode,
+		errorCode:  errorCode,
+		message:    message,
+	}
+}
+
+// notFound creates the error salesforce returns for records that don't exist.
+func notFound() *apiError {
+	return newAPIError(404, "NOT_FOUND", "The requested resource does not exist")
+}
+
+// APIError creates the error HTTPClient returns for a salesforce error response with the given status code, error
+// code and message, such as APIError(400, "REQUIRED_FIELD_MISSING", "Required fields are missing: [Name]").
+func APIError(statusCode int, errorCode, message string) error {
+	return clientError(newAPIError(statusCode, errorCode, message))
+}
+
+// clientError converts errors of the store to the errors HTTPClient returns.
+func clientError(err error) error {
+	e, ok := err.(*apiError)
+	if !ok {
+		return err
+	}
+
+	switch e.statusCode {
+	case 300:
+		multipleChoices := simpleforce.ErrMultipleChoices{}
+		for _, u := range e.urls {
+			multipleChoices.IDs = append(multipleChoices.IDs, path.Base(u))
+		}
+
+		return multipleChoices
+
+	case 412:
+		return simpleforce.ErrPreconditionFailed{Message: e.message}
+
+	default:
+		return simpleforce.ErrAPI{StatusCode: e.statusCode, ErrorCode: e.errorCode, Message: e.message}
+	}
+}
+
+// saveError converts an error to the error reported for a record of a collection, composite or publish request.
+func saveError(err error) simpleforce.SaveError {
+	e, ok := err.(*apiError)
+	if !ok {
+		e = newAPIError(500, "UNKNOWN_EXCEPTION", err.Error())
+	}
+
+	return simpleforce.SaveError{
+		StatusCode: e.errorCode,
+		Message:    e.message,
+		Fields:     []string{},
+	}
+}
+
+// record is a stored SObject.
+type record struct {
+	typeName string
+	fields   map[string]interface{}
+	version  int
+	modified time.Time
+}
+
+func (r *record) id() string {
+	id, _ := r.fields["Id"].(string)
+	return id
+}
+
+func (r *record) etag() string {
+	return strconv.Quote(fmt.Sprintf("%s-%d", r.id(), r.version))
+}
+
+// sobject returns a copy of the record as an SObject.
+func (r *record) sobject() *simpleforce.SObject {
+	sobj := r.stub()
+	for key, value := range copyValue(r.fields).(map[string]interface{}) {
+		(*sobj)[key] = value
+	}
+
+	return sobj
+}
+
+// stub returns an SObject with only the attributes and ID of the record.
+func (r *record) stub() *simpleforce.SObject {
+	return &simpleforce.SObject{
+		"attributes": r.attributes(),
+		"Id":         r.id(),
+	}
+}
+
+func (r *record) attributes() simpleforce.SObjectAttributes {
+	return simpleforce.SObjectAttributes{
+		Type: r.typeName,
+		URL:  fmt.Sprintf("/services/data/%s/sobjects/%s/%s", apiVersion, r.typeName, r.id()),
+	}
+}
+
+// copy returns a deep copy of the record.
+func (r *record) copy() *record {
+	copied := *r
+	copied.fields = copyValue(r.fields).(map[string]interface{})
+
+	return &copied
+}
+
+// copyValue deep copies a JSON value.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = copyValue(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = copyValue(value)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// findKey returns the key of fields matching name case-insensitively, as salesforce field names are.
+func findKey(fields map[string]interface{}, name string) (string, bool) {
+	if _, ok := fields[name]; ok {
+		return name, true
+	}
+
+	for key := range fields {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// lookup returns the value of a field, matching its name case-insensitively.
+func lookup(fields map[string]interface{}, name string) (interface{}, bool) {
+	key, ok := findKey(fields, name)
+	if !ok {
+		return nil, false
+	}
+
+	return fields[key], true
+}
+
+// normalize round-trips fields through JSON, so stored values have the types of decoded salesforce responses.
+func normalize(fields map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized map[string]interface{}
+	err = json.Unmarshal(data, &normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// sorted returns the records of an SObject type sorted by ID. The caller must hold c.mu.
+func (c *Client) sorted(typeName string) []*record {
+	records := make([]*record, 0, len(c.records[typeName]))
+	for _, rec := range c.records[typeName] {
+		records = append(records, rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].id() < records[j].id()
+	})
+
+	return records
+}
+
+// keyPrefix returns the key prefix of an SObject type, assigning one if needed. The caller must hold c.mu.
+func (c *Client) keyPrefix(typeName string) string {
+	if prefix, ok := c.prefixes[typeName]; ok {
+		return prefix
+	}
+
+	prefix, ok := standardKeyPrefixes[typeName]
+	if !ok {
+		prefix = fmt.Sprintf("a%02d", len(c.prefixes))
+	}
+
+	c.prefixes[typeName] = prefix
+
+	return prefix
+}
+
+// newID generates the next 18 character ID of an SObject type. The caller must hold c.mu.
+func (c *Client) newID(typeName string) string {
+	c.counters[typeName]++
+
+	id, _ := simpleforce.ParseID(fmt.Sprintf("%s%012d", c.keyPrefix(typeName), c.counters[typeName]))
+
+	return id.String()
+}
+
+// timestamp returns the time of a write, later than the previous write of rec at the one second resolution of HTTP
+// dates, so conditional requests can tell the versions apart. The caller must hold c.mu.
+func (c *Client) timestamp(rec *record) time.Time {
+	now := c.now().UTC().Truncate(time.Millisecond)
+
+	if !now.Truncate(time.Second).After(rec.modified.Truncate(time.Second)) {
+		now = rec.modified.Truncate(time.Second).Add(time.Second)
+	}
+
+	return now
+}
+
+// find returns the record of an SObject type with the given ID. The caller must hold c.mu.
+func (c *Client) find(typeName, id string) *record {
+	return c.records[typeName][simpleforce.ID(id).To18().String()]
+}
+
+// findByExternalID returns the single record of an SObject type whose idField equals idValue, or nil if there is
+// none. An error with status 300 is returned if several records match. The caller must hold c.mu.
+func (c *Client) findByExternalID(typeName, idField, idValue string) (*record, error) {
+	if len(typeName) == 0 || len(idField) == 0 || len(idValue) == 0 {
+		return nil, notFound()
+	}
+
+	if strings.EqualFold(idField, "Id") {
+		return c.find(typeName, idValue), nil
+	}
+
+	if !c.externalIDs[typeName][strings.ToLower(idField)] {
+		return nil, newAPIError(404, "NOT_FOUND", "Provided external ID field does not exist or is not accessible: "+idField)
+	}
+
+	var matches []*record
+	for _, rec := range c.sorted(typeName) {
+		value, _ := lookup(rec.fields, idField)
+		if value != nil && strings.EqualFold(fmt.Sprint(value), idValue) {
+			matches = append(matches, rec)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	default:
+		e := newAPIError(300, "MULTIPLE_CHOICES", "external ID matches multiple records")
+		for _, rec := range matches {
+			e.urls = append(e.urls, rec.attributes().URL)
+		}
+		return nil, e
+	}
+}
+
+// insert stores a new record with the given fields. The caller must hold c.mu.
+func (c *Client) insert(typeName string, fields map[string]interface{}) (*record, error) {
+	if len(typeName) == 0 {
+		return nil, notFound()
+	}
+
+	rec := &record{
+		typeName: typeName,
+		fields:   make(map[string]interface{}),
+	}
+
+	err := c.write(rec, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	id := c.newID(typeName)
+	rec.fields["Id"] = id
+	rec.fields["CreatedDate"] = rec.fields["LastModifiedDate"]
+	rec.fields["CreatedById"] = c.userID
+	rec.fields["IsDeleted"] = false
+
+	if c.records[typeName] == nil {
+		c.records[typeName] = make(map[string]*record)
+	}
+	c.records[typeName][id] = rec
+
+	return rec, nil
+}
+
+// write applies fields to rec and bumps its version. The caller must hold c.mu.
+func (c *Client) write(rec *record, fields map[string]interface{}) error {
+	normalized, err := normalize(fields)
+	if err != nil {
+		return newAPIError(400, "JSON_PARSER_ERROR", err.Error())
+	}
+
+	for key, value := range normalized {
+		if systemFields[strings.ToLower(key)] || key == "attributes" {
+			continue
+		}
+
+		if existing, ok := findKey(rec.fields, key); ok {
+			key = existing
+		}
+
+		rec.fields[key] = value
+	}
+
+	rec.modified = c.timestamp(rec)
+	rec.version++
+
+	modified := rec.modified.Format(dateTimeLayout)
+	rec.fields["LastModifiedDate"] = modified
+	rec.fields["SystemModstamp"] = modified
+	rec.fields["LastModifiedById"] = c.userID
+
+	return nil
+}
+
+// snapshot returns a deep copy of the stored records, to be restored if an all-or-none operation fails.
+func (c *Client) snapshot() map[string]map[string]*record {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := make(map[string]map[string]*record, len(c.records))
+	for typeName, records := range c.records {
+		snapshot[typeName] = make(map[string]*record, len(records))
+		for id, rec := range records {
+			snapshot[typeName][id] = rec.copy()
+		}
+	}
+
+	return snapshot
+}
+
+// restore replaces the stored records with a snapshot.
+func (c *Client) restore(snapshot map[string]map[string]*record) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records = snapshot
+
+	// Deletions rolled back by the restore are forgotten.
+	for typeName, records := range snapshot {
+		for id := range records {
+			delete(c.deleted[typeName], id)
+		}
+	}
+}
+
+// queryPage runs a query or returns the next page of a previous query.
+func (c *Client) queryPage(query, nextRecordsURL string) (*simpleforce.QueryResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(nextRecordsURL) > 0 {
+		cur, ok := c.cursors[nextRecordsURL]
+		if !ok {
+			return nil, newAPIError(400, "INVALID_QUERY_LOCATOR", "invalid query locator")
+		}
+		delete(c.cursors, nextRecordsURL)
+
+		return c.page(cur.records, cur.total), nil
+	}
+
+	records, total, err := c.query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.page(records, total), nil
+}
+
+// cursor holds the remaining records of a paginated query.
+type cursor struct {
+	records []*simpleforce.SObject
+	total   int
+}
+
+// page returns the first page of records, keeping the rest for the next page. The caller must hold c.mu.
+func (c *Client) page(records []*simpleforce.SObject, total int) *simpleforce.QueryResult {
+	result := &simpleforce.QueryResult{
+		TotalSize: total,
+		Done:      true,
+		Records:   records,
+	}
+
+	if c.pageSize > 0 && len(records) > c.pageSize {
+		c.locators++
+
+		result.Records = records[:c.pageSize]
+		result.Done = false
+		result.NextRecordsURL = fmt.Sprintf("/services/data/%s/query/01g%012d-%d", apiVersion, c.locators, total-len(records)+c.pageSize)
+
+		c.cursors[result.NextRecordsURL] = &cursor{records[c.pageSize:], total}
+	}
+
+	if result.Records == nil {
+		result.Records = []*simpleforce.SObject{}
+	}
+
+	return result
+}
+
+// search runs a parameterized search.
+func (c *Client) search(req *simpleforce.SearchRequest) (*simpleforce.SearchResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req == nil || len(req.Query) == 0 {
+		return nil, newAPIError(400, "INVALID_SEARCH", "search term must be longer than one character")
+	}
+
+	term := strings.ToLower(unescapeSOSL(req.Query))
+
+	sobjects := req.SObjects
+	if len(sobjects) == 0 {
+		types := make([]string, 0, len(c.records))
+		for typeName := range c.records {
+			types = append(types, typeName)
+		}
+		sort.Strings(types)
+
+		for _, typeName := range types {
+			sobjects = append(sobjects, simpleforce.SearchSObject{Name: typeName})
+		}
+	}
+
+	result := &simpleforce.SearchResult{Records: []*simpleforce.SObject{}}
+
+	for _, so := range sobjects {
+		limit := so.Limit
+		if limit == 0 {
+			limit = req.DefaultLimit
+		}
+
+		fields := so.Fields
+		if len(fields) == 0 {
+			fields = req.Fields
+		}
+
+		matched := 0
+		for _, rec := range c.sorted(so.Name) {
+			if limit > 0 && matched == limit {
+				break
+			}
+			if req.OverallLimit > 0 && len(result.Records) == req.OverallLimit {
+				return result, nil
+			}
+
+			if !containsTerm(rec.fields, term) {
+				continue
+			}
+			matched++
+
+			sobj := rec.stub()
+			for _, field := range fields {
+				value, _ := c.resolve(rec, field)
+				(*sobj)[field] = value
+			}
+			sobj.ClearChanges()
+
+			result.Records = append(result.Records, sobj)
+		}
+	}
+
+	return result, nil
+}
+
+// unescapeSOSL removes the backslashes escaping reserved characters in a search term.
+func unescapeSOSL(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+func containsTerm(fields map[string]interface{}, term string) bool {
+	for _, value := range fields {
+		if s, ok := value.(string); ok && strings.Contains(strings.ToLower(s), term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describe returns the metadata of an SObject type.
+func (c *Client) describe(typeName string) (*simpleforce.SObjectMeta, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta, ok := c.describes[typeName]
+	if !ok {
+		return nil, notFound()
+	}
+
+	copied := simpleforce.SObjectMeta(copyValue(map[string]interface{}(*meta)).(map[string]interface{}))
+
+	return &copied, nil
+}
+
+// describeGlobal lists the SObject types with records or metadata, with their key prefixes.
+func (c *Client) describeGlobal() *simpleforce.SObjectMeta {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	types := make(map[string]bool)
+	for typeName := range c.records {
+		types[typeName] = true
+	}
+	for typeName := range c.describes {
+		types[typeName] = true
+	}
+
+	names := make([]string, 0, len(types))
+	for typeName := range types {
+		names = append(names, typeName)
+	}
+	sort.Strings(names)
+
+	sobjects := make([]interface{}, 0, len(names))
+	for _, typeName := range names {
+		sobjects = append(sobjects, map[string]interface{}{
+			"name":      typeName,
+			"keyPrefix": c.keyPrefix(typeName),
+		})
+	}
+
+	return &simpleforce.SObjectMeta{"sobjects": sobjects}
+}
+
+// create stores a new record and returns its ID.
+func (c *Client) create(typeName string, fields map[string]interface{}) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec, err := c.insert(typeName, fields)
+	if err != nil {
+		return "", err
+	}
+
+	return rec.id(), nil
+}
+
+// get returns a copy of a record, limited to fields if any are given. The returned SObject is nil if opts makes the
+// request conditional and the record didn't change.
+func (c *Client) get(typeName, id string, opts *simpleforce.GetOptions) (*simpleforce.SObject, *simpleforce.GetResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec := c.find(typeName, id)
+	if rec == nil {
+		return nil, nil, notFound()
+	}
+
+	if opts == nil {
+		opts = &simpleforce.GetOptions{}
+	}
+
+	result := &simpleforce.GetResult{
+		ETag:         rec.etag(),
+		LastModified: rec.modified.Truncate(time.Second),
+	}
+
+	if (len(opts.IfNoneMatch) > 0 && opts.IfNoneMatch == result.ETag) ||
+		(!opts.IfModifiedSince.IsZero() && !result.LastModified.After(opts.IfModifiedSince.Truncate(time.Second))) {
+		result.NotModified = true
+		return nil, result, nil
+	}
+
+	if len(opts.Fields) == 0 {
+		return rec.sobject(), result, nil
+	}
+
+	sobj := rec.stub()
+	for _, field := range opts.Fields {
+		value, _ := lookup(rec.fields, field)
+		(*sobj)[field] = copyValue(value)
+	}
+
+	return sobj, result, nil
+}
+
+// getByExternalID returns a copy of the record matching an external ID.
+func (c *Client) getByExternalID(typeName, idField, idValue string) (*simpleforce.SObject, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec, err := c.findByExternalID(typeName, idField, idValue)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, notFound()
+	}
+
+	return rec.sobject(), nil
+}
+
+// update writes fields to a record if it satisfies precondition, which may be nil.
+func (c *Client) update(typeName, id string, fields map[string]interface{}, precondition *simpleforce.Precondition) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec := c.find(typeName, id)
+	if rec == nil {
+		return notFound()
+	}
+
+	if precondition != nil {
+		modified := !precondition.IfUnmodifiedSince.IsZero() &&
+			rec.modified.Truncate(time.Second).After(precondition.IfUnmodifiedSince.Truncate(time.Second))
+
+		if modified || (len(precondition.IfMatch) > 0 && precondition.IfMatch != rec.etag()) {
+			return newAPIError(412, "PRECONDITION_FAILED", "The record has been modified since the precondition")
+		}
+	}
+
+	return c.write(rec, fields)
+}
+
+// upsert updates the record matching an external ID, or creates one if there is none.
+func (c *Client) upsert(typeName, idField, idValue string, fields map[string]interface{}) (*simpleforce.UpsertResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec, err := c.findByExternalID(typeName, idField, idValue)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &simpleforce.UpsertResult{Success: true, Errors: []simpleforce.SaveError{}}
+
+	if rec == nil {
+		if strings.EqualFold(idField, "Id") {
+			return nil, notFound()
+		}
+
+		fields[idField] = idValue
+
+		rec, err = c.insert(typeName, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Created = true
+	} else {
+		err = c.write(rec, fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.ID = rec.id()
+
+	return result, nil
+}
+
+// remove deletes a record.
+func (c *Client) remove(typeName, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec := c.find(typeName, id)
+	if rec == nil {
+		return notFound()
+	}
+
+	c.markDeleted(rec)
+
+	return nil
+}
+
+// removeByExternalID deletes the record matching an external ID.
+func (c *Client) removeByExternalID(typeName, idField, idValue string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec, err := c.findByExternalID(typeName, idField, idValue)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return notFound()
+	}
+
+	c.markDeleted(rec)
+
+	return nil
+}
+
+// markDeleted removes rec from the stored records and records the time of its deletion for GetDeleted. The caller
+// must hold c.mu.
+func (c *Client) markDeleted(rec *record) {
+	delete(c.records[rec.typeName], rec.id())
+
+	if c.deleted[rec.typeName] == nil {
+		c.deleted[rec.typeName] = make(map[string]time.Time)
+	}
+	c.deleted[rec.typeName][rec.id()] = c.now().UTC().Truncate(time.Millisecond)
+}
+
+// checkReplicationWindow rejects the windows salesforce rejects for the updated and deleted resources.
+func (c *Client) checkReplicationWindow(start, end time.Time) error {
+	if end.Before(start) {
+		return newAPIError(400, "INVALID_REPLICATION_DATE", "startDate must chronologically precede endDate")
+	}
+	if start.Before(c.now().Add(-simpleforce.MaxReplicationWindow)) {
+		return newAPIError(400, "INVALID_REPLICATION_DATE", "startDate cannot be more than 30 days ago")
+	}
+	if end.Sub(start) > simpleforce.MaxReplicationWindow {
+		return newAPIError(400, "INVALID_REPLICATION_DATE", "Max date range is 30 days")
+	}
+
+	return nil
+}
+
+// updated returns the IDs of the records of typeName last modified in the window from start, inclusive, to end.
+func (c *Client) updated(typeName string, start, end time.Time) (*simpleforce.UpdatedResult, error) {
+	err := c.checkReplicationWindow(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &simpleforce.UpdatedResult{IDs: []string{}, LatestDateCovered: end.UTC()}
+	for _, rec := range c.sorted(typeName) {
+		if !rec.modified.Before(start) && rec.modified.Before(end) {
+			result.IDs = append(result.IDs, rec.id())
+		}
+	}
+
+	return result, nil
+}
+
+// deletedRecords returns the records of typeName deleted in the window from start, inclusive, to end. Deletions are
+// never purged, so the earliest date available is the start of the window.
+func (c *Client) deletedRecords(typeName string, start, end time.Time) (*simpleforce.DeletedResult, error) {
+	err := c.checkReplicationWindow(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &simpleforce.DeletedResult{
+		DeletedRecords:        []simpleforce.DeletedRecord{},
+		EarliestDateAvailable: start.UTC(),
+		LatestDateCovered:     end.UTC(),
+	}
+
+	for id, deletedDate := range c.deleted[typeName] {
+		if !deletedDate.Before(start) && deletedDate.Before(end) {
+			result.DeletedRecords = append(result.DeletedRecords, simpleforce.DeletedRecord{ID: id, DeletedDate: deletedDate})
+		}
+	}
+
+	sort.Slice(result.DeletedRecords, func(i, j int) bool {
+		return result.DeletedRecords[i].ID < result.DeletedRecords[j].ID
+	})
+
+	return result, nil
+}
+
+// versionData returns the content of a ContentVersion, stored in its VersionData field.
+func (c *Client) versionData(id string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec := c.find("ContentVersion", id)
+	if rec == nil {
+		return nil, notFound()
+	}
+
+	data, _ := lookup(rec.fields, "VersionData")
+	content, _ := data.(string)
+
+	return []byte(content), nil
+}
+
+// typeOf returns the SObject type of an ID by its key prefix, or an empty string if the prefix is unknown.
+func (c *Client) typeOf(id string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := simpleforce.ID(id).KeyPrefix()
+	for typeName, p := range c.prefixes {
+		if p == prefix {
+			return typeName
+		}
+	}
+
+	return ""
+}
@@ -1,13 +1,27 @@
 package simpleforce
 
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
 const (
-	sobjectAttributesKey = "attributes" // points to the attributes structure which should be common to all SObjects.
-	sobjectIDKey         = "Id"
+	sobjectAttributesKey       = "attributes" // points to the attributes structure which should be common to all SObjects.
+	sobjectIDKey               = "Id"
+	sobjectLastModifiedDateKey = "LastModifiedDate"
+	// sobjectChangesKey points to the change tracking state. Salesforce field names cannot begin with an underscore,
+	// so the key never collides with a field. It is skipped when the SObject is encoded or its fields are copied.
+	sobjectChangesKey = "_changes"
+
+	// dateTimeLayout is the format salesforce uses for datetime fields.
+	dateTimeLayout = "2006-01-02T15:04:05.000-0700"
 )
 
 var (
 	// When updating existing records, certain fields are read only and needs to be removed before submitted to Salesforce.
 	// Following list of fields are extracted from INVALID_FIELD_FOR_INSERT_UPDATE error message.
+	// The list is only used by clients without a Validator, which knows the read only fields of each SObject type.
 	blacklistedUpdateFields = []string{
 		"LastModifiedDate",
 		"LastReferencedDate",
@@ -60,9 +74,9 @@ func (s *SObject) Type() string {
 	return attributes.Type
 }
 
-// ID returns the external ID of the SObject.
+// ID returns the external ID of the SObject. Valid IDs are returned in their 18 character form.
 func (obj *SObject) ID() string {
-	return obj.StringField(sobjectIDKey)
+	return ID(obj.StringField(sobjectIDKey)).To18().String()
 }
 
 // StringField accesses a field in the SObject as string. Empty string is returned if the field doesn't exist.
@@ -111,12 +125,157 @@ func (s *SObject) AttributesField() *SObjectAttributes {
 }
 
 // Set indexes value into SObject instance with provided key. The same SObject pointer is returned to allow
-// chained access.
+// chained access. If the SObject is tracking changes, key is recorded as changed.
 func (s *SObject) Set(key string, value interface{}) *SObject {
 	(*s)[key] = value
+
+	if changes := s.changes(); changes != nil {
+		changes.set[key] = struct{}{}
+	}
+
 	return s
 }
 
+// SetNull sets the field with provided key to null, clearing its value on salesforce when the SObject is saved.
+func (s *SObject) SetNull(key string) *SObject {
+	return s.Set(key, nil)
+}
+
+// changeTracker holds the state needed to work out which fields of an SObject were modified.
+type changeTracker struct {
+	original map[string]interface{}
+	set      map[string]struct{}
+}
+
+// changes returns the change tracking state of the SObject, or nil if it isn't tracking changes.
+func (s *SObject) changes() *changeTracker {
+	changes, _ := (*s)[sobjectChangesKey].(*changeTracker)
+	return changes
+}
+
+// ClearChanges snapshots the current fields of the SObject and starts tracking changes against them. SObjects
+// retrieved with GetSObject or Query are already tracking changes.
+func (s *SObject) ClearChanges() {
+	changes := &changeTracker{
+		original: make(map[string]interface{}, len(*s)),
+		set:      make(map[string]struct{}),
+	}
+
+	for key, val := range *s {
+		if isMetadataKey(key) {
+			continue
+		}
+		// Nested values are copied so in-place edits of relationships and compound fields are detected. Strings and
+		// numbers are immutable and shared with the SObject.
+		changes.original[key] = copyValue(val)
+	}
+
+	(*s)[sobjectChangesKey] = changes
+}
+
+// copyValue deep copies the maps and slices of a decoded JSON value.
+func copyValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			c[key] = copyValue(elem)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, elem := range v {
+			c[i] = copyValue(elem)
+		}
+		return c
+	case SObject:
+		return SObject(copyValue(map[string]interface{}(v)).(map[string]interface{}))
+	case *SObject:
+		if v == nil {
+			return v
+		}
+		c := SObject(copyValue(map[string]interface{}(*v)).(map[string]interface{}))
+		return &c
+	default:
+		return val
+	}
+}
+
+// ChangedFields returns the sorted names of the fields modified since the SObject started tracking changes. A field
+// is modified if it was passed to Set, or if its value differs from the snapshot. Fields deleted from the SObject
+// are included and are cleared on salesforce when the SObject is updated. Nil is returned if the SObject isn't
+// tracking changes.
+func (s *SObject) ChangedFields() []string {
+	changes := s.changes()
+	if changes == nil {
+		return nil
+	}
+
+	changed := make(map[string]struct{})
+
+	for key := range changes.set {
+		if !isMetadataKey(key) {
+			changed[key] = struct{}{}
+		}
+	}
+
+	for key, val := range *s {
+		if isMetadataKey(key) {
+			continue
+		}
+
+		if origVal, ok := changes.original[key]; !ok || !reflect.DeepEqual(origVal, val) {
+			changed[key] = struct{}{}
+		}
+	}
+
+	for key := range changes.original {
+		if _, ok := (*s)[key]; !ok {
+			changed[key] = struct{}{}
+		}
+	}
+
+	fields := make([]string, 0, len(changed))
+	for key := range changed {
+		fields = append(fields, key)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// Fields returns a copy of the fields of the SObject, including its attributes and ID. Unlike ranging over the
+// SObject, the copy leaves out the change tracking state.
+func (s *SObject) Fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(*s))
+
+	for key, val := range *s {
+		if key == sobjectChangesKey {
+			continue
+		}
+		fields[key] = val
+	}
+
+	return fields
+}
+
+// MarshalJSON encodes the SObject without its change tracking state.
+func (s SObject) MarshalJSON() ([]byte, error) {
+	if _, ok := s[sobjectChangesKey]; !ok {
+		return json.Marshal(map[string]interface{}(s))
+	}
+
+	m := make(map[string]interface{}, len(s))
+	for key, val := range s {
+		if key == sobjectChangesKey {
+			continue
+		}
+		m[key] = val
+	}
+
+	return json.Marshal(m)
+}
+
 // setType sets the type, or name for the SObject.
 func (s *SObject) setType(typeName string) {
 	attributes := s.InterfaceField(sobjectAttributesKey)
@@ -133,29 +292,51 @@ func (s *SObject) setType(typeName string) {
 	}
 }
 
-// SetID sets the external ID for the SObject.
+// SetID sets the external ID for the SObject. Valid IDs are stored in their 18 character form.
 func (s *SObject) SetID(id string) *SObject {
-	(*s)[sobjectIDKey] = id
+	(*s)[sobjectIDKey] = ID(id).To18().String()
 
 	return s
 }
 
+// isMetadataKey reports whether key holds SObject metadata rather than a field to be written to salesforce.
+func isMetadataKey(key string) bool {
+	return key == sobjectAttributesKey ||
+		key == sobjectIDKey ||
+		key == sobjectChangesKey
+}
+
+// makeUpdateCopy copies the fields of an SObject to be sent in an update. If the SObject is tracking changes, only
+// the changed fields are copied, with deleted fields set to null.
+func (s *SObject) makeUpdateCopy(blacklistedFields []string) map[string]interface{} {
+	if s.changes() == nil {
+		return s.makeCopy(blacklistedFields)
+	}
+
+	stripped := make(map[string]interface{})
+
+	for _, key := range s.ChangedFields() {
+		stripped[key] = (*s)[key]
+	}
+
+	for _, key := range blacklistedFields {
+		delete(stripped, key)
+	}
+
+	return stripped
+}
+
 // makeCopy copies the fields of an SObject to a new map without metadata fields.
 func (s *SObject) makeCopy(blacklistedFields []string) map[string]interface{} {
 	stripped := make(map[string]interface{})
 
 	for key, val := range *s {
-		if key == sobjectAttributesKey ||
-			key == sobjectIDKey {
+		if isMetadataKey(key) {
 			continue
 		}
 		stripped[key] = val
 	}
 
-	for _, key := range blacklistedUpdateFields {
-		delete(stripped, key)
-	}
-
 	for _, key := range blacklistedFields {
 		delete(stripped, key)
 	}
@@ -1,6 +1,8 @@
 package simpleforce
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -46,3 +48,67 @@ func TestSObject_InterfaceField(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSObject_ChangedFields(t *testing.T) {
+	obj := NewSObject("Case").
+		SetID("object1").
+		Set("Subject", "foo").
+		Set("Status", "New").
+		Set("Priority", "Low")
+	if obj.ChangedFields() != nil {
+		t.Fail()
+	}
+
+	obj.ClearChanges()
+	if len(obj.ChangedFields()) != 0 {
+		t.Fail()
+	}
+
+	obj.Set("Subject", "bar")
+	(*obj)["Status"] = "Closed"
+	delete(*obj, "Priority")
+	obj.SetNull("Description")
+	obj.Set("Origin", "Web")
+
+	expected := []string{"Description", "Origin", "Priority", "Status", "Subject"}
+	if !reflect.DeepEqual(obj.ChangedFields(), expected) {
+		t.Fail()
+	}
+
+	obj.ClearChanges()
+	if len(obj.ChangedFields()) != 0 {
+		t.Fail()
+	}
+}
+
+func TestSObject_ChangedFields_nested(t *testing.T) {
+	obj := NewSObject("Contact").
+		Set("LastName", "Smith").
+		Set("MailingAddress", map[string]interface{}{"city": "Boston"})
+	obj.ClearChanges()
+
+	// Tracking state is left out of the fields.
+	if len(obj.Fields()) != 3 {
+		t.Fail()
+	}
+
+	(*obj)["MailingAddress"].(map[string]interface{})["city"] = "Denver"
+
+	if !reflect.DeepEqual(obj.ChangedFields(), []string{"MailingAddress"}) {
+		t.Fail()
+	}
+}
+
+func TestSObject_MarshalJSON(t *testing.T) {
+	obj := NewSObject("").Set("Subject", "foo")
+	obj.ClearChanges()
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"Subject":"foo"}` {
+		t.Fail()
+	}
+}
@@ -0,0 +1,114 @@
+package soql
+
+// Statement is a parsed SOQL query.
+type Statement struct {
+	Select []SelectItem
+	// From is the queried SObject, or the child relationship of a subquery.
+	From  string
+	Alias string
+	// Scope is the filter scope of USING SCOPE, such as mine.
+	Scope string
+	Where Expr
+	// With is the filter of a WITH clause, such as SECURITY_ENFORCED.
+	With    string
+	GroupBy []Field
+	// GroupByMode is ROLLUP or CUBE if the grouping was wrapped in one.
+	GroupByMode string
+	Having      Expr
+	OrderBy     []OrderItem
+	Limit       *int
+	Offset      *int
+	// For holds the VIEW, REFERENCE or UPDATE locking and tracking clauses.
+	For []string
+}
+
+// SelectItem is an entry of the SELECT list. Exactly one of Field, Subquery and TypeOf is set.
+type SelectItem struct {
+	Field    *Field
+	Subquery *Statement
+	TypeOf   *TypeOf
+}
+
+// Field is a field reference, optionally wrapped in a function such as COUNT(Id) or toLabel(Status).
+type Field struct {
+	// Name is the field name or relationship path, such as Account.Name. It is empty for COUNT().
+	Name string
+	// Function is the name of the wrapping function, if any.
+	Function string
+	Alias    string
+}
+
+// TypeOf is a TYPEOF expression selecting different fields depending on the type of a polymorphic relationship.
+type TypeOf struct {
+	Relationship string
+	When         []TypeOfWhen
+	Else         []string
+}
+
+// TypeOfWhen is a WHEN branch of a TYPEOF expression.
+type TypeOfWhen struct {
+	Type   string
+	Fields []string
+}
+
+// OrderItem is an entry of the ORDER BY list.
+type OrderItem struct {
+	Field Field
+	Desc  bool
+	// Nulls is FIRST or LAST if the position of null values was specified.
+	Nulls string
+}
+
+// Expr is a condition of a WHERE or HAVING clause: a *LogicalExpr, *NotExpr or *Comparison.
+type Expr interface {
+	expr()
+}
+
+// LogicalExpr combines conditions with AND or OR.
+type LogicalExpr struct {
+	Operator string
+	Operands []Expr
+}
+
+// NotExpr negates a condition.
+type NotExpr struct {
+	Operand Expr
+}
+
+// Comparison compares a field with a value, such as Name = 'Acme' or Id IN (SELECT AccountId FROM Contact).
+type Comparison struct {
+	Field Field
+	// Operator is one of =, !=, <, <=, >, >=, LIKE, IN, NOT IN, INCLUDES and EXCLUDES.
+	Operator string
+	Value    Value
+}
+
+func (*LogicalExpr) expr() {}
+func (*NotExpr) expr()     {}
+func (*Comparison) expr()  {}
+
+// ValueKind is the kind of a literal value.
+type ValueKind int
+
+// Value kinds.
+const (
+	NullValue ValueKind = iota
+	BoolValue
+	StringValue
+	NumberValue
+	DateValue
+	DateTimeValue
+	DateLiteralValue
+	CurrencyValue
+	ListValue
+	SubqueryValue
+)
+
+// Value is the right hand side of a comparison.
+type Value struct {
+	Kind ValueKind
+	// Text is the value as written in the query. For strings, escapes are resolved.
+	Text     string
+	List     []Value
+	Subquery *Statement
+}
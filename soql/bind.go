@@ -0,0 +1,255 @@
+package soql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// dateLayout is the format of SOQL date literals.
+	dateLayout = "2006-01-02"
+	// dateTimeLayout is the format of SOQL datetime literals.
+	dateTimeLayout = "2006-01-02T15:04:05Z"
+)
+
+// Date is a date without time of day, bound as a SOQL date literal such as 2021-08-01. time.Time values are bound as
+// datetime literals.
+type Date time.Time
+
+// DateLiteral is a relative date literal such as TODAY or LAST_N_DAYS:30. It is bound without quotes.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_dateformats.htm
+type DateLiteral string
+
+// Date literals without a parameter.
+const (
+	Yesterday         DateLiteral = "YESTERDAY"
+	Today             DateLiteral = "TODAY"
+	Tomorrow          DateLiteral = "TOMORROW"
+	LastWeek          DateLiteral = "LAST_WEEK"
+	ThisWeek          DateLiteral = "THIS_WEEK"
+	NextWeek          DateLiteral = "NEXT_WEEK"
+	LastMonth         DateLiteral = "LAST_MONTH"
+	ThisMonth         DateLiteral = "THIS_MONTH"
+	NextMonth         DateLiteral = "NEXT_MONTH"
+	LastQuarter       DateLiteral = "LAST_QUARTER"
+	ThisQuarter       DateLiteral = "THIS_QUARTER"
+	NextQuarter       DateLiteral = "NEXT_QUARTER"
+	LastYear          DateLiteral = "LAST_YEAR"
+	ThisYear          DateLiteral = "THIS_YEAR"
+	NextYear          DateLiteral = "NEXT_YEAR"
+	LastFiscalYear    DateLiteral = "LAST_FISCAL_YEAR"
+	ThisFiscalYear    DateLiteral = "THIS_FISCAL_YEAR"
+	NextFiscalYear    DateLiteral = "NEXT_FISCAL_YEAR"
+	Last90Days        DateLiteral = "LAST_90_DAYS"
+	Next90Days        DateLiteral = "NEXT_90_DAYS"
+	LastFiscalQuarter DateLiteral = "LAST_FISCAL_QUARTER"
+	ThisFiscalQuarter DateLiteral = "THIS_FISCAL_QUARTER"
+	NextFiscalQuarter DateLiteral = "NEXT_FISCAL_QUARTER"
+)
+
+// LastNDays returns the LAST_N_DAYS:n date literal.
+func LastNDays(n int) DateLiteral {
+	return DateLiteral("LAST_N_DAYS:" + strconv.Itoa(n))
+}
+
+// NextNDays returns the NEXT_N_DAYS:n date literal.
+func NextNDays(n int) DateLiteral {
+	return DateLiteral("NEXT_N_DAYS:" + strconv.Itoa(n))
+}
+
+// NDaysAgo returns the N_DAYS_AGO:n date literal.
+func NDaysAgo(n int) DateLiteral {
+	return DateLiteral("N_DAYS_AGO:" + strconv.Itoa(n))
+}
+
+// LastNMonths returns the LAST_N_MONTHS:n date literal.
+func LastNMonths(n int) DateLiteral {
+	return DateLiteral("LAST_N_MONTHS:" + strconv.Itoa(n))
+}
+
+// NextNMonths returns the NEXT_N_MONTHS:n date literal.
+func NextNMonths(n int) DateLiteral {
+	return DateLiteral("NEXT_N_MONTHS:" + strconv.Itoa(n))
+}
+
+var dateLiteralPattern = regexp.MustCompile(`^[A-Z_0-9]+(:\d+)?$`)
+
+// Escape escapes s for use inside a single quoted SOQL string literal.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_quotedstringescapes.htm
+func Escape(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// EscapeLike escapes the wildcards % and _ in s, so they match literally when s is embedded in a LIKE pattern.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// formatLikePattern quotes and escapes a LIKE pattern, keeping the wildcard escapes \% and \_ intact.
+func formatLikePattern(pattern string) string {
+	var b strings.Builder
+
+	b.WriteString("'")
+
+	for {
+		i := strings.IndexByte(pattern, '\\')
+		if i < 0 || i+1 == len(pattern) {
+			b.WriteString(Escape(pattern))
+			break
+		}
+
+		b.WriteString(Escape(pattern[:i]))
+
+		if next := pattern[i+1]; next == '%' || next == '_' {
+			b.WriteString(pattern[i : i+2])
+		} else {
+			b.WriteString(Escape(pattern[i : i+2]))
+		}
+
+		pattern = pattern[i+2:]
+	}
+
+	b.WriteString("'")
+
+	return b.String()
+}
+
+// Format formats v as a SOQL literal. Strings are quoted and escaped, time.Time is formatted as a datetime in UTC,
+// Date as a date, DateLiteral verbatim, nil as null and slices as a parenthesized list for IN.
+func Format(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case time.Time:
+		return v.UTC().Format(dateTimeLayout), nil
+	case *time.Time:
+		if v == nil {
+			return "null", nil
+		}
+		return v.UTC().Format(dateTimeLayout), nil
+	case Date:
+		return time.Time(v).Format(dateLayout), nil
+	case DateLiteral:
+		if !dateLiteralPattern.MatchString(string(v)) {
+			return "", fmt.Errorf("soql: invalid date literal %q", string(v))
+		}
+		return string(v), nil
+	case fmt.Stringer:
+		// Types such as simpleforce.ID are bound as strings.
+		if reflect.ValueOf(v).Kind() != reflect.String {
+			return "", fmt.Errorf("soql: unsupported value type %T", v)
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "null", nil
+		}
+		return Format(rv.Elem().Interface())
+	case reflect.String:
+		return "'" + Escape(rv.String()) + "'", nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return "", fmt.Errorf("soql: empty list")
+		}
+
+		items := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, err := Format(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+
+		return "(" + strings.Join(items, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("soql: unsupported value type %T", v)
+	}
+}
+
+// Bind replaces each ? placeholder in query with the corresponding argument formatted with Format. Question marks
+// inside string literals of query are left alone.
+//
+//	soql.Bind("SELECT Id FROM Contact WHERE LastName = ? AND Id IN ?", name, ids)
+func Bind(query string, args ...interface{}) (string, error) {
+	var b strings.Builder
+
+	arg := 0
+	inString := false
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case inString && c == '\\' && i+1 < len(query):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(query[i])
+			continue
+		case c == '\'':
+			inString = !inString
+		case c == '?' && !inString:
+			if arg >= len(args) {
+				return "", fmt.Errorf("soql: not enough arguments for query")
+			}
+
+			value, err := Format(args[arg])
+			if err != nil {
+				return "", err
+			}
+			arg++
+
+			b.WriteString(value)
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	if arg != len(args) {
+		return "", fmt.Errorf("soql: %d arguments given for %d placeholders", len(args), arg)
+	}
+
+	return b.String(), nil
+}
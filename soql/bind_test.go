@@ -0,0 +1,76 @@
+package soql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type id string
+
+func TestEscape(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`O\'Brien`, Escape("O'Brien"))
+	assert.Equal(`a\\b\"c\nd`, Escape("a\\b\"c\nd"))
+	assert.Equal(`100\%\_done`, EscapeLike("100%_done"))
+	assert.Equal(`'100\%\_d\\one\'s é'`, formatLikePattern(EscapeLike(`100%_d\one's é`)))
+}
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	est := time.FixedZone("EST", -5*60*60)
+	s := "x"
+
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{"O'Brien", `'O\'Brien'`},
+		{id("001"), "'001'"},
+		{&s, "'x'"},
+		{true, "true"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{12.5, "12.5"},
+		{time.Date(2021, 8, 1, 7, 30, 0, 0, est), "2021-08-01T12:30:00Z"},
+		{Date(time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)), "2021-08-01"},
+		{LastNDays(30), "LAST_N_DAYS:30"},
+		{Today, "TODAY"},
+		{[]string{"a", "b'c"}, `('a', 'b\'c')`},
+		{[]id{"001"}, "('001')"},
+	}
+
+	for _, test := range tests {
+		actual, err := Format(test.value)
+		assert.NoError(err)
+		assert.Equal(test.expected, actual)
+	}
+
+	for _, value := range []interface{}{DateLiteral("TODAY OR Id != null"), []string{}, struct{}{}, map[string]string{}} {
+		_, err := Format(value)
+		assert.Error(err, value)
+	}
+}
+
+func TestBind(t *testing.T) {
+	assert := assert.New(t)
+
+	query, err := Bind("SELECT Id FROM Contact WHERE LastName = ? AND Description != 'why?' AND Id IN ?",
+		"O'Brien", []string{"003000000000001"})
+	assert.NoError(err)
+	assert.Equal(`SELECT Id FROM Contact WHERE LastName = 'O\'Brien' AND Description != 'why?' AND Id IN ('003000000000001')`, query)
+
+	query, err = Bind(`SELECT Id FROM Contact WHERE LastName = 'it\'s?' AND FirstName = ?`, "x")
+	assert.NoError(err)
+	assert.Equal(`SELECT Id FROM Contact WHERE LastName = 'it\'s?' AND FirstName = 'x'`, query)
+
+	_, err = Bind("SELECT Id FROM Contact WHERE LastName = ?")
+	assert.Error(err)
+
+	_, err = Bind("SELECT Id FROM Contact", "x")
+	assert.Error(err)
+}
@@ -0,0 +1,181 @@
+package soql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Condition is a WHERE or HAVING condition. Conditions are created with the functions of this package, which bind
+// their values safely.
+type Condition interface {
+	build(b *strings.Builder) error
+}
+
+type comparison struct {
+	field    string
+	operator string
+	value    interface{}
+	// list is true if the value must be a list or subquery.
+	list bool
+}
+
+func (c comparison) build(b *strings.Builder) error {
+	err := checkFields([]string{c.field})
+	if err != nil {
+		return err
+	}
+
+	var value string
+
+	if sub, ok := c.value.(*Query); ok {
+		value, err = sub.Build()
+		value = "(" + value + ")"
+	} else if pattern, ok := c.value.(likePattern); ok {
+		value = formatLikePattern(string(pattern))
+	} else {
+		if kind := reflect.ValueOf(c.value).Kind(); c.list && kind != reflect.Slice && kind != reflect.Array {
+			return fmt.Errorf("soql: %s requires a slice, got %T", c.operator, c.value)
+		}
+
+		value, err = Format(c.value)
+	}
+	if err != nil {
+		return err
+	}
+
+	b.WriteString(c.field)
+	b.WriteString(" ")
+	b.WriteString(c.operator)
+	b.WriteString(" ")
+	b.WriteString(value)
+
+	return nil
+}
+
+// Eq matches records whose field equals value. A nil value matches empty fields.
+func Eq(field string, value interface{}) Condition {
+	return comparison{field, "=", value, false}
+}
+
+// Ne matches records whose field doesn't equal value.
+func Ne(field string, value interface{}) Condition {
+	return comparison{field, "!=", value, false}
+}
+
+// Lt matches records whose field is less than value.
+func Lt(field string, value interface{}) Condition {
+	return comparison{field, "<", value, false}
+}
+
+// Le matches records whose field is less than or equal to value.
+func Le(field string, value interface{}) Condition {
+	return comparison{field, "<=", value, false}
+}
+
+// Gt matches records whose field is greater than value.
+func Gt(field string, value interface{}) Condition {
+	return comparison{field, ">", value, false}
+}
+
+// Ge matches records whose field is greater than or equal to value.
+func Ge(field string, value interface{}) Condition {
+	return comparison{field, ">=", value, false}
+}
+
+// Like matches records whose field matches pattern, where % matches any characters and _ a single character. Use
+// EscapeLike on user input embedded in pattern.
+func Like(field string, pattern string) Condition {
+	return comparison{field, "LIKE", likePattern(pattern), false}
+}
+
+// likePattern is a LIKE pattern, which is escaped differently from other strings.
+type likePattern string
+
+// In matches records whose field equals one of values, which must be a non-empty slice, or whose field is in the
+// results of a semi-join subquery.
+func In(field string, values interface{}) Condition {
+	return comparison{field, "IN", values, true}
+}
+
+// NotIn matches records whose field equals none of values, which must be a non-empty slice, or whose field isn't in
+// the results of an anti-join subquery.
+func NotIn(field string, values interface{}) Condition {
+	return comparison{field, "NOT IN", values, true}
+}
+
+// Includes matches records whose multi-select picklist field includes any of values.
+func Includes(field string, values ...string) Condition {
+	return comparison{field, "INCLUDES", values, true}
+}
+
+// Excludes matches records whose multi-select picklist field includes none of values.
+func Excludes(field string, values ...string) Condition {
+	return comparison{field, "EXCLUDES", values, true}
+}
+
+type logical struct {
+	operator   string
+	conditions []Condition
+}
+
+func (l logical) build(b *strings.Builder) error {
+	if len(l.conditions) == 0 {
+		return fmt.Errorf("soql: %s without conditions", l.operator)
+	}
+
+	if len(l.conditions) == 1 {
+		return l.conditions[0].build(b)
+	}
+
+	b.WriteString("(")
+
+	for i, cond := range l.conditions {
+		if i > 0 {
+			b.WriteString(" ")
+			b.WriteString(l.operator)
+			b.WriteString(" ")
+		}
+
+		err := cond.build(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.WriteString(")")
+
+	return nil
+}
+
+// And matches records matching all conditions.
+func And(conditions ...Condition) Condition {
+	return logical{"AND", conditions}
+}
+
+// Or matches records matching any of the conditions.
+func Or(conditions ...Condition) Condition {
+	return logical{"OR", conditions}
+}
+
+type not struct {
+	condition Condition
+}
+
+func (n not) build(b *strings.Builder) error {
+	b.WriteString("(NOT ")
+
+	err := n.condition.build(b)
+	if err != nil {
+		return err
+	}
+
+	b.WriteString(")")
+
+	return nil
+}
+
+// Not matches records not matching condition.
+func Not(condition Condition) Condition {
+	return not{condition}
+}
@@ -0,0 +1,184 @@
+package soql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// String formats the parsed query back into SOQL. Whitespace and keyword case are normalized, <> is written as != and
+// nested function calls keep only their outermost function, as in the parsed Statement.
+func (s *Statement) String() string {
+	var b strings.Builder
+	s.format(&b)
+	return b.String()
+}
+
+func (s *Statement) format(b *strings.Builder) {
+	b.WriteString("SELECT ")
+	for i, item := range s.Select {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		switch {
+		case item.Subquery != nil:
+			b.WriteString("(")
+			item.Subquery.format(b)
+			b.WriteString(")")
+		case item.TypeOf != nil:
+			item.TypeOf.format(b)
+		case item.Field != nil:
+			item.Field.format(b)
+			if len(item.Field.Alias) > 0 {
+				b.WriteString(" " + item.Field.Alias)
+			}
+		}
+	}
+
+	b.WriteString(" FROM " + s.From)
+	if len(s.Alias) > 0 {
+		b.WriteString(" " + s.Alias)
+	}
+
+	if len(s.Scope) > 0 {
+		b.WriteString(" USING SCOPE " + s.Scope)
+	}
+
+	if s.Where != nil {
+		b.WriteString(" WHERE ")
+		formatExpr(b, s.Where)
+	}
+
+	if len(s.With) > 0 {
+		b.WriteString(" WITH " + s.With)
+	}
+
+	if len(s.GroupBy) > 0 {
+		b.WriteString(" GROUP BY ")
+		if len(s.GroupByMode) > 0 {
+			b.WriteString(s.GroupByMode + "(")
+		}
+		for i, field := range s.GroupBy {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			field.format(b)
+		}
+		if len(s.GroupByMode) > 0 {
+			b.WriteString(")")
+		}
+	}
+
+	if s.Having != nil {
+		b.WriteString(" HAVING ")
+		formatExpr(b, s.Having)
+	}
+
+	if len(s.OrderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		for i, item := range s.OrderBy {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			item.Field.format(b)
+			if item.Desc {
+				b.WriteString(" DESC")
+			}
+			if len(item.Nulls) > 0 {
+				b.WriteString(" NULLS " + item.Nulls)
+			}
+		}
+	}
+
+	if s.Limit != nil {
+		b.WriteString(" LIMIT " + strconv.Itoa(*s.Limit))
+	}
+
+	if s.Offset != nil {
+		b.WriteString(" OFFSET " + strconv.Itoa(*s.Offset))
+	}
+
+	for _, clause := range s.For {
+		b.WriteString(" FOR " + clause)
+	}
+}
+
+func (f *Field) format(b *strings.Builder) {
+	if len(f.Function) == 0 {
+		b.WriteString(f.Name)
+		return
+	}
+
+	b.WriteString(f.Function + "(" + f.Name + ")")
+}
+
+func (t *TypeOf) format(b *strings.Builder) {
+	b.WriteString("TYPEOF " + t.Relationship)
+	for _, when := range t.When {
+		b.WriteString(" WHEN " + when.Type + " THEN " + strings.Join(when.Fields, ", "))
+	}
+	if len(t.Else) > 0 {
+		b.WriteString(" ELSE " + strings.Join(t.Else, ", "))
+	}
+	b.WriteString(" END")
+}
+
+// formatExpr formats a condition. Nested logical expressions are parenthesized, as SOQL requires for mixing AND and
+// OR.
+func formatExpr(b *strings.Builder, expr Expr) {
+	switch expr := expr.(type) {
+	case *LogicalExpr:
+		for i, operand := range expr.Operands {
+			if i > 0 {
+				b.WriteString(" " + expr.Operator + " ")
+			}
+			formatOperand(b, operand)
+		}
+
+	case *NotExpr:
+		b.WriteString("NOT ")
+		formatOperand(b, expr.Operand)
+
+	case *Comparison:
+		expr.Field.format(b)
+		b.WriteString(" " + expr.Operator + " ")
+		expr.Value.format(b)
+	}
+}
+
+func formatOperand(b *strings.Builder, expr Expr) {
+	if _, ok := expr.(*LogicalExpr); !ok {
+		formatExpr(b, expr)
+		return
+	}
+
+	b.WriteString("(")
+	formatExpr(b, expr)
+	b.WriteString(")")
+}
+
+func (v *Value) format(b *strings.Builder) {
+	switch v.Kind {
+	case StringValue:
+		// The parser keeps the wildcard escapes \% and \_ in the value.
+		b.WriteString(formatLikePattern(v.Text))
+
+	case ListValue:
+		b.WriteString("(")
+		for i := range v.List {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			v.List[i].format(b)
+		}
+		b.WriteString(")")
+
+	case SubqueryValue:
+		b.WriteString("(")
+		v.Subquery.format(b)
+		b.WriteString(")")
+
+	default:
+		b.WriteString(v.Text)
+	}
+}
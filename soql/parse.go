@@ -0,0 +1,786 @@
+package soql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError describes a malformed query. Pos is the byte offset of the problem in the query.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("soql: syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokDate
+	tokDateTime
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var (
+	dateTokenPattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+	dateTimeTokenPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})`)
+	numberTokenPattern   = regexp.MustCompile(`^[+-]?\d+(\.\d+)?`)
+	identTokenPattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*(:\d+)?`)
+)
+
+// lex splits a query into tokens.
+func lex(query string) ([]token, error) {
+	var tokens []token
+
+	for pos := 0; pos < len(query); {
+		c := query[pos]
+		rest := query[pos:]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", pos})
+			pos++
+
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", pos})
+			pos++
+
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", pos})
+			pos++
+
+		case c == '\'':
+			text, n, err := lexString(rest)
+			if err != nil {
+				return nil, &SyntaxError{pos, err.Error()}
+			}
+			tokens = append(tokens, token{tokString, text, pos})
+			pos += n
+
+		case strings.HasPrefix(rest, "!=") || strings.HasPrefix(rest, "<>") ||
+			strings.HasPrefix(rest, "<=") || strings.HasPrefix(rest, ">="):
+			op := rest[:2]
+			if op == "<>" {
+				op = "!="
+			}
+			tokens = append(tokens, token{tokOperator, op, pos})
+			pos += 2
+
+		case c == '=' || c == '<' || c == '>':
+			tokens = append(tokens, token{tokOperator, string(c), pos})
+			pos++
+
+		case dateTimeTokenPattern.MatchString(rest):
+			m := dateTimeTokenPattern.FindString(rest)
+			tokens = append(tokens, token{tokDateTime, m, pos})
+			pos += len(m)
+
+		case dateTokenPattern.MatchString(rest):
+			m := dateTokenPattern.FindString(rest)
+			tokens = append(tokens, token{tokDate, m, pos})
+			pos += len(m)
+
+		case numberTokenPattern.MatchString(rest):
+			m := numberTokenPattern.FindString(rest)
+			tokens = append(tokens, token{tokNumber, m, pos})
+			pos += len(m)
+
+		case identTokenPattern.MatchString(rest):
+			m := identTokenPattern.FindString(rest)
+			tokens = append(tokens, token{tokIdent, m, pos})
+			pos += len(m)
+
+		case c == ':':
+			return nil, &SyntaxError{pos, "bind variables are only supported in Apex"}
+
+		default:
+			return nil, &SyntaxError{pos, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+
+	return append(tokens, token{tokEOF, "", len(query)}), nil
+}
+
+// lexString reads a quoted string literal, returning its unescaped value and length in the query.
+func lexString(s string) (string, int, error) {
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case '\'', '"', '\\':
+				b.WriteByte(s[i])
+			case '%', '_':
+				// LIKE wildcard escapes are kept for the pattern.
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			default:
+				return "", 0, fmt.Errorf("invalid escape sequence \\%c", s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// Parse parses a SOQL query. A *SyntaxError is returned if the query is malformed.
+func Parse(query string) (*Statement, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+
+	return stmt, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{p.peek().pos, fmt.Sprintf(format, args...)}
+}
+
+// isKeyword reports whether the next token is the given keyword.
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, keyword)
+}
+
+// acceptKeyword consumes the next token if it is the given keyword.
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.errorf("expected %s, got %q", keyword, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	if p.peek().kind != kind {
+		return token{}, p.errorf("expected %s, got %q", what, p.peek().text)
+	}
+	return p.next(), nil
+}
+
+// reserved lists the keywords that can't be used as aliases.
+var reserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "WITH": true, "GROUP": true, "HAVING": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "FOR": true, "USING": true, "AND": true, "OR": true, "NOT": true, "IN": true,
+	"LIKE": true, "INCLUDES": true, "EXCLUDES": true, "ASC": true, "DESC": true, "NULLS": true, "UPDATE": true,
+	"TYPEOF": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+}
+
+func isReserved(s string) bool {
+	return reserved[strings.ToUpper(s)]
+}
+
+func (p *parser) parseStatement() (*Statement, error) {
+	err := p.expectKeyword("SELECT")
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &Statement{}
+
+	for {
+		item, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Select = append(stmt.Select, item)
+
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+
+	err = p.expectKeyword("FROM")
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := p.expect(tokIdent, "object name")
+	if err != nil {
+		return nil, err
+	}
+	stmt.From = from.text
+
+	if t := p.peek(); t.kind == tokIdent && !isReserved(t.text) {
+		stmt.Alias = p.next().text
+	}
+
+	if p.acceptKeyword("USING") {
+		err = p.expectKeyword("SCOPE")
+		if err != nil {
+			return nil, err
+		}
+
+		scope, err := p.expect(tokIdent, "scope")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Scope = scope.text
+	}
+
+	if p.acceptKeyword("WHERE") {
+		stmt.Where, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("WITH") {
+		if p.isKeyword("DATA") {
+			return nil, p.errorf("WITH DATA CATEGORY is not supported")
+		}
+
+		with, err := p.expect(tokIdent, "WITH filter")
+		if err != nil {
+			return nil, err
+		}
+		stmt.With = strings.ToUpper(with.text)
+	}
+
+	if p.acceptKeyword("GROUP") {
+		err = p.expectKeyword("BY")
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.GroupBy, stmt.GroupByMode, err = p.parseGroupBy()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("HAVING") {
+		stmt.Having, err = p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("ORDER") {
+		err = p.expectKeyword("BY")
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.OrderBy, err = p.parseOrderBy()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("LIMIT") {
+		stmt.Limit, err = p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("OFFSET") {
+		stmt.Offset, err = p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for p.acceptKeyword("FOR") {
+		clause, err := p.expect(tokIdent, "VIEW, REFERENCE or UPDATE")
+		if err != nil {
+			return nil, err
+		}
+
+		switch upper := strings.ToUpper(clause.text); upper {
+		case "VIEW", "REFERENCE", "UPDATE":
+			stmt.For = append(stmt.For, upper)
+		default:
+			return nil, &SyntaxError{clause.pos, fmt.Sprintf("unexpected FOR %s", clause.text)}
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *parser) parseSelectItem() (SelectItem, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+
+		sub, err := p.parseStatement()
+		if err != nil {
+			return SelectItem{}, err
+		}
+
+		_, err = p.expect(tokRParen, ")")
+		if err != nil {
+			return SelectItem{}, err
+		}
+
+		return SelectItem{Subquery: sub}, nil
+	}
+
+	if p.acceptKeyword("TYPEOF") {
+		typeOf, err := p.parseTypeOf()
+		if err != nil {
+			return SelectItem{}, err
+		}
+
+		return SelectItem{TypeOf: typeOf}, nil
+	}
+
+	field, err := p.parseField()
+	if err != nil {
+		return SelectItem{}, err
+	}
+
+	if t := p.peek(); t.kind == tokIdent && !isReserved(t.text) {
+		field.Alias = p.next().text
+	}
+
+	return SelectItem{Field: &field}, nil
+}
+
+func (p *parser) parseTypeOf() (*TypeOf, error) {
+	rel, err := p.expect(tokIdent, "relationship name")
+	if err != nil {
+		return nil, err
+	}
+
+	typeOf := &TypeOf{Relationship: rel.text}
+
+	for p.acceptKeyword("WHEN") {
+		typeName, err := p.expect(tokIdent, "object name")
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expectKeyword("THEN")
+		if err != nil {
+			return nil, err
+		}
+
+		fields, err := p.parseNameList()
+		if err != nil {
+			return nil, err
+		}
+
+		typeOf.When = append(typeOf.When, TypeOfWhen{Type: typeName.text, Fields: fields})
+	}
+
+	if len(typeOf.When) == 0 {
+		return nil, p.errorf("TYPEOF requires at least one WHEN")
+	}
+
+	if p.acceptKeyword("ELSE") {
+		typeOf.Else, err = p.parseNameList()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.expectKeyword("END")
+	if err != nil {
+		return nil, err
+	}
+
+	return typeOf, nil
+}
+
+func (p *parser) parseNameList() ([]string, error) {
+	var names []string
+
+	for {
+		name, err := p.expect(tokIdent, "field name")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name.text)
+
+		if p.peek().kind != tokComma {
+			return names, nil
+		}
+		p.next()
+	}
+}
+
+// parseField parses a field name or a function call on a field, such as COUNT(Id).
+func (p *parser) parseField() (Field, error) {
+	name, err := p.expect(tokIdent, "field name")
+	if err != nil {
+		return Field{}, err
+	}
+
+	if p.peek().kind != tokLParen {
+		if isReserved(name.text) {
+			return Field{}, &SyntaxError{name.pos, fmt.Sprintf("expected field name, got %q", name.text)}
+		}
+
+		return Field{Name: name.text}, nil
+	}
+
+	p.next()
+
+	field := Field{Function: name.text}
+
+	if p.peek().kind == tokIdent {
+		arg, err := p.parseField()
+		if err != nil {
+			return Field{}, err
+		}
+
+		// Nested calls, such as convertTimezone inside CALENDAR_YEAR, keep the outermost function.
+		field.Name = arg.Name
+	}
+
+	_, err = p.expect(tokRParen, ")")
+	if err != nil {
+		return Field{}, err
+	}
+
+	return field, nil
+}
+
+func (p *parser) parseGroupBy() ([]Field, string, error) {
+	mode := ""
+	if p.isKeyword("ROLLUP") || p.isKeyword("CUBE") {
+		mode = strings.ToUpper(p.next().text)
+
+		_, err := p.expect(tokLParen, "(")
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var fields []Field
+
+	for {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, "", err
+		}
+		fields = append(fields, field)
+
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+
+	if len(mode) > 0 {
+		_, err := p.expect(tokRParen, ")")
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return fields, mode, nil
+}
+
+func (p *parser) parseOrderBy() ([]OrderItem, error) {
+	var items []OrderItem
+
+	for {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+
+		item := OrderItem{Field: field}
+
+		if p.acceptKeyword("DESC") {
+			item.Desc = true
+		} else {
+			p.acceptKeyword("ASC")
+		}
+
+		if p.acceptKeyword("NULLS") {
+			switch {
+			case p.acceptKeyword("FIRST"):
+				item.Nulls = "FIRST"
+			case p.acceptKeyword("LAST"):
+				item.Nulls = "LAST"
+			default:
+				return nil, p.errorf("expected FIRST or LAST, got %q", p.peek().text)
+			}
+		}
+
+		items = append(items, item)
+
+		if p.peek().kind != tokComma {
+			return items, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseInt() (*int, error) {
+	t, err := p.expect(tokNumber, "number")
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(t.text)
+	if err != nil || n < 0 {
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("expected a non-negative integer, got %q", t.text)}
+	}
+
+	return &n, nil
+}
+
+// parseExpr parses conditions joined by AND or OR. SOQL doesn't allow mixing AND and OR without parentheses.
+func (p *parser) parseExpr() (Expr, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	operator := ""
+	operands := []Expr{first}
+
+	for p.isKeyword("AND") || p.isKeyword("OR") {
+		t := p.next()
+		op := strings.ToUpper(t.text)
+
+		if len(operator) > 0 && op != operator {
+			return nil, &SyntaxError{t.pos, "AND and OR must be grouped with parentheses"}
+		}
+		operator = op
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+
+	return &LogicalExpr{Operator: operator, Operands: operands}, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &NotExpr{Operand: operand}, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = p.expect(tokRParen, ")")
+		if err != nil {
+			return nil, err
+		}
+
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	field, err := p.parseField()
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &Comparison{Field: field}
+
+	switch t := p.peek(); {
+	case t.kind == tokOperator:
+		cmp.Operator = p.next().text
+
+	case p.acceptKeyword("LIKE"):
+		cmp.Operator = "LIKE"
+
+	case p.acceptKeyword("IN"):
+		cmp.Operator = "IN"
+
+	case p.acceptKeyword("NOT"):
+		err = p.expectKeyword("IN")
+		if err != nil {
+			return nil, err
+		}
+		cmp.Operator = "NOT IN"
+
+	case p.acceptKeyword("INCLUDES"):
+		cmp.Operator = "INCLUDES"
+
+	case p.acceptKeyword("EXCLUDES"):
+		cmp.Operator = "EXCLUDES"
+
+	default:
+		return nil, p.errorf("expected comparison operator, got %q", t.text)
+	}
+
+	switch cmp.Operator {
+	case "IN", "NOT IN", "INCLUDES", "EXCLUDES":
+		cmp.Value, err = p.parseList(cmp.Operator != "INCLUDES" && cmp.Operator != "EXCLUDES")
+	default:
+		cmp.Value, err = p.parseValue()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cmp, nil
+}
+
+// parseList parses a parenthesized list of values or, if allowSubquery is true, a semi-join subquery.
+func (p *parser) parseList(allowSubquery bool) (Value, error) {
+	_, err := p.expect(tokLParen, "(")
+	if err != nil {
+		return Value{}, err
+	}
+
+	if allowSubquery && p.isKeyword("SELECT") {
+		sub, err := p.parseStatement()
+		if err != nil {
+			return Value{}, err
+		}
+
+		_, err = p.expect(tokRParen, ")")
+		if err != nil {
+			return Value{}, err
+		}
+
+		return Value{Kind: SubqueryValue, Subquery: sub}, nil
+	}
+
+	list := Value{Kind: ListValue}
+
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return Value{}, err
+		}
+		list.List = append(list.List, v)
+
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+
+	_, err = p.expect(tokRParen, ")")
+	if err != nil {
+		return Value{}, err
+	}
+
+	return list, nil
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}\d+(\.\d+)?$`)
+
+func (p *parser) parseValue() (Value, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokString:
+		return Value{Kind: StringValue, Text: t.text}, nil
+	case tokNumber:
+		return Value{Kind: NumberValue, Text: t.text}, nil
+	case tokDate:
+		return Value{Kind: DateValue, Text: t.text}, nil
+	case tokDateTime:
+		return Value{Kind: DateTimeValue, Text: t.text}, nil
+	case tokIdent:
+		switch upper := strings.ToUpper(t.text); {
+		case upper == "NULL":
+			return Value{Kind: NullValue, Text: t.text}, nil
+		case upper == "TRUE" || upper == "FALSE":
+			return Value{Kind: BoolValue, Text: t.text}, nil
+		case currencyPattern.MatchString(t.text):
+			return Value{Kind: CurrencyValue, Text: t.text}, nil
+		case dateLiteralPattern.MatchString(upper) && !strings.Contains(t.text, "."):
+			return Value{Kind: DateLiteralValue, Text: t.text}, nil
+		}
+	}
+
+	return Value{}, &SyntaxError{t.pos, fmt.Sprintf("expected value, got %q", t.text)}
+}
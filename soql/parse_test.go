@@ -0,0 +1,151 @@
+package soql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("SELECT Id, Account.Name, (SELECT Name FROM Contacts), TYPEOF What WHEN Account THEN Phone ELSE Name END " +
+		"FROM Case c USING SCOPE mine " +
+		`WHERE (Subject LIKE 'O\'Brien%' OR Status != 'Closed') AND CreatedDate > LAST_N_DAYS:30 ` +
+		"AND AccountId IN (SELECT Id FROM Account) AND Amount__c >= USD100.5 " +
+		"WITH SECURITY_ENFORCED ORDER BY CreatedDate DESC NULLS LAST, Id LIMIT 10 OFFSET 5 FOR VIEW")
+	assert.NoError(err)
+
+	assert.Len(stmt.Select, 4)
+	assert.Equal(&Field{Name: "Id"}, stmt.Select[0].Field)
+	assert.Equal(&Field{Name: "Account.Name"}, stmt.Select[1].Field)
+	assert.Equal("Contacts", stmt.Select[2].Subquery.From)
+	assert.Equal(&TypeOf{
+		Relationship: "What",
+		When:         []TypeOfWhen{{Type: "Account", Fields: []string{"Phone"}}},
+		Else:         []string{"Name"},
+	}, stmt.Select[3].TypeOf)
+
+	assert.Equal("Case", stmt.From)
+	assert.Equal("c", stmt.Alias)
+	assert.Equal("mine", stmt.Scope)
+	assert.Equal("SECURITY_ENFORCED", stmt.With)
+
+	where, ok := stmt.Where.(*LogicalExpr)
+	assert.True(ok)
+	assert.Equal("AND", where.Operator)
+	assert.Len(where.Operands, 4)
+	assert.Equal(&LogicalExpr{
+		Operator: "OR",
+		Operands: []Expr{
+			&Comparison{Field: Field{Name: "Subject"}, Operator: "LIKE", Value: Value{Kind: StringValue, Text: "O'Brien%"}},
+			&Comparison{Field: Field{Name: "Status"}, Operator: "!=", Value: Value{Kind: StringValue, Text: "Closed"}},
+		},
+	}, where.Operands[0])
+	assert.Equal(Value{Kind: DateLiteralValue, Text: "LAST_N_DAYS:30"}, where.Operands[1].(*Comparison).Value)
+	assert.Equal(SubqueryValue, where.Operands[2].(*Comparison).Value.Kind)
+	assert.Equal("Account", where.Operands[2].(*Comparison).Value.Subquery.From)
+	assert.Equal(Value{Kind: CurrencyValue, Text: "USD100.5"}, where.Operands[3].(*Comparison).Value)
+
+	assert.Equal([]OrderItem{{Field: Field{Name: "CreatedDate"}, Desc: true, Nulls: "LAST"}, {Field: Field{Name: "Id"}}}, stmt.OrderBy)
+	assert.Equal(10, *stmt.Limit)
+	assert.Equal(5, *stmt.Offset)
+	assert.Equal([]string{"VIEW"}, stmt.For)
+}
+
+func TestParse_aggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := Parse("select LeadSource, COUNT(Id) total, COUNT() from Lead group by rollup(LeadSource) having COUNT(Id) > 100")
+	assert.NoError(err)
+
+	assert.Equal(&Field{Name: "Id", Function: "COUNT", Alias: "total"}, stmt.Select[1].Field)
+	assert.Equal(&Field{Function: "COUNT"}, stmt.Select[2].Field)
+	assert.Equal([]Field{{Name: "LeadSource"}}, stmt.GroupBy)
+	assert.Equal("ROLLUP", stmt.GroupByMode)
+	assert.Equal(&Comparison{
+		Field:    Field{Name: "Id", Function: "COUNT"},
+		Operator: ">",
+		Value:    Value{Kind: NumberValue, Text: "100"},
+	}, stmt.Having)
+}
+
+func TestParse_builder(t *testing.T) {
+	assert := assert.New(t)
+
+	query, err := Select("Id", "Name").
+		From("Account").
+		Where(And(
+			Eq("Name", "O'Brien"),
+			Or(Gt("CreatedDate", LastNDays(30)), Eq("OwnerId", nil)),
+			NotIn("Type", []string{"Customer", "Partner"}),
+			Not(Like("Phone", "%"+EscapeLike("555_")+"%")),
+			Excludes("Languages__c", "English"),
+		)).
+		Build()
+	assert.NoError(err)
+
+	stmt, err := Parse(query)
+	assert.NoError(err)
+
+	where := stmt.Where.(*LogicalExpr)
+	assert.Len(where.Operands, 5)
+	assert.Equal(Value{Kind: StringValue, Text: "O'Brien"}, where.Operands[0].(*Comparison).Value)
+	assert.Equal(NullValue, where.Operands[1].(*LogicalExpr).Operands[1].(*Comparison).Value.Kind)
+	assert.Equal("NOT IN", where.Operands[2].(*Comparison).Operator)
+	assert.Equal(Value{Kind: StringValue, Text: `%555\_%`}, where.Operands[3].(*NotExpr).Operand.(*Comparison).Value)
+	assert.Equal("EXCLUDES", where.Operands[4].(*Comparison).Operator)
+}
+
+func TestStatement_String(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []string{
+		"SELECT Id, Account.Name, (SELECT Name FROM Contacts), TYPEOF What WHEN Account THEN Phone ELSE Name END " +
+			"FROM Case c USING SCOPE mine " +
+			`WHERE (Subject LIKE 'O\'Brien\_%' OR Status != 'Closed') AND CreatedDate > LAST_N_DAYS:30 ` +
+			"AND AccountId IN (SELECT Id FROM Account) AND Amount__c >= USD100.5 AND NOT Type IN ('A', 'B') " +
+			"WITH SECURITY_ENFORCED ORDER BY CreatedDate DESC NULLS LAST, Id LIMIT 10 OFFSET 5 FOR VIEW",
+		"SELECT LeadSource, COUNT(Id) total, COUNT() FROM Lead GROUP BY ROLLUP(LeadSource) HAVING COUNT(Id) > 100",
+	}
+
+	for _, query := range queries {
+		stmt, err := Parse(query)
+		assert.NoError(err)
+		assert.Equal(query, stmt.String())
+	}
+
+	stmt, err := Parse("select Id from Account where Name <> 'Acme'")
+	assert.NoError(err)
+	assert.Equal("SELECT Id FROM Account WHERE Name != 'Acme'", stmt.String())
+}
+
+func TestParse_invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{"", 0},
+		{"SELECT FROM Account", 7},
+		{"SELECT Id Account", 17},
+		{"SELECT Id FROM Account WHERE Name = 'Acme", 36},
+		{"SELECT Id FROM Account WHERE Name = :name", 36},
+		{"SELECT Id FROM Account WHERE A = 1 AND B = 2 OR C = 3", 45},
+		{"SELECT Id FROM Account WHERE Name", 33},
+		{"SELECT Id FROM Account LIMIT -1", 29},
+		{"SELECT Id FROM Account LIMIT 1 extra", 31},
+		{"SELECT Id FROM Account FOR DELETE", 27},
+		{"SELECT (SELECT Id FROM Contacts FROM Account", 32},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.query)
+
+		syntaxErr, ok := err.(*SyntaxError)
+		if assert.True(ok, test.query) {
+			assert.Equal(test.pos, syntaxErr.Pos, test.query)
+		}
+	}
+}
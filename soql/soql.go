@@ -0,0 +1,273 @@
+// Package soql builds SOQL queries and SOSL searches with safely bound values.
+//
+//	query, err := soql.Select("Id", "Name").
+//		From("Contact").
+//		Where(soql.And(
+//			soql.Eq("LastName", "O'Brien"),
+//			soql.Gt("CreatedDate", soql.LastNDays(30)),
+//		)).
+//		OrderBy("Name", soql.Asc).
+//		Limit(10).
+//		Build()
+//
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select.htm
+package soql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Order is the sort order of an ORDER BY field.
+type Order string
+
+// Sort orders.
+const (
+	Asc            Order = "ASC"
+	Desc           Order = "DESC"
+	AscNullsFirst  Order = "ASC NULLS FIRST"
+	AscNullsLast   Order = "ASC NULLS LAST"
+	DescNullsFirst Order = "DESC NULLS FIRST"
+	DescNullsLast  Order = "DESC NULLS LAST"
+)
+
+var (
+	// fieldPattern matches field names, relationship paths and function calls such as COUNT(Id) or
+	// toLabel(Status), with an optional alias.
+	fieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*(\([A-Za-z0-9_.]*\))?( [A-Za-z_][A-Za-z0-9_]*)?$`)
+	// objectPattern matches SObject and relationship names.
+	objectPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+type orderBy struct {
+	field string
+	order Order
+}
+
+// Query is a SOQL query under construction. Methods modify the Query in place and return it to allow chaining.
+type Query struct {
+	fields     []string
+	subqueries []*Query
+	from       string
+	where      Condition
+	groupBy    []string
+	having     Condition
+	orderBy    []orderBy
+	limit      int
+	offset     int
+	forClause  string
+}
+
+// Select starts a query selecting the given fields.
+func Select(fields ...string) *Query {
+	return &Query{
+		fields: fields,
+	}
+}
+
+// SelectSubquery adds a child relationship subquery, such as (SELECT Id FROM Contacts), to the selected fields.
+func (q *Query) SelectSubquery(subquery *Query) *Query {
+	q.subqueries = append(q.subqueries, subquery)
+	return q
+}
+
+// From sets the SObject, or for subqueries the child relationship, to query.
+func (q *Query) From(object string) *Query {
+	q.from = object
+	return q
+}
+
+// Where sets the condition records must match. Use And to combine several conditions.
+func (q *Query) Where(cond Condition) *Query {
+	q.where = cond
+	return q
+}
+
+// GroupBy sets the fields to group aggregate results by.
+func (q *Query) GroupBy(fields ...string) *Query {
+	q.groupBy = fields
+	return q
+}
+
+// Having sets the condition grouped results must match.
+func (q *Query) Having(cond Condition) *Query {
+	q.having = cond
+	return q
+}
+
+// OrderBy adds a field to sort by. Fields are sorted by in the order they are added.
+func (q *Query) OrderBy(field string, order Order) *Query {
+	q.orderBy = append(q.orderBy, orderBy{field, order})
+	return q
+}
+
+// Limit sets the maximum number of records returned. Zero means no limit.
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+// Offset sets the number of records to skip.
+func (q *Query) Offset(offset int) *Query {
+	q.offset = offset
+	return q
+}
+
+// ForView marks the records as viewed, updating their LastViewedDate.
+func (q *Query) ForView() *Query {
+	q.forClause = "FOR VIEW"
+	return q
+}
+
+// ForReference marks the records as referenced, updating their LastReferencedDate.
+func (q *Query) ForReference() *Query {
+	q.forClause = "FOR REFERENCE"
+	return q
+}
+
+// ForUpdate locks the records for the duration of the transaction.
+func (q *Query) ForUpdate() *Query {
+	q.forClause = "FOR UPDATE"
+	return q
+}
+
+// Build returns the SOQL query string. An error is returned if the query is incomplete, a field or object name is
+// malformed or a value can't be bound.
+func (q *Query) Build() (string, error) {
+	var b strings.Builder
+
+	err := q.build(&b)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// String returns the SOQL query string, or an empty string if the query can't be built.
+func (q *Query) String() string {
+	s, _ := q.Build()
+	return s
+}
+
+func (q *Query) build(b *strings.Builder) error {
+	if len(q.fields) == 0 && len(q.subqueries) == 0 {
+		return fmt.Errorf("soql: no fields selected")
+	}
+
+	if !objectPattern.MatchString(q.from) {
+		return fmt.Errorf("soql: invalid object name %q", q.from)
+	}
+
+	err := checkFields(q.fields)
+	if err != nil {
+		return err
+	}
+
+	b.WriteString("SELECT ")
+	b.WriteString(strings.Join(q.fields, ", "))
+
+	for i, sub := range q.subqueries {
+		if i > 0 || len(q.fields) > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString("(")
+		err = sub.build(b)
+		if err != nil {
+			return err
+		}
+		b.WriteString(")")
+	}
+
+	b.WriteString(" FROM ")
+	b.WriteString(q.from)
+
+	if q.where != nil {
+		b.WriteString(" WHERE ")
+		err = q.where.build(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(q.groupBy) > 0 {
+		err = checkFields(q.groupBy)
+		if err != nil {
+			return err
+		}
+
+		b.WriteString(" GROUP BY ")
+		b.WriteString(strings.Join(q.groupBy, ", "))
+	}
+
+	if q.having != nil {
+		if len(q.groupBy) == 0 {
+			return fmt.Errorf("soql: HAVING requires GROUP BY")
+		}
+
+		b.WriteString(" HAVING ")
+		err = q.having.build(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = q.buildOrderAndLimit(b)
+	if err != nil {
+		return err
+	}
+
+	if len(q.forClause) > 0 {
+		b.WriteString(" ")
+		b.WriteString(q.forClause)
+	}
+
+	return nil
+}
+
+// buildOrderAndLimit writes the ORDER BY, LIMIT and OFFSET clauses.
+func (q *Query) buildOrderAndLimit(b *strings.Builder) error {
+	for i, o := range q.orderBy {
+		err := checkFields([]string{o.field})
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(o.field)
+		if len(o.order) > 0 {
+			b.WriteString(" ")
+			b.WriteString(string(o.order))
+		}
+	}
+
+	if q.limit > 0 {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.Itoa(q.limit))
+	}
+
+	if q.offset > 0 {
+		b.WriteString(" OFFSET ")
+		b.WriteString(strconv.Itoa(q.offset))
+	}
+
+	return nil
+}
+
+func checkFields(fields []string) error {
+	for _, field := range fields {
+		if !fieldPattern.MatchString(field) {
+			return fmt.Errorf("soql: invalid field %q", field)
+		}
+	}
+
+	return nil
+}
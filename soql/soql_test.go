@@ -0,0 +1,68 @@
+package soql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_Build(t *testing.T) {
+	assert := assert.New(t)
+
+	query, err := Select("Id", "Name", "Account.Name").
+		SelectSubquery(Select("Id").From("Contacts").Where(Eq("IsDeleted", false)).Limit(5)).
+		From("Account").
+		Where(And(
+			Eq("Name", "O'Brien"),
+			Or(Gt("CreatedDate", LastNDays(30)), Eq("OwnerId", nil)),
+			In("Type", []string{"Customer", "Partner"}),
+			Not(Like("Phone", "%"+EscapeLike("555_")+"%")),
+			Includes("Languages__c", "English", "Spanish"),
+			In("Id", Select("AccountId").From("Contact")),
+		)).
+		OrderBy("Name", Asc).
+		OrderBy("CreatedDate", DescNullsLast).
+		Limit(10).
+		Offset(20).
+		ForView().
+		Build()
+	assert.NoError(err)
+	assert.Equal("SELECT Id, Name, Account.Name, (SELECT Id FROM Contacts WHERE IsDeleted = false LIMIT 5) FROM Account "+
+		`WHERE (Name = 'O\'Brien' AND (CreatedDate > LAST_N_DAYS:30 OR OwnerId = null) AND Type IN ('Customer', 'Partner') `+
+		`AND (NOT Phone LIKE '%555\_%') AND Languages__c INCLUDES ('English', 'Spanish') AND Id IN (SELECT AccountId FROM Contact)) `+
+		"ORDER BY Name ASC, CreatedDate DESC NULLS LAST LIMIT 10 OFFSET 20 FOR VIEW", query)
+}
+
+func TestQuery_Build_aggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	query, err := Select("LeadSource", "COUNT(Id) total").
+		From("Lead").
+		GroupBy("LeadSource").
+		Having(Gt("COUNT(Id)", 100)).
+		ForReference().
+		Build()
+	assert.NoError(err)
+	assert.Equal("SELECT LeadSource, COUNT(Id) total FROM Lead GROUP BY LeadSource HAVING COUNT(Id) > 100 FOR REFERENCE", query)
+}
+
+func TestQuery_Build_invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []*Query{
+		Select().From("Account"),
+		Select("Id").From("Account WHERE Id != null"),
+		Select("Id FROM User --").From("Account"),
+		Select("Id").From("Account").Where(In("Id", "001")),
+		Select("Id").From("Account").Where(In("Id", []string{})),
+		Select("Id").From("Account").Where(And()),
+		Select("Id").From("Account").Having(Gt("COUNT(Id)", 1)),
+		Select("Id").From("Account").OrderBy("Name; DELETE", Asc),
+	}
+
+	for _, q := range queries {
+		_, err := q.Build()
+		assert.Error(err)
+		assert.Empty(q.String())
+	}
+}
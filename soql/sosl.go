@@ -0,0 +1,200 @@
+package soql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SearchGroup is the scope of fields searched by a SOSL query.
+type SearchGroup string
+
+// Search groups.
+const (
+	AllFields     SearchGroup = "ALL FIELDS"
+	NameFields    SearchGroup = "NAME FIELDS"
+	EmailFields   SearchGroup = "EMAIL FIELDS"
+	PhoneFields   SearchGroup = "PHONE FIELDS"
+	SidebarFields SearchGroup = "SIDEBAR FIELDS"
+)
+
+// soslReserved are the characters with a special meaning in SOSL search terms.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_sosl_find.htm
+const soslReserved = `?&|!{}[]()^~*:\"'+-`
+
+// EscapeSOSL escapes the reserved characters of SOSL in s, so s is searched for literally. The result can be used
+// in FIND clauses and as the search term of parameterized searches.
+func EscapeSOSL(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		if strings.ContainsRune(soslReserved, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Search is a SOSL query under construction. Methods modify the Search in place and return it to allow chaining.
+//
+//	search, err := soql.Find("O'Brien").
+//		In(soql.NameFields).
+//		Returning(soql.Select("Id", "Name").From("Contact").Limit(10)).
+//		Returning(soql.Select().From("Lead")).
+//		Build()
+//
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_sosl_syntax.htm
+type Search struct {
+	terms     string
+	in        SearchGroup
+	returning []*Query
+	limit     int
+}
+
+// Find starts a search for term. Reserved characters in term are escaped, so it is searched for literally.
+func Find(term string) *Search {
+	return FindExpression(EscapeSOSL(term))
+}
+
+// FindExpression starts a search for a SOSL search expression, which may use operators such as AND and OR and the
+// wildcards * and ?. User input must be escaped with EscapeSOSL before it is embedded in expr.
+func FindExpression(expr string) *Search {
+	return &Search{
+		terms: expr,
+	}
+}
+
+// In sets the fields searched. All fields are searched by default.
+func (s *Search) In(group SearchGroup) *Search {
+	s.in = group
+	return s
+}
+
+// Returning adds an SObject to return matching records of. Only the fields, WHERE, ORDER BY, LIMIT and OFFSET
+// clauses of q are used; select no fields to return only record IDs.
+func (s *Search) Returning(q *Query) *Search {
+	s.returning = append(s.returning, q)
+	return s
+}
+
+// Limit sets the maximum number of records returned across all SObjects. Zero means no limit.
+func (s *Search) Limit(limit int) *Search {
+	s.limit = limit
+	return s
+}
+
+// Build returns the SOSL query string. An error is returned if the search is empty, a field or object name is
+// malformed or a value can't be bound.
+func (s *Search) Build() (string, error) {
+	if len(strings.TrimSpace(s.terms)) == 0 {
+		return "", fmt.Errorf("soql: empty search")
+	}
+
+	var b strings.Builder
+
+	b.WriteString("FIND {")
+	b.WriteString(escapeBraces(s.terms))
+	b.WriteString("}")
+
+	if len(s.in) > 0 {
+		b.WriteString(" IN ")
+		b.WriteString(string(s.in))
+	}
+
+	for i, q := range s.returning {
+		if i == 0 {
+			b.WriteString(" RETURNING ")
+		} else {
+			b.WriteString(", ")
+		}
+
+		err := q.buildReturning(&b)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if s.limit > 0 {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.Itoa(s.limit))
+	}
+
+	return b.String(), nil
+}
+
+// String returns the SOSL query string, or an empty string if the search can't be built.
+func (s *Search) String() string {
+	str, _ := s.Build()
+	return str
+}
+
+// escapeBraces escapes unescaped braces in a search expression, which would otherwise end the FIND clause early.
+func escapeBraces(expr string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+
+		switch {
+		case c == '\\' && i+1 < len(expr):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(expr[i])
+			continue
+		case c == '{' || c == '}':
+			b.WriteByte('\\')
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// buildReturning writes the query as a RETURNING item, such as Account(Id, Name WHERE Industry = 'Energy' LIMIT 5).
+func (q *Query) buildReturning(b *strings.Builder) error {
+	if !objectPattern.MatchString(q.from) {
+		return fmt.Errorf("soql: invalid object name %q", q.from)
+	}
+
+	if len(q.subqueries) > 0 || len(q.groupBy) > 0 || q.having != nil || len(q.forClause) > 0 {
+		return fmt.Errorf("soql: RETURNING %s only supports fields, WHERE, ORDER BY, LIMIT and OFFSET", q.from)
+	}
+
+	err := checkFields(q.fields)
+	if err != nil {
+		return err
+	}
+
+	b.WriteString(q.from)
+
+	if len(q.fields) == 0 {
+		if q.where != nil || len(q.orderBy) > 0 || q.limit > 0 || q.offset > 0 {
+			return fmt.Errorf("soql: RETURNING %s requires fields to filter, sort or limit", q.from)
+		}
+
+		return nil
+	}
+
+	b.WriteString("(")
+	b.WriteString(strings.Join(q.fields, ", "))
+
+	if q.where != nil {
+		b.WriteString(" WHERE ")
+		err = q.where.build(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = q.buildOrderAndLimit(b)
+	if err != nil {
+		return err
+	}
+
+	b.WriteString(")")
+
+	return nil
+}
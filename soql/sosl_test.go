@@ -0,0 +1,49 @@
+package soql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeSOSL(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`O\'Brien \& Sons \- \{test\}\*`, EscapeSOSL(`O'Brien & Sons - {test}*`))
+	assert.Equal(`C\:\\temp`, EscapeSOSL(`C:\temp`))
+}
+
+func TestSearch_Build(t *testing.T) {
+	assert := assert.New(t)
+
+	search, err := Find("O'Brien & Co").
+		In(NameFields).
+		Returning(Select("Id", "Name").From("Contact").Where(Eq("IsDeleted", false)).OrderBy("Name", Asc).Limit(10)).
+		Returning(Select().From("Lead")).
+		Limit(20).
+		Build()
+	assert.NoError(err)
+	assert.Equal(`FIND {O\'Brien \& Co} IN NAME FIELDS RETURNING Contact(Id, Name WHERE IsDeleted = false ORDER BY Name ASC LIMIT 10), Lead LIMIT 20`, search)
+
+	search, err = FindExpression(`acme* OR "road runner" OR }`).Build()
+	assert.NoError(err)
+	assert.Equal(`FIND {acme* OR "road runner" OR \}}`, search)
+}
+
+func TestSearch_Build_invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	searches := []*Search{
+		Find(" "),
+		Find("acme").Returning(Select("Id").From("Account Foo")),
+		Find("acme").Returning(Select().From("Account").Limit(5)),
+		Find("acme").Returning(Select("Id").From("Account").GroupBy("Name")),
+		Find("acme").Returning(Select("Id; DELETE").From("Account")),
+	}
+
+	for _, search := range searches {
+		_, err := search.Build()
+		assert.Error(err)
+		assert.Empty(search.String())
+	}
+}
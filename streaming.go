@@ -0,0 +1,500 @@
+package simpleforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Replay IDs with special meaning when subscribing to a channel.
+const (
+	// ReplayNew receives only events published after subscribing.
+	ReplayNew int64 = -1
+	// ReplayAll receives all events retained by salesforce, followed by new events.
+	ReplayAll int64 = -2
+)
+
+// Bounds of the delay between handshakes when salesforce keeps dropping the session.
+const (
+	streamingMinBackoff = time.Second
+	streamingMaxBackoff = time.Minute
+)
+
+// streamingHandshakeAttempts is the number of consecutive handshakes salesforce may reject before Run gives up, as
+// the credentials are then unlikely to be accepted again.
+const streamingHandshakeAttempts = 5
+
+// Bayeux meta channels.
+const (
+	channelHandshake   = "/meta/handshake"
+	channelConnect     = "/meta/connect"
+	channelSubscribe   = "/meta/subscribe"
+	channelUnsubscribe = "/meta/unsubscribe"
+)
+
+// StreamingEvent is an event received from the streaming API.
+type StreamingEvent struct {
+	Channel     string
+	ReplayID    int64
+	CreatedDate time.Time
+	// Type is the operation of a PushTopic event: created, updated, deleted or undeleted. It is empty for other
+	// events.
+	Type string
+	// SObject is the record of a PushTopic event, or the payload of an event with an object payload such as a
+	// platform event or change event. It is nil for generic events, whose payload is a string.
+	SObject *SObject
+	// Data is the raw data of the event.
+	Data json.RawMessage
+}
+
+// StreamingClient subscribes to channels of the streaming API, such as PushTopics (/topic/Name), generic streaming
+// channels (/u/Name), platform events (/event/Name__e) and change events (/data/ChangeEvents), using CometD long
+// polling.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_streaming.meta/api_streaming/intro_stream.htm
+type StreamingClient struct {
+	httpClient *http.Client
+	endpoint   *url.URL
+	jar        http.CookieJar
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu            sync.Mutex
+	clientID      string
+	messageID     int
+	advice        bayeuxAdvice
+	subscriptions map[string]int64
+}
+
+type bayeuxMessage struct {
+	Channel                  string                 `json:"channel"`
+	ID                       string                 `json:"id,omitempty"`
+	ClientID                 string                 `json:"clientId,omitempty"`
+	Version                  string                 `json:"version,omitempty"`
+	MinimumVersion           string                 `json:"minimumVersion,omitempty"`
+	SupportedConnectionTypes []string               `json:"supportedConnectionTypes,omitempty"`
+	ConnectionType           string                 `json:"connectionType,omitempty"`
+	Subscription             string                 `json:"subscription,omitempty"`
+	Successful               bool                   `json:"successful,omitempty"`
+	Error                    string                 `json:"error,omitempty"`
+	Advice                   *bayeuxAdvice          `json:"advice,omitempty"`
+	Ext                      map[string]interface{} `json:"ext,omitempty"`
+	Data                     json.RawMessage        `json:"data,omitempty"`
+}
+
+type bayeuxAdvice struct {
+	Reconnect string `json:"reconnect,omitempty"`
+	Interval  int    `json:"interval,omitempty"`
+	Timeout   int    `json:"timeout,omitempty"`
+}
+
+type streamingData struct {
+	Event struct {
+		ReplayID    int64  `json:"replayId"`
+		CreatedDate string `json:"createdDate"`
+		Type        string `json:"type"`
+	} `json:"event"`
+	SObject *SObject        `json:"sobject"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewStreamingClient creates a new StreamingClient. httpClient must be authenticated like the one given to
+// NewHTTPClient; long polling requests last up to two minutes, so its timeout must be longer than that.
+func NewStreamingClient(httpClient *http.Client, baseURL, apiVersion string) *StreamingClient {
+	endpoint, _ := url.Parse(strings.TrimSuffix(baseURL, "/") + "/cometd/" + strings.TrimPrefix(apiVersion, "v"))
+
+	// The streaming API requires the cookies of the handshake to be sent with later requests.
+	var jar http.CookieJar
+	if httpClient.Jar == nil {
+		jar, _ = cookiejar.New(nil)
+	}
+
+	return &StreamingClient{
+		httpClient:    httpClient,
+		endpoint:      endpoint,
+		jar:           jar,
+		minBackoff:    streamingMinBackoff,
+		maxBackoff:    streamingMaxBackoff,
+		subscriptions: make(map[string]int64),
+	}
+}
+
+// Subscribe subscribes to a channel, receiving the events after replayID, or as selected by ReplayNew and
+// ReplayAll. If Run is running, the subscription is sent immediately; otherwise it is sent when Run connects. If the
+// session was dropped, the subscription is sent when Run handshakes again.
+func (s *StreamingClient) Subscribe(ctx context.Context, channel string, replayID int64) error {
+	s.mu.Lock()
+	s.subscriptions[channel] = replayID
+	clientID := s.clientID
+	s.mu.Unlock()
+
+	if len(clientID) == 0 {
+		return nil
+	}
+
+	err := s.subscribe(ctx, clientID, channel, replayID)
+	if _, ok := err.(errRehandshake); ok {
+		return nil
+	}
+
+	return err
+}
+
+// Unsubscribe unsubscribes from a channel.
+func (s *StreamingClient) Unsubscribe(ctx context.Context, channel string) error {
+	s.mu.Lock()
+	delete(s.subscriptions, channel)
+	clientID := s.clientID
+	s.mu.Unlock()
+
+	if len(clientID) == 0 {
+		return nil
+	}
+
+	_, err := s.call(ctx, &bayeuxMessage{
+		Channel:      channelUnsubscribe,
+		ClientID:     clientID,
+		Subscription: channel,
+	})
+
+	return err
+}
+
+// ReplayID returns the replay ID a channel resumes from: the ID of the last event delivered, or the replay ID given
+// to Subscribe if none was.
+func (s *StreamingClient) ReplayID(channel string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replayID, ok := s.subscriptions[channel]
+	return replayID, ok
+}
+
+// Run connects to the streaming API and sends the events of the subscribed channels to events until ctx is done or
+// an error occurs. Events are received no faster than they are read from events, so its capacity bounds the events
+// buffered in memory. When salesforce drops the session or rejects the credentials, such as on a 401 or 403
+// response or a "402::Unknown client" error on any channel, Run handshakes again and resubscribes after the last
+// delivered events. Repeated failures are retried with exponential backoff, from one second up to one minute
+// between handshakes. If 5 handshakes in a row are rejected, such as for a revoked token or a user without the
+// Streaming API permission, Run returns the last rejection. Run can be called again after it returns, resuming the
+// same way.
+func (s *StreamingClient) Run(ctx context.Context, events chan<- *StreamingEvent) error {
+	connected := false
+	rejected := 0
+	var backoff time.Duration
+
+	for {
+		var msgs []*bayeuxMessage
+		var err error
+
+		if connected {
+			msgs, err = s.connect(ctx)
+		} else {
+			err = s.handshake(ctx)
+		}
+
+		if rehandshake, ok := err.(errRehandshake); ok {
+			if !connected {
+				rejected++
+				if rejected >= streamingHandshakeAttempts {
+					return rehandshake.cause
+				}
+			}
+			connected = false
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			backoff *= 2
+			if backoff < s.minBackoff {
+				backoff = s.minBackoff
+			}
+			if backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !connected {
+			connected = true
+			rejected = 0
+			continue
+		}
+		backoff = 0
+
+		for _, msg := range msgs {
+			if strings.HasPrefix(msg.Channel, "/meta/") {
+				continue
+			}
+
+			event, err := decodeStreamingEvent(msg)
+			if err != nil {
+				return err
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			s.mu.Lock()
+			if _, ok := s.subscriptions[event.Channel]; ok {
+				s.subscriptions[event.Channel] = event.ReplayID
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// errRehandshake signals that salesforce dropped the session or rejected the credentials, and a new handshake is
+// needed. cause is the error salesforce returned.
+type errRehandshake struct {
+	cause error
+}
+
+func (e errRehandshake) Error() string {
+	return e.cause.Error()
+}
+
+// handshake opens a session and subscribes to the channels.
+func (s *StreamingClient) handshake(ctx context.Context) error {
+	s.mu.Lock()
+	s.clientID = ""
+	s.mu.Unlock()
+
+	msgs, err := s.call(ctx, &bayeuxMessage{
+		Channel:                  channelHandshake,
+		Version:                  "1.0",
+		MinimumVersion:           "1.0",
+		SupportedConnectionTypes: []string{"long-polling"},
+		Ext:                      map[string]interface{}{"replay": true},
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.clientID = msgs[0].ClientID
+	if msgs[0].Advice != nil {
+		s.advice = *msgs[0].Advice
+	}
+	clientID := s.clientID
+	subscriptions := make(map[string]int64, len(s.subscriptions))
+	for channel, replayID := range s.subscriptions {
+		subscriptions[channel] = replayID
+	}
+	s.mu.Unlock()
+
+	for channel, replayID := range subscriptions {
+		err = s.subscribe(ctx, clientID, channel, replayID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *StreamingClient) subscribe(ctx context.Context, clientID, channel string, replayID int64) error {
+	_, err := s.call(ctx, &bayeuxMessage{
+		Channel:      channelSubscribe,
+		ClientID:     clientID,
+		Subscription: channel,
+		Ext: map[string]interface{}{
+			"replay": map[string]int64{channel: replayID},
+		},
+	})
+
+	return err
+}
+
+// connect long polls for events, after waiting the interval advised by salesforce.
+func (s *StreamingClient) connect(ctx context.Context) ([]*bayeuxMessage, error) {
+	s.mu.Lock()
+	clientID := s.clientID
+	interval := time.Duration(s.advice.Interval) * time.Millisecond
+	s.mu.Unlock()
+
+	if interval > 0 {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	msgs, err := s.send(ctx, &bayeuxMessage{
+		Channel:        channelConnect,
+		ClientID:       clientID,
+		ConnectionType: "long-polling",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range msgs {
+		if msg.Channel != channelConnect {
+			continue
+		}
+
+		if msg.Advice != nil {
+			s.mu.Lock()
+			s.advice = *msg.Advice
+			s.mu.Unlock()
+		}
+
+		if !msg.Successful {
+			if unknownClient(msg) {
+				return nil, errRehandshake{ErrStreaming{channelConnect, msg.Error}}
+			}
+
+			if msg.Advice == nil || msg.Advice.Reconnect != "retry" {
+				return nil, ErrStreaming{channelConnect, msg.Error}
+			}
+		}
+	}
+
+	return msgs, nil
+}
+
+// unknownClient reports whether a failed message means the session was dropped.
+func unknownClient(msg *bayeuxMessage) bool {
+	if msg.Advice != nil && msg.Advice.Reconnect == "handshake" {
+		return true
+	}
+
+	code := strings.SplitN(msg.Error, "::", 2)[0]
+
+	return code == "401" || code == "402" || code == "403"
+}
+
+// call sends a meta message and returns its response, or ErrStreaming if it wasn't successful.
+func (s *StreamingClient) call(ctx context.Context, msg *bayeuxMessage) ([]*bayeuxMessage, error) {
+	msgs, err := s.send(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range msgs {
+		if res.Channel == msg.Channel && (len(res.ID) == 0 || res.ID == msg.ID) {
+			if !res.Successful {
+				channel := msg.Channel
+				if len(msg.Subscription) > 0 {
+					channel = msg.Subscription
+				}
+
+				if unknownClient(res) {
+					return nil, errRehandshake{ErrStreaming{channel, res.Error}}
+				}
+
+				return nil, ErrStreaming{channel, res.Error}
+			}
+
+			return []*bayeuxMessage{res}, nil
+		}
+	}
+
+	return nil, ErrStreaming{msg.Channel, "no response"}
+}
+
+// send posts a message to the CometD endpoint and returns the messages of the response.
+func (s *StreamingClient) send(ctx context.Context, msg *bayeuxMessage) ([]*bayeuxMessage, error) {
+	s.mu.Lock()
+	s.messageID++
+	msg.ID = strconv.Itoa(s.messageID)
+	s.mu.Unlock()
+
+	reqData, err := json.Marshal([]*bayeuxMessage{msg})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint.String(), bytes.NewReader(reqData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if s.jar != nil {
+		for _, cookie := range s.jar.Cookies(s.endpoint) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if s.jar != nil {
+		s.jar.SetCookies(s.endpoint, res.Cookies())
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return nil, errRehandshake{parseSalesforceError(res.StatusCode, body)}
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return nil, parseSalesforceError(res.StatusCode, body)
+	}
+
+	var msgs []*bayeuxMessage
+
+	err = json.Unmarshal(body, &msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+func decodeStreamingEvent(msg *bayeuxMessage) (*StreamingEvent, error) {
+	data := &streamingData{}
+
+	err := json.Unmarshal(msg.Data, data)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &StreamingEvent{
+		Channel:  msg.Channel,
+		ReplayID: data.Event.ReplayID,
+		Type:     data.Event.Type,
+		SObject:  data.SObject,
+		Data:     msg.Data,
+	}
+
+	if createdDate, err := time.Parse(time.RFC3339Nano, data.Event.CreatedDate); err == nil {
+		event.CreatedDate = createdDate
+	}
+
+	if event.SObject == nil && len(data.Payload) > 0 && data.Payload[0] == '{' {
+		err = json.Unmarshal(data.Payload, &event.SObject)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return event, nil
+}
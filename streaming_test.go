@@ -0,0 +1,230 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamingClient_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	handshakes := 0
+	connects := 0
+	var replayIDs []float64
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/cometd/43.0", r.URL.Path)
+
+		var msgs []map[string]interface{}
+		assert.NoError(json.NewDecoder(r.Body).Decode(&msgs))
+		msg := msgs[0]
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		var res []map[string]interface{}
+
+		switch msg["channel"] {
+		case "/meta/handshake":
+			handshakes++
+			http.SetCookie(w, &http.Cookie{Name: "BAYEUX_BROWSER", Value: "browser"})
+			res = []map[string]interface{}{{"channel": "/meta/handshake", "id": msg["id"], "clientId": "client", "successful": true}}
+		case "/meta/subscribe":
+			replay := msg["ext"].(map[string]interface{})["replay"].(map[string]interface{})
+			replayIDs = append(replayIDs, replay["/topic/Cases"].(float64))
+			res = []map[string]interface{}{{"channel": "/meta/subscribe", "id": msg["id"], "subscription": "/topic/Cases", "successful": true}}
+		case "/meta/connect":
+			cookie, err := r.Cookie("BAYEUX_BROWSER")
+			assert.NoError(err)
+			assert.Equal("browser", cookie.Value)
+
+			connects++
+			switch connects {
+			case 1:
+				res = []map[string]interface{}{
+					{"channel": "/topic/Cases", "data": map[string]interface{}{
+						"event":   map[string]interface{}{"replayId": 5, "createdDate": "2021-03-04T05:06:07.000Z", "type": "updated"},
+						"sobject": map[string]interface{}{"Id": "500000000000001AAA", "Subject": "Broken"},
+					}},
+					{"channel": "/meta/connect", "id": msg["id"], "successful": true},
+				}
+			case 2:
+				res = []map[string]interface{}{{"channel": "/meta/connect", "id": msg["id"], "successful": false, "error": "403::Unknown client"}}
+			case 3:
+				res = []map[string]interface{}{
+					{"channel": "/topic/Cases", "data": map[string]interface{}{
+						"event":   map[string]interface{}{"replayId": 6, "createdDate": "2021-03-04T05:06:08.000Z", "type": "created"},
+						"sobject": map[string]interface{}{"Id": "500000000000002AAA", "Subject": "New"},
+					}},
+					{"channel": "/meta/connect", "id": msg["id"], "successful": true},
+				}
+			default:
+				mu.Unlock()
+				<-r.Context().Done()
+				mu.Lock()
+				return
+			}
+		}
+
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer ts.Close()
+
+	client := NewStreamingClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.NoError(client.Subscribe(ctx, "/topic/Cases", ReplayNew))
+
+	events := make(chan *StreamingEvent)
+	done := make(chan error)
+	go func() {
+		done <- client.Run(ctx, events)
+	}()
+
+	event := <-events
+	assert.Equal("/topic/Cases", event.Channel)
+	assert.Equal(int64(5), event.ReplayID)
+	assert.Equal("updated", event.Type)
+	assert.Equal("Broken", event.SObject.StringField("Subject"))
+	assert.Equal(2021, event.CreatedDate.Year())
+
+	event = <-events
+	assert.Equal(int64(6), event.ReplayID)
+	assert.Equal("500000000000002AAA", event.SObject.ID())
+
+	cancel()
+	assert.ErrorIs(<-done, context.Canceled)
+
+	replayID, ok := client.ReplayID("/topic/Cases")
+	assert.True(ok)
+	assert.Equal(int64(6), replayID)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(2, handshakes)
+	assert.Equal([]float64{-1, 5}, replayIDs)
+}
+
+func TestStreamingClient_Run_rehandshake(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	handshakes := 0
+	subscribes := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msgs []map[string]interface{}
+		assert.NoError(json.NewDecoder(r.Body).Decode(&msgs))
+		msg := msgs[0]
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		var res []map[string]interface{}
+
+		switch msg["channel"] {
+		case "/meta/handshake":
+			handshakes++
+			switch handshakes {
+			case 1:
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			case 2:
+				res = []map[string]interface{}{{"channel": "/meta/handshake", "id": msg["id"], "successful": false, "error": "403::Handshake denied"}}
+			default:
+				res = []map[string]interface{}{{"channel": "/meta/handshake", "id": msg["id"], "clientId": "client", "successful": true}}
+			}
+		case "/meta/subscribe":
+			subscribes++
+			if subscribes == 1 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			res = []map[string]interface{}{{"channel": "/meta/subscribe", "id": msg["id"], "subscription": "/topic/Cases", "successful": true}}
+		case "/meta/connect":
+			res = []map[string]interface{}{
+				{"channel": "/topic/Cases", "data": map[string]interface{}{
+					"event":   map[string]interface{}{"replayId": 7, "createdDate": "2021-03-04T05:06:07.000Z", "type": "updated"},
+					"sobject": map[string]interface{}{"Id": "500000000000001AAA"},
+				}},
+				{"channel": "/meta/connect", "id": msg["id"], "successful": true},
+			}
+		}
+
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer ts.Close()
+
+	client := NewStreamingClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	client.minBackoff = time.Millisecond
+	client.maxBackoff = 2 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.NoError(client.Subscribe(ctx, "/topic/Cases", ReplayNew))
+
+	events := make(chan *StreamingEvent)
+	done := make(chan error)
+	go func() {
+		done <- client.Run(ctx, events)
+	}()
+
+	// Rejected handshakes and subscriptions are retried.
+	event := <-events
+	assert.Equal(int64(7), event.ReplayID)
+
+	cancel()
+	assert.ErrorIs(<-done, context.Canceled)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(4, handshakes)
+	assert.Equal(2, subscribes)
+}
+
+func TestStreamingClient_Run_handshakeFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"channel": "/meta/handshake", "successful": false, "error": "400::Bad request"}]`))
+	}))
+	defer ts.Close()
+
+	client := NewStreamingClient(ts.Client(), ts.URL, DefaultAPIVersion)
+
+	err := client.Run(context.Background(), make(chan *StreamingEvent))
+	assert.Equal(ErrStreaming{"/meta/handshake", "400::Bad request"}, err)
+}
+
+func TestStreamingClient_Run_credentialsRejected(t *testing.T) {
+	assert := assert.New(t)
+
+	handshakes := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handshakes++
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`[{"message": "Session expired or invalid", "errorCode": "INVALID_SESSION_ID"}]`))
+	}))
+	defer ts.Close()
+
+	client := NewStreamingClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	client.minBackoff = time.Millisecond
+	client.maxBackoff = 2 * time.Millisecond
+
+	// Handshakes that keep being rejected aren't retried forever.
+	err := client.Run(context.Background(), make(chan *StreamingEvent))
+	assert.Equal(ErrAPI{StatusCode: http.StatusUnauthorized, ErrorCode: "INVALID_SESSION_ID", Message: "Session expired or invalid"}, err)
+	assert.Equal(streamingHandshakeAttempts, handshakes)
+}
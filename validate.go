@@ -0,0 +1,296 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// SObjectDescriber describes SObjects. Client implements SObjectDescriber.
+type SObjectDescriber interface {
+	DescribeSObject(ctx context.Context, sobj *SObject) (*SObjectMeta, error)
+}
+
+// Describer describes single SObjects and lists all available SObjects. Client and DescribeCache implement Describer.
+type Describer interface {
+	SObjectDescriber
+	GlobalDescriber
+}
+
+// Validator checks SObjects against their describe metadata before they are written to salesforce, catching errors
+// that would otherwise cost a round trip, and drops the fields that can't be written. Pass a DescribeCache as the
+// describer to avoid describing SObjects on every validation. Set it on an HTTPClient with SetValidator to validate
+// every write.
+type Validator struct {
+	describer Describer
+	resolver  *TypeResolver
+	strict    bool
+}
+
+// NewValidator creates a new Validator. If strict is true, fields that can't be written are rejected instead of
+// dropped, and values of unrestricted picklists must be one of the active picklist values.
+func NewValidator(describer Describer, strict bool) *Validator {
+	return &Validator{
+		describer: describer,
+		resolver:  NewTypeResolver(describer),
+		strict:    strict,
+	}
+}
+
+// writeOperation is the kind of write an SObject is validated for.
+type writeOperation int
+
+const (
+	writeCreate writeOperation = iota
+	writeUpdate
+	writeUpsert
+)
+
+// ValidateCreate validates SObject for CreateSObject. The fields that aren't createable are returned so they can be
+// passed to CreateSObject as blacklisted fields. ErrValidation is returned if any field is invalid.
+func (v *Validator) ValidateCreate(ctx context.Context, sobj *SObject) ([]string, error) {
+	return v.validate(ctx, sobj, writeCreate)
+}
+
+// ValidateUpdate validates SObject for UpdateSObject. If the SObject is tracking changes, only the changed fields are
+// validated. The fields that aren't updateable are returned so they can be passed to UpdateSObject as blacklisted
+// fields. ErrValidation is returned if any field is invalid.
+func (v *Validator) ValidateUpdate(ctx context.Context, sobj *SObject) ([]string, error) {
+	return v.validate(ctx, sobj, writeUpdate)
+}
+
+// ValidateUpsert validates SObject for UpsertSObject. As the upsert may either create or update the record, fields
+// must be createable or updateable and required fields aren't checked. The fields that can't be written are returned
+// so they can be passed to UpsertSObject as blacklisted fields. ErrValidation is returned if any field is invalid.
+func (v *Validator) ValidateUpsert(ctx context.Context, sobj *SObject) ([]string, error) {
+	return v.validate(ctx, sobj, writeUpsert)
+}
+
+func (v *Validator) validate(ctx context.Context, sobj *SObject, op writeOperation) ([]string, error) {
+	if len(sobj.Type()) == 0 {
+		return nil, ErrInvalidSObject{"Type is empty"}
+	}
+
+	fields, err := v.fields(ctx, sobj.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	if changed := sobj.ChangedFields(); op == writeUpdate && changed != nil {
+		keys = changed
+	} else {
+		for key := range *sobj {
+			if !isMetadataKey(key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+	}
+
+	var dropped []string
+	errs := ErrValidation{}
+
+	for _, key := range keys {
+		field, ok := fields[strings.ToLower(key)]
+		if !ok {
+			errs.add(key, "no such field on "+sobj.Type())
+			continue
+		}
+
+		var writeable bool
+		var operation string
+		switch op {
+		case writeCreate:
+			writeable, operation = field.Createable, "createable"
+		case writeUpdate:
+			writeable, operation = field.Updateable, "updateable"
+		default:
+			writeable, operation = field.Createable || field.Updateable, "createable or updateable"
+		}
+
+		if !writeable {
+			if v.strict {
+				errs.add(key, "field is not "+operation)
+			} else {
+				dropped = append(dropped, key)
+			}
+			continue
+		}
+
+		val := (*sobj)[key]
+		if val == nil {
+			if !field.Nillable {
+				errs.add(key, "field cannot be null")
+			}
+			continue
+		}
+
+		msg, err := v.validateValue(ctx, field, val)
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) > 0 {
+			errs.add(key, msg)
+		}
+	}
+
+	if op == writeCreate {
+		present := make(map[string]bool)
+		for key := range *sobj {
+			present[strings.ToLower(key)] = true
+		}
+
+		for name, field := range fields {
+			if isRequired(field) && !present[name] {
+				errs.add(field.Name, "required field is missing")
+			}
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		sort.Slice(errs.Errors, func(i, j int) bool {
+			return errs.Errors[i].Field < errs.Errors[j].Field
+		})
+
+		return nil, errs
+	}
+
+	return dropped, nil
+}
+
+// validateValue checks a non-nil value against the field metadata and returns a description of the problem, if any.
+func (v *Validator) validateValue(ctx context.Context, field *FieldMeta, val interface{}) (string, error) {
+	switch field.Type {
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			return "expected a boolean", nil
+		}
+
+	case "int", "double", "currency", "percent":
+		if !isNumber(val) {
+			return "expected a number", nil
+		}
+
+	case "string", "textarea", "email", "phone", "url", "combobox", "encryptedstring", "picklist", "multipicklist":
+		str, ok := val.(string)
+		if !ok {
+			return "expected a string", nil
+		}
+
+		if field.Length > 0 && utf8.RuneCountInString(str) > field.Length {
+			return fmt.Sprintf("value exceeds maximum length of %d", field.Length), nil
+		}
+
+		if field.Type == "picklist" || field.Type == "multipicklist" {
+			return v.validatePicklist(field, str), nil
+		}
+
+	case "reference":
+		return v.validateReference(ctx, field, val)
+	}
+
+	return "", nil
+}
+
+// validatePicklist checks that each value of a (multi-select) picklist is an active picklist value.
+func (v *Validator) validatePicklist(field *FieldMeta, str string) string {
+	if !field.RestrictedPicklist && !v.strict {
+		return ""
+	}
+
+	active := make(map[string]bool)
+	for _, pv := range field.PicklistValues {
+		if pv.Active {
+			active[pv.Value] = true
+		}
+	}
+
+	values := []string{str}
+	if field.Type == "multipicklist" {
+		values = strings.Split(str, ";")
+	}
+
+	for _, value := range values {
+		if !active[value] {
+			return fmt.Sprintf("%q is not a valid picklist value", value)
+		}
+	}
+
+	return ""
+}
+
+// validateReference checks that the value is an ID of one of the SObject types the field refers to. The type of the ID
+// is resolved from its key prefix, so polymorphic fields such as WhatId don't describe every type they refer to.
+func (v *Validator) validateReference(ctx context.Context, field *FieldMeta, val interface{}) (string, error) {
+	str, _ := val.(string)
+
+	id, err := ParseID(str)
+	if err != nil {
+		return "expected a salesforce ID", nil
+	}
+
+	if len(field.ReferenceTo) == 0 {
+		return "", nil
+	}
+
+	typeName, err := v.resolver.ResolveType(ctx, id)
+	if err != nil && !errors.As(err, &ErrUnknownKeyPrefix{}) {
+		return "", err
+	}
+
+	for _, referenceTo := range field.ReferenceTo {
+		if strings.EqualFold(typeName, referenceTo) {
+			return "", nil
+		}
+	}
+
+	return fmt.Sprintf("ID doesn't refer to %s", strings.Join(field.ReferenceTo, " or ")), nil
+}
+
+// fields returns the field metadata of an SObject type indexed by lower case field name.
+func (v *Validator) fields(ctx context.Context, typeName string) (map[string]*FieldMeta, error) {
+	meta, err := v.describer.DescribeSObject(ctx, NewSObject(typeName))
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := meta.Fields()
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := make(map[string]*FieldMeta, len(fields))
+	for i := range fields {
+		indexed[strings.ToLower(fields[i].Name)] = &fields[i]
+	}
+
+	return indexed, nil
+}
+
+// isRequired reports whether a value must be provided for the field when creating a record.
+func isRequired(field *FieldMeta) bool {
+	return field.Createable && !field.Nillable && !field.DefaultedOnCreate && field.Type != "boolean"
+}
+
+// isNumber reports whether val holds a numeric value.
+func isNumber(val interface{}) bool {
+	if _, ok := val.(json.Number); ok {
+		return true
+	}
+
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,179 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticDescriber map[string]string
+
+func (d staticDescriber) DescribeSObject(ctx context.Context, sobj *SObject) (*SObjectMeta, error) {
+	meta := &SObjectMeta{}
+	err := json.Unmarshal([]byte(d[sobj.Type()]), meta)
+
+	return meta, err
+}
+
+func (d staticDescriber) DescribeGlobal(ctx context.Context) (*SObjectMeta, error) {
+	var sobjects []interface{}
+	for typeName := range d {
+		meta, err := d.DescribeSObject(ctx, NewSObject(typeName))
+		if err != nil {
+			return nil, err
+		}
+		sobjects = append(sobjects, map[string]interface{}{"name": meta.Name(), "keyPrefix": meta.KeyPrefix()})
+	}
+
+	return &SObjectMeta{"sobjects": sobjects}, nil
+}
+
+var testDescriber = staticDescriber{
+	"Account": `{"name": "Account", "keyPrefix": "001", "fields": []}`,
+	"Contact": `{"name": "Contact", "keyPrefix": "003", "fields": []}`,
+	"User":    `{"name": "User", "keyPrefix": "005", "fields": []}`,
+	"Case": `{
+		"name": "Case",
+		"keyPrefix": "500",
+		"fields": [
+			{"name": "Id", "type": "id", "createable": false, "updateable": false, "nillable": false, "defaultedOnCreate": true},
+			{"name": "CaseNumber", "type": "string", "length": 30, "createable": false, "updateable": false, "nillable": false, "defaultedOnCreate": true},
+			{"name": "Subject", "type": "string", "length": 10, "createable": true, "updateable": true, "nillable": true},
+			{"name": "Origin", "type": "picklist", "length": 40, "createable": true, "updateable": true, "nillable": false,
+				"picklistValues": [{"value": "Web", "active": true}, {"value": "Fax", "active": false}]},
+			{"name": "Status", "type": "picklist", "length": 40, "createable": true, "updateable": true, "nillable": true, "defaultedOnCreate": true,
+				"restrictedPicklist": true, "picklistValues": [{"value": "New", "active": true}, {"value": "Closed", "active": true}]},
+			{"name": "IsEscalated", "type": "boolean", "createable": true, "updateable": true, "nillable": false, "defaultedOnCreate": true},
+			{"name": "Count__c", "type": "double", "createable": true, "updateable": true, "nillable": true},
+			{"name": "AccountId", "type": "reference", "referenceTo": ["Account"], "createable": true, "updateable": true, "nillable": true},
+			{"name": "OwnerId", "type": "reference", "referenceTo": ["Group", "User"], "createable": true, "updateable": true, "nillable": true, "defaultedOnCreate": true},
+			{"name": "Formula__c", "type": "string", "createable": false, "updateable": false, "nillable": true, "calculated": true}
+		]
+	}`,
+}
+
+func TestValidator_ValidateCreate(t *testing.T) {
+	assert := assert.New(t)
+
+	v := NewValidator(testDescriber, false)
+
+	sobj := NewSObject("Case").
+		Set("Subject", "foo").
+		Set("Origin", "Phone").
+		Set("Status", "New").
+		Set("AccountId", "001000000000001").
+		Set("OwnerId", "005000000000001").
+		Set("Formula__c", "bar")
+
+	dropped, err := v.ValidateCreate(context.Background(), sobj)
+	assert.NoError(err)
+	assert.Equal([]string{"Formula__c"}, dropped)
+}
+
+func TestValidator_ValidateCreate_invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	v := NewValidator(testDescriber, false)
+
+	sobj := NewSObject("Case").
+		Set("Subject", "longer than ten").
+		Set("Status", "Open").
+		Set("IsEscalated", "yes").
+		Set("Count__c", "1").
+		Set("AccountId", "003000000000001").
+		Set("OwnerId", "00G000000000001").
+		Set("Unknown__c", "baz")
+
+	_, err := v.ValidateCreate(context.Background(), sobj)
+	assert.Equal(ErrValidation{Errors: []FieldError{
+		{Field: "AccountId", Message: "ID doesn't refer to Account"},
+		{Field: "Count__c", Message: "expected a number"},
+		{Field: "IsEscalated", Message: "expected a boolean"},
+		{Field: "Origin", Message: "required field is missing"},
+		{Field: "OwnerId", Message: "ID doesn't refer to Group or User"},
+		{Field: "Status", Message: `"Open" is not a valid picklist value`},
+		{Field: "Subject", Message: "value exceeds maximum length of 10"},
+		{Field: "Unknown__c", Message: "no such field on Case"},
+	}}, err)
+}
+
+func TestValidator_ValidateUpdate_strict(t *testing.T) {
+	assert := assert.New(t)
+
+	v := NewValidator(testDescriber, true)
+
+	sobj := NewSObject("Case").
+		SetID("500000000000001").
+		Set("CaseNumber", "00001").
+		Set("Origin", "Phone")
+	sobj.ClearChanges()
+
+	// Only changed fields are validated.
+	sobj.Set("Origin", "Fax").
+		SetNull("IsEscalated")
+
+	_, err := v.ValidateUpdate(context.Background(), sobj)
+	assert.Equal(ErrValidation{Errors: []FieldError{
+		{Field: "IsEscalated", Message: "field cannot be null"},
+		{Field: "Origin", Message: `"Fax" is not a valid picklist value`},
+	}}, err)
+
+	sobj.ClearChanges()
+	sobj.Set("CaseNumber", "00002")
+
+	_, err = v.ValidateUpdate(context.Background(), sobj)
+	assert.Equal(ErrValidation{Errors: []FieldError{
+		{Field: "CaseNumber", Message: "field is not updateable"},
+	}}, err)
+}
+
+func TestHTTPClient_SetValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	var bodies []SObject
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := SObject{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		assert.NoError(err)
+		bodies = append(bodies, body)
+
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"id": "500000000000001", "success": true}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	client.SetValidator(NewValidator(testDescriber, false))
+
+	sobj := NewSObject("Case").
+		Set("Origin", "Web").
+		Set("Formula__c", "bar")
+
+	err := client.CreateSObject(context.Background(), sobj, nil, false, nil)
+	assert.NoError(err)
+
+	sobj.Set("CaseNumber", "00001").
+		Set("Subject", "foo")
+
+	err = client.UpdateSObject(context.Background(), sobj, nil, nil)
+	assert.NoError(err)
+
+	err = client.CreateSObject(context.Background(), NewSObject("Case").Set("Subject", "foo"), nil, false, nil)
+	assert.Equal(ErrValidation{Errors: []FieldError{
+		{Field: "Origin", Message: "required field is missing"},
+	}}, err)
+
+	// Fields that can't be written are dropped and invalid SObjects are never sent.
+	assert.Equal([]SObject{
+		{"Origin": "Web"},
+		{"Origin": "Web", "Subject": "foo"},
+	}, bodies)
+}
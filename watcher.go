@@ -0,0 +1,345 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleanorhealth/simpleforce/internal/atomicfile"
+	"github.com/eleanorhealth/simpleforce/soql"
+)
+
+// DefaultWatchInterval is how often ChangeWatcher polls for changes when no interval is given.
+const DefaultWatchInterval = time.Minute
+
+// DefaultWatchLookBack is how far before its checkpoint a ChangeWatcher polls again for records committed late.
+const DefaultWatchLookBack = 5 * time.Minute
+
+// watchCheckpointLimit is the number of handled records a checkpoint remembers before it shortens its look-back.
+const watchCheckpointLimit = 10000
+
+const systemModstampKey = "SystemModstamp"
+
+// WatchCheckpoint is the position of a ChangeWatcher. A record's SystemModstamp is set when it is saved but the
+// change only becomes visible when its transaction commits, so a long transaction can commit records older than
+// ones already handled. The checkpoint therefore remembers the records handled over a look-back period rather than
+// a single SystemModstamp.
+type WatchCheckpoint struct {
+	// SystemModstamp is the latest SystemModstamp handled, with the millisecond precision of salesforce.
+	SystemModstamp time.Time `json:"systemModstamp"`
+	// Since is the start of the look-back. Records modified before it are considered handled.
+	Since time.Time `json:"since"`
+	// Handled holds the SystemModstamp of the records handled since Since, by ID.
+	Handled map[string]time.Time `json:"handled"`
+}
+
+// handled reports whether the version of the record identified by id and modified at modstamp was handled.
+func (c *WatchCheckpoint) handled(id string, modstamp time.Time) bool {
+	if modstamp.Before(c.Since) {
+		return true
+	}
+
+	handledAt, ok := c.Handled[id]
+	return ok && !modstamp.After(handledAt)
+}
+
+// advance records that the version of the record identified by id and modified at modstamp was handled.
+func (c *WatchCheckpoint) advance(id string, modstamp time.Time) {
+	if c.Handled == nil {
+		c.Handled = make(map[string]time.Time)
+	}
+
+	c.Handled[id] = modstamp
+	if modstamp.After(c.SystemModstamp) {
+		c.SystemModstamp = modstamp
+	}
+}
+
+// prune moves Since to lookBack before SystemModstamp and forgets the records modified before it. If more than
+// limit records remain, Since moves up to the oldest of the newest limit records; records sharing SystemModstamp
+// are never forgotten, as records with the same SystemModstamp may still be polled.
+func (c *WatchCheckpoint) prune(lookBack time.Duration, limit int) {
+	since := c.SystemModstamp.Add(-lookBack)
+
+	if len(c.Handled) > limit {
+		modstamps := make([]time.Time, 0, len(c.Handled))
+		for _, modstamp := range c.Handled {
+			modstamps = append(modstamps, modstamp)
+		}
+		sort.Slice(modstamps, func(i, j int) bool { return modstamps[i].Before(modstamps[j]) })
+
+		if oldest := modstamps[len(modstamps)-limit]; oldest.After(since) {
+			since = oldest
+		}
+	}
+
+	if since.After(c.Since) {
+		c.Since = since
+	}
+
+	for id, modstamp := range c.Handled {
+		if modstamp.Before(c.Since) {
+			delete(c.Handled, id)
+		}
+	}
+}
+
+// CheckpointStore persists the checkpoint of a ChangeWatcher, so it resumes after the last change handled.
+type CheckpointStore interface {
+	// LoadCheckpoint returns the stored checkpoint of an SObject type. ok is false if none was stored.
+	LoadCheckpoint(ctx context.Context, typeName string) (checkpoint *WatchCheckpoint, ok bool, err error)
+	SaveCheckpoint(ctx context.Context, typeName string, checkpoint *WatchCheckpoint) error
+}
+
+// MemoryCheckpointStore is a CheckpointStore keeping checkpoints in memory, for tests and watchers that don't need
+// to resume after restarts.
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]WatchCheckpoint
+}
+
+// NewMemoryCheckpointStore creates a new empty MemoryCheckpointStore.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]WatchCheckpoint)}
+}
+
+func (s *MemoryCheckpointStore) LoadCheckpoint(ctx context.Context, typeName string) (*WatchCheckpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoint, ok := s.checkpoints[typeName]
+	if !ok {
+		return nil, false, nil
+	}
+
+	checkpoint.Handled = copyHandled(checkpoint.Handled)
+	return &checkpoint, true, nil
+}
+
+func (s *MemoryCheckpointStore) SaveCheckpoint(ctx context.Context, typeName string, checkpoint *WatchCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[typeName] = WatchCheckpoint{
+		SystemModstamp: checkpoint.SystemModstamp,
+		Since:          checkpoint.Since,
+		Handled:        copyHandled(checkpoint.Handled),
+	}
+	return nil
+}
+
+func copyHandled(handled map[string]time.Time) map[string]time.Time {
+	copied := make(map[string]time.Time, len(handled))
+	for id, modstamp := range handled {
+		copied[id] = modstamp
+	}
+
+	return copied
+}
+
+// FileCheckpointStore is a CheckpointStore keeping the checkpoints of all SObject types in a JSON file. Writes
+// replace the file atomically, so a crash never leaves it corrupt.
+type FileCheckpointStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileCheckpointStore creates a FileCheckpointStore persisting checkpoints to path.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (s *FileCheckpointStore) LoadCheckpoint(ctx context.Context, typeName string) (*WatchCheckpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoints, err := s.read()
+	if err != nil {
+		return nil, false, err
+	}
+
+	checkpoint, ok := checkpoints[typeName]
+	return checkpoint, ok, nil
+}
+
+func (s *FileCheckpointStore) SaveCheckpoint(ctx context.Context, typeName string, checkpoint *WatchCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoints, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	checkpoints[typeName] = checkpoint
+
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(s.path, data)
+}
+
+func (s *FileCheckpointStore) read() (map[string]*WatchCheckpoint, error) {
+	checkpoints := make(map[string]*WatchCheckpoint)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &checkpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+// WatchHandler processes a created or updated record. Returning an error stops the watcher; the record and the ones
+// after it are polled again when the watcher restarts.
+type WatchHandler func(ctx context.Context, record *SObject) error
+
+// ChangeWatcher polls an SObject type for records created or updated since its checkpoint, ordered by
+// SystemModstamp. It is a fallback for objects that don't support Change Data Capture; deletions aren't reported.
+//
+// Each poll goes back over the look-back period before the checkpoint, DefaultWatchLookBack unless set with
+// SetLookBack, to catch records saved by transactions that took that long to commit. Changes committed later than
+// that are missed. The look-back shortens while more than 10000 records were handled within it.
+type ChangeWatcher struct {
+	client   Client
+	store    CheckpointStore
+	typeName string
+	fields   []string
+	interval time.Duration
+	lookBack time.Duration
+	limit    int
+}
+
+// NewChangeWatcher creates a new ChangeWatcher retrieving fields of the records of typeName every interval, or
+// DefaultWatchInterval if interval is not positive. Id and SystemModstamp are always retrieved.
+func NewChangeWatcher(client Client, store CheckpointStore, typeName string, fields []string, interval time.Duration) *ChangeWatcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	selectFields := []string{sobjectIDKey, systemModstampKey}
+	for _, field := range fields {
+		if !strings.EqualFold(field, sobjectIDKey) && !strings.EqualFold(field, systemModstampKey) {
+			selectFields = append(selectFields, field)
+		}
+	}
+
+	return &ChangeWatcher{
+		client:   client,
+		store:    store,
+		typeName: typeName,
+		fields:   selectFields,
+		interval: interval,
+		lookBack: DefaultWatchLookBack,
+		limit:    watchCheckpointLimit,
+	}
+}
+
+// SetLookBack sets how far before its checkpoint the watcher polls again for records committed late.
+func (w *ChangeWatcher) SetLookBack(lookBack time.Duration) {
+	w.lookBack = lookBack
+}
+
+// Run polls for changes until ctx is done or an error occurs, passing each changed record to handler once per
+// SystemModstamp. If no checkpoint is stored, records modified at or after since are polled first.
+//
+// The checkpoint is saved after each page of records and when handler fails, so a record is only handled again if
+// the process stops in between; handlers that must never see a change twice should be idempotent.
+func (w *ChangeWatcher) Run(ctx context.Context, since time.Time, handler WatchHandler) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		err := w.Poll(ctx, since, handler)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll handles the records changed since the checkpoint once, with the same guarantees as Run.
+func (w *ChangeWatcher) Poll(ctx context.Context, since time.Time, handler WatchHandler) error {
+	checkpoint, ok, err := w.store.LoadCheckpoint(ctx, w.typeName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		checkpoint = &WatchCheckpoint{SystemModstamp: since, Since: since}
+	}
+	checkpoint.prune(w.lookBack, w.limit)
+
+	// The query covers the look-back, truncated to the second precision of SOQL datetimes; the records handled
+	// within it are skipped.
+	query, err := soql.Select(w.fields...).
+		From(w.typeName).
+		Where(soql.Ge(systemModstampKey, checkpoint.Since.Truncate(time.Second))).
+		OrderBy(systemModstampKey, soql.Asc).
+		OrderBy(sobjectIDKey, soql.Asc).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	var nextRecordsURL string
+	for {
+		result, err := w.client.Query(ctx, query, nextRecordsURL)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range result.Records {
+			modstamp, err := record.DateTimeFieldE(systemModstampKey)
+			if err != nil {
+				return err
+			}
+
+			id := record.ID()
+			if checkpoint.handled(id, modstamp) {
+				continue
+			}
+
+			err = handler(ctx, record)
+			if err != nil {
+				if saveErr := w.store.SaveCheckpoint(ctx, w.typeName, checkpoint); saveErr != nil {
+					return saveErr
+				}
+				return err
+			}
+
+			checkpoint.advance(id, modstamp)
+		}
+
+		checkpoint.prune(w.lookBack, w.limit)
+
+		err = w.store.SaveCheckpoint(ctx, w.typeName, checkpoint)
+		if err != nil {
+			return err
+		}
+
+		if result.Done || len(result.NextRecordsURL) == 0 {
+			return nil
+		}
+		nextRecordsURL = result.NextRecordsURL
+	}
+}
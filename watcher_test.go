@@ -0,0 +1,199 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func to18(id string) string {
+	return ID(id).To18().String()
+}
+
+// handledAt formats the SystemModstamps of the records handled by a checkpoint, which may be in any location.
+func handledAt(checkpoint *WatchCheckpoint) map[string]string {
+	handled := make(map[string]string, len(checkpoint.Handled))
+	for id, modstamp := range checkpoint.Handled {
+		handled[id] = modstamp.UTC().Format(time.RFC3339Nano)
+	}
+
+	return handled
+}
+
+func TestChangeWatcher_Poll(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	base := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	modstampPattern := regexp.MustCompile(`WHERE SystemModstamp >= (\S+) ORDER BY SystemModstamp ASC, Id ASC$`)
+
+	var mu sync.Mutex
+	modstamps := map[string]time.Time{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match := modstampPattern.FindStringSubmatch(r.URL.Query().Get("q"))
+		assert.NotNil(match)
+
+		since, err := time.Parse(time.RFC3339, match[1])
+		assert.NoError(err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		records := []*SObject{}
+		for id, modstamp := range modstamps {
+			if !modstamp.Before(since) {
+				records = append(records, NewSObject("Lead").SetID(id).SetDateTime("SystemModstamp", modstamp))
+			}
+		}
+		sort.Slice(records, func(i, j int) bool {
+			mi, mj := records[i].DateTimeField("SystemModstamp"), records[j].DateTimeField("SystemModstamp")
+			if !mi.Equal(mj) {
+				return mi.Before(mj)
+			}
+			return records[i].ID() < records[j].ID()
+		})
+
+		assert.NoError(json.NewEncoder(w).Encode(QueryResult{TotalSize: len(records), Done: true, Records: records}))
+	}))
+	defer ts.Close()
+
+	set := func(id string, modstamp time.Time) {
+		mu.Lock()
+		modstamps[id] = modstamp
+		mu.Unlock()
+	}
+
+	client := NewHTTPClient(ts.Client(), ts.URL, DefaultAPIVersion)
+	store := NewMemoryCheckpointStore()
+	watcher := NewChangeWatcher(client, store, "Lead", []string{"Id", "Name"}, 0)
+	assert.Equal([]string{"Id", "SystemModstamp", "Name"}, watcher.fields)
+
+	var handled []string
+	handler := func(ctx context.Context, record *SObject) error {
+		handled = append(handled, record.ID())
+		return nil
+	}
+
+	set("00Q000000000001", base.Add(-time.Hour))
+	set("00Q000000000002", base)
+	set("00Q000000000003", base.Add(time.Second))
+	set("00Q000000000004", base.Add(time.Second))
+
+	assert.NoError(watcher.Poll(ctx, base, handler))
+	assert.Equal([]string{to18("00Q000000000002"), to18("00Q000000000003"), to18("00Q000000000004")}, handled)
+
+	checkpoint, ok, err := store.LoadCheckpoint(ctx, "Lead")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.True(base.Add(time.Second).Equal(checkpoint.SystemModstamp))
+	assert.True(base.Equal(checkpoint.Since))
+	assert.Equal(map[string]string{
+		to18("00Q000000000002"): base.Format(time.RFC3339Nano),
+		to18("00Q000000000003"): base.Add(time.Second).Format(time.RFC3339Nano),
+		to18("00Q000000000004"): base.Add(time.Second).Format(time.RFC3339Nano),
+	}, handledAt(checkpoint))
+
+	// A record committed late with the checkpoint's SystemModstamp is handled, the ones already handled are not.
+	handled = nil
+	set("00Q000000000005", base.Add(time.Second))
+	set("00Q000000000002", base.Add(2*time.Second))
+
+	assert.NoError(watcher.Poll(ctx, base, handler))
+	assert.Equal([]string{to18("00Q000000000005"), to18("00Q000000000002")}, handled)
+
+	// A failing handler keeps the progress made before it.
+	handled = nil
+	errHandler := errors.New("handler failed")
+	set("00Q000000000006", base.Add(3*time.Second))
+	set("00Q000000000007", base.Add(4*time.Second))
+
+	err = watcher.Poll(ctx, base, func(ctx context.Context, record *SObject) error {
+		if record.ID() == to18("00Q000000000007") {
+			return errHandler
+		}
+		return handler(ctx, record)
+	})
+	assert.Equal(errHandler, err)
+	assert.Equal([]string{to18("00Q000000000006")}, handled)
+
+	handled = nil
+	assert.NoError(watcher.Poll(ctx, base, handler))
+	assert.Equal([]string{to18("00Q000000000007")}, handled)
+
+	// Records committed late are handled within the look-back, with millisecond precision.
+	handled = nil
+	set("00Q000000000008", base.Add(3*time.Second+500*time.Millisecond))
+	set("00Q000000000009", base.Add(4*time.Second))
+
+	assert.NoError(watcher.Poll(ctx, base, handler))
+	assert.Equal([]string{to18("00Q000000000008"), to18("00Q000000000009")}, handled)
+
+	// The look-back moves with the checkpoint, and the records modified before it are forgotten.
+	handled = nil
+	watcher.SetLookBack(time.Second)
+	set("00Q000000000010", base.Add(2*time.Second))
+	set("00Q000000000011", base.Add(5*time.Second))
+
+	assert.NoError(watcher.Poll(ctx, base, handler))
+	assert.Equal([]string{to18("00Q000000000011")}, handled)
+
+	checkpoint, _, err = store.LoadCheckpoint(ctx, "Lead")
+	assert.NoError(err)
+	assert.True(base.Add(4 * time.Second).Equal(checkpoint.Since))
+	assert.Equal(map[string]string{
+		to18("00Q000000000007"): base.Add(4 * time.Second).Format(time.RFC3339Nano),
+		to18("00Q000000000009"): base.Add(4 * time.Second).Format(time.RFC3339Nano),
+		to18("00Q000000000011"): base.Add(5 * time.Second).Format(time.RFC3339Nano),
+	}, handledAt(checkpoint))
+
+	// Beyond the limit, the oldest records are forgotten, except those sharing the checkpoint's SystemModstamp.
+	handled = nil
+	watcher.SetLookBack(time.Hour)
+	watcher.limit = 2
+	set("00Q000000000012", base.Add(6*time.Second))
+	set("00Q000000000013", base.Add(6*time.Second))
+	set("00Q000000000014", base.Add(6*time.Second))
+
+	assert.NoError(watcher.Poll(ctx, base, handler))
+	assert.Equal([]string{to18("00Q000000000012"), to18("00Q000000000013"), to18("00Q000000000014")}, handled)
+
+	checkpoint, _, err = store.LoadCheckpoint(ctx, "Lead")
+	assert.NoError(err)
+	assert.True(base.Add(6 * time.Second).Equal(checkpoint.Since))
+	assert.Len(checkpoint.Handled, 3)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+
+	store := NewFileCheckpointStore(path)
+
+	_, ok, err := store.LoadCheckpoint(ctx, "Lead")
+	assert.NoError(err)
+	assert.False(ok)
+
+	modstamp := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(store.SaveCheckpoint(ctx, "Lead", &WatchCheckpoint{SystemModstamp: modstamp, Since: modstamp, Handled: map[string]time.Time{to18("00Q000000000001"): modstamp}}))
+	assert.NoError(store.SaveCheckpoint(ctx, "Task", &WatchCheckpoint{SystemModstamp: modstamp}))
+
+	checkpoint, ok, err := NewFileCheckpointStore(path).LoadCheckpoint(ctx, "Lead")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.True(modstamp.Equal(checkpoint.SystemModstamp))
+	assert.True(modstamp.Equal(checkpoint.Since))
+	assert.True(modstamp.Equal(checkpoint.Handled[to18("00Q000000000001")]))
+}